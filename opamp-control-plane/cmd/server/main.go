@@ -0,0 +1,536 @@
+// Command server runs the OpAMP control plane: it syncs collector configs
+// from a git repository, serves them to agents over OpAMP, and exposes a
+// REST API for fleet inventory and operations.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/api"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/certs"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/config"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/federation"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/gitsync"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/logging"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/notify"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/opamp"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/packages"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/rollout"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/settings"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	settingsPath := flag.String("config", "server.yaml", "path to the server settings file")
+	flag.Parse()
+
+	cfg, err := settings.Load(*settingsPath)
+	if err != nil {
+		return err
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Log.Level)); err != nil {
+		level = slog.LevelInfo
+	}
+	logs := logging.NewManager(os.Stderr, level)
+	logger := logs.Logger("server")
+	logger.Info("starting opamp control plane",
+		"opamp_addr", cfg.Server.OpAMPListenAddr, "api_addr", cfg.Server.APIListenAddr)
+
+	reg, err := registry.Open(cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+	defer reg.Close()
+
+	eventStream := api.NewEventStream()
+	if len(cfg.Notify.Webhooks) > 0 {
+		webhooks := make([]notify.Webhook, 0, len(cfg.Notify.Webhooks))
+		for _, wh := range cfg.Notify.Webhooks {
+			webhooks = append(webhooks, notify.Webhook{URL: wh.URL, Secret: wh.Secret, Events: wh.Events})
+		}
+		notifier := notify.New(webhooks, logs.Logger("notify"))
+		reg.SetEventHook(func(e *registry.Event) {
+			notifier.Notify(e)
+			eventStream.Publish(e)
+		})
+	} else {
+		reg.SetEventHook(eventStream.Publish)
+	}
+
+	store := config.NewStore()
+	if cfg.Sops.Enabled {
+		store.SetDecryptor(config.NewSopsDecryptor(cfg.Sops.Binary, cfg.Sops.AgeKeyFile))
+	}
+	apiSelectorDefs, err := reg.APISelectors()
+	if err != nil {
+		return fmt.Errorf("load api selectors: %w", err)
+	}
+	apiSelectors := make([]config.Selector, 0, len(apiSelectorDefs))
+	for name, def := range apiSelectorDefs {
+		sel, err := config.ParseAPISelector(name, []byte(def))
+		if err != nil {
+			logger.Warn("skipping stored api selector", "selector", name, "error", err)
+			continue
+		}
+		apiSelectors = append(apiSelectors, sel)
+	}
+	store.SetExtraSelectors(apiSelectors)
+
+	resolver := config.NewResolver(store, logs.Logger("resolver"))
+	if cfg.Server.ConfigHashAlgorithm != "" {
+		hasher, err := config.NewHasher(cfg.Server.ConfigHashAlgorithm)
+		if err != nil {
+			return fmt.Errorf("config hash: %w", err)
+		}
+		resolver.SetHasher(hasher)
+	}
+	if cfg.Validation.OtelcolBinary != "" {
+		resolver.SetValidatorBackend(config.NewOtelcolValidator(cfg.Validation.OtelcolBinary))
+	}
+	resolver.SetRejectPlaintextSecrets(cfg.Validation.RejectPlaintextSecrets)
+	resolver.SetSizeLimits(cfg.Validation.MaxFileSize, cfg.Validation.MaxConfigSize)
+	if cfg.Policy.Dir != "" {
+		resolver.SetPolicyEngine(config.NewOPAPolicy(cfg.Policy.Binary, cfg.Policy.Dir, cfg.Policy.Query))
+	}
+	if cfg.Vault.Addr != "" {
+		token := cfg.Vault.Token
+		if cfg.Vault.TokenFile != "" {
+			data, err := os.ReadFile(cfg.Vault.TokenFile)
+			if err != nil {
+				return fmt.Errorf("vault: %w", err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		resolver.SetSecretsProvider(config.NewVaultProvider(cfg.Vault.Addr, token))
+	}
+	if len(cfg.Git.Environments) > 0 {
+		envLabel := cfg.Git.EnvironmentLabel
+		if envLabel == "" {
+			envLabel = "deployment.environment"
+		}
+		envRoots := map[string]string{}
+		var envMatchers []config.EnvironmentMatch
+		for _, env := range cfg.Git.Environments {
+			root := env.Name + "/"
+			if env.Path != "" {
+				root = strings.Trim(env.Path, "/") + "/"
+			}
+			if len(env.Match) > 0 {
+				envMatchers = append(envMatchers, config.EnvironmentMatch{Labels: env.Match, Root: root})
+				continue
+			}
+			envRoots[env.Name] = root
+		}
+		resolver.SetEnvironments(envLabel, envRoots)
+		resolver.SetEnvironmentMatchers(envMatchers)
+	}
+
+	labelMapping := opamp.DefaultLabelMapping()
+	for attr, label := range cfg.Labels.Semconv {
+		labelMapping[attr] = label
+	}
+	// Patterns were validated with the settings; MustCompile cannot panic.
+	var labelRules []opamp.LabelRule
+	for _, rule := range cfg.Labels.Rules {
+		labelRules = append(labelRules, opamp.LabelRule{
+			Attr:  rule.Attr,
+			Match: regexp.MustCompile(rule.Match),
+			Label: rule.Label,
+			Value: rule.Value,
+		})
+	}
+	var pkgStore *packages.Store
+	if cfg.Packages.Dir != "" {
+		pkgStore = packages.NewStore(cfg.Packages.Dir, cfg.Packages.BaseURL)
+	}
+	var certSigner *certs.CA
+	if cfg.Certs.CACert != "" {
+		certSigner, err = certs.Load(cfg.Certs.CACert, cfg.Certs.CAKey, cfg.Certs.Validity)
+		if err != nil {
+			return fmt.Errorf("certs: %w", err)
+		}
+	}
+	var authenticator opamp.Authenticator
+	if len(cfg.Auth.Tokens) > 0 || cfg.Auth.TokenFile != "" {
+		tokens := map[string]string{}
+		if cfg.Auth.TokenFile != "" {
+			fileTokens, err := opamp.LoadTokenFile(cfg.Auth.TokenFile)
+			if err != nil {
+				return fmt.Errorf("auth: %w", err)
+			}
+			for token, name := range fileTokens {
+				tokens[token] = name
+			}
+		}
+		for _, t := range cfg.Auth.Tokens {
+			tokens[t.Token] = t.Name
+		}
+		authenticator = opamp.NewStaticTokenAuthenticator(tokens)
+	}
+
+	opampServer := opamp.New(reg, resolver, opamp.Options{
+		LabelMapping: labelMapping,
+		LabelRules:   labelRules,
+		LabelNormalization: opamp.LabelNormalization{
+			Rename:          cfg.Labels.Normalize.Rename,
+			StripPrefixes:   cfg.Labels.Normalize.StripPrefixes,
+			LowercaseValues: cfg.Labels.Normalize.LowercaseValues,
+		},
+		Packages:            pkgStore,
+		CertSigner:          certSigner,
+		Authenticator:       authenticator,
+		MinPushInterval:     cfg.Push.MinAgentInterval,
+		HeartbeatInterval:   cfg.Server.HeartbeatInterval,
+		DrainPeerURL:        cfg.Server.DrainPeerURL,
+		DrainRetryAfter:     cfg.Server.DrainRetryAfter,
+		MaxConnections:      cfg.Server.MaxConnections,
+		MaxConnectionsPerIP: cfg.Server.MaxConnectionsPerIP,
+		Allowlist:           cfg.Server.Allowlist,
+	}, logs.Logger("opamp"))
+	pushSched := opamp.NewPushScheduler(opampServer, cfg.Push.Debounce, logs.Logger("push"))
+
+	// Branch environments each get their own checkout under local_path; the
+	// primary syncer then runs in local mode over the parent directory, and
+	// the per-environment syncers do the pulling.
+	branchEnvs := false
+	for _, env := range cfg.Git.Environments {
+		if env.Branch != "" {
+			branchEnvs = true
+		}
+	}
+	primaryURL := cfg.Git.URL
+	if branchEnvs {
+		primaryURL = ""
+	}
+	var sparseDirs []string
+	if cfg.Git.Sparse {
+		sparseDirs = []string{strings.Trim(filepath.ToSlash(cfg.Git.Path), "/") + "/"}
+	}
+	var appAuth *gitsync.AppAuth
+	if cfg.Git.GitHubApp.AppID != 0 {
+		keyPEM, err := os.ReadFile(cfg.Git.GitHubApp.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("github app key: %w", err)
+		}
+		appAuth, err = gitsync.NewAppAuth(cfg.Git.URL, cfg.Git.GitHubApp.AppID, cfg.Git.GitHubApp.InstallationID, keyPEM)
+		if err != nil {
+			return fmt.Errorf("github app: %w", err)
+		}
+	}
+	var signingKeyring string
+	if cfg.Git.SigningKeys != "" {
+		data, err := os.ReadFile(cfg.Git.SigningKeys)
+		if err != nil {
+			return fmt.Errorf("git signing keys: %w", err)
+		}
+		signingKeyring = string(data)
+	}
+	var caBundle []byte
+	if cfg.Git.CABundle != "" {
+		caBundle, err = os.ReadFile(cfg.Git.CABundle)
+		if err != nil {
+			return fmt.Errorf("git ca bundle: %w", err)
+		}
+	}
+	syncer := gitsync.New(gitsync.Config{
+		URL:            primaryURL,
+		Branch:         cfg.Git.Branch,
+		Ref:            cfg.Git.Ref,
+		LocalPath:      cfg.Git.LocalPath,
+		PollInterval:   cfg.Git.PollInterval,
+		Token:          cfg.Git.Token,
+		AppAuth:        appAuth,
+		Proxy:          cfg.Git.Proxy,
+		CABundle:       caBundle,
+		SparseDirs:     sparseDirs,
+		ForceReset:     cfg.Git.ForceReset,
+		SigningKeyring: signingKeyring,
+	}, logs.Logger("gitsync"))
+	// The syncer manages the whole checkout; the store only reads the
+	// configured subdirectory of it.
+	configRoot := cfg.Git.LocalPath
+	if cfg.Git.Path != "" {
+		configRoot = filepath.Join(cfg.Git.LocalPath, cfg.Git.Path)
+	}
+	applyConfigs := func(commit string) {
+		if err := store.LoadConfigs(configRoot); err != nil {
+			logger.Error("config reload failed", "error", err)
+			return
+		}
+		store.SetCommit(commit)
+		logger.Info("configs reloaded", "commit", commit, "files", len(store.Files()))
+		if err := reg.RecordChange("gitsync", registry.EventGitSync, "commit "+commit); err != nil {
+			logger.Error("failed to record change", "error", err)
+		}
+		for _, problem := range store.SelectorProblems() {
+			logger.Warn("selectors lint", "problem", problem.String())
+		}
+		for _, conflict := range store.SelectorConflicts() {
+			logger.Warn("selector conflict", "tree", conflict.Tree, "kind", conflict.Kind, "problem", conflict.String())
+		}
+		for _, problem := range store.LintOverlays() {
+			logger.Warn("overlay lint", "file", problem.File, "key", problem.Key, "problem", problem.Message)
+		}
+		pushSched.Trigger()
+	}
+	onSync := applyConfigs
+	var changes *config.ChangeQueue
+	if cfg.Git.RequireApproval {
+		changes = config.NewChangeQueue(store, logs.Logger("changes"))
+		changes.OnApprove(applyConfigs)
+		changes.SetAgentCounter(func(selectors []string) int {
+			affected := make(map[string]bool, len(selectors))
+			for _, sel := range selectors {
+				affected[sel] = true
+			}
+			agents, err := reg.List(registry.AgentFilter{Limit: 10000})
+			if err != nil {
+				return 0
+			}
+			count := 0
+			for _, agent := range agents {
+				resolved, err := resolver.Resolve(agent)
+				if err != nil || resolved.Unmanaged {
+					continue
+				}
+				key := resolved.Selector
+				if agent.Tenant != "" {
+					key = agent.Tenant + "/" + key
+				}
+				if affected[key] {
+					count++
+				}
+			}
+			return count
+		})
+		onSync = func(commit string) {
+			// The first sync populates an empty snapshot; there is nothing
+			// deployed yet to protect with an approval gate.
+			if len(store.Files()) == 0 {
+				applyConfigs(commit)
+				return
+			}
+			cs, err := changes.Propose(configRoot, commit)
+			if err != nil {
+				logger.Error("change set proposal failed", "error", err)
+				return
+			}
+			if cs == nil {
+				return
+			}
+			logger.Info("change set pending approval", "id", cs.ID, "commit", commit,
+				"files", len(cs.Files), "selectors", len(cs.Selectors), "agents", cs.AffectedAgents)
+			if err := reg.RecordChange("gitsync", registry.EventChangeProposed, cs.ID+" commit "+commit); err != nil {
+				logger.Error("failed to record change", "error", err)
+			}
+		}
+	}
+	syncer.OnSync(onSync)
+	var envSyncers []*gitsync.Syncer
+	for _, env := range cfg.Git.Environments {
+		if env.Branch == "" {
+			continue
+		}
+		envSyncer := gitsync.New(gitsync.Config{
+			URL:            cfg.Git.URL,
+			Branch:         env.Branch,
+			LocalPath:      filepath.Join(cfg.Git.LocalPath, env.Name),
+			PollInterval:   cfg.Git.PollInterval,
+			Token:          cfg.Git.Token,
+			AppAuth:        appAuth,
+			Proxy:          cfg.Git.Proxy,
+			CABundle:       caBundle,
+			ForceReset:     cfg.Git.ForceReset,
+			SigningKeyring: signingKeyring,
+		}, logs.Logger("gitsync").With("environment", env.Name))
+		envSyncer.OnSync(onSync)
+		envSyncers = append(envSyncers, envSyncer)
+	}
+
+	var exporter *gitsync.Exporter
+	if cfg.Git.Export.Branch != "" {
+		exportURL := cfg.Git.Export.URL
+		if exportURL == "" {
+			exportURL = cfg.Git.URL
+		}
+		collect := func() (map[string][]byte, error) {
+			files := map[string][]byte{}
+			for _, tenant := range store.Tenants() {
+				for _, sel := range store.Selectors(tenant) {
+					resolved, err := resolver.ResolveSelector(tenant, sel.Name, "")
+					if err != nil {
+						logger.Warn("export render failed", "tenant", tenant, "selector", sel.Name, "error", err)
+						continue
+					}
+					name := "configs/" + sel.Name + ".yaml"
+					if tenant != "" {
+						name = "configs/tenants/" + tenant + "/" + sel.Name + ".yaml"
+					}
+					files[name] = resolved.Body
+				}
+			}
+			agents, err := reg.List(registry.AgentFilter{})
+			if err != nil {
+				return nil, fmt.Errorf("list agents: %w", err)
+			}
+			summary := map[string]any{
+				"commit": store.Commit(),
+				"agents": agents,
+			}
+			data, err := json.MarshalIndent(summary, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("fleet summary: %w", err)
+			}
+			files["fleet.json"] = append(data, '\n')
+			return files, nil
+		}
+		exporter = gitsync.NewExporter(gitsync.ExportConfig{
+			URL:       exportURL,
+			Branch:    cfg.Git.Export.Branch,
+			LocalPath: cfg.Git.Export.LocalPath,
+			Interval:  cfg.Git.Export.Interval,
+			Token:     cfg.Git.Token,
+			AppAuth:   appAuth,
+			Proxy:     cfg.Git.Proxy,
+			CABundle:  caBundle,
+		}, collect, logs.Logger("export"))
+	}
+
+	var bundle *gitsync.BundleSource
+	if cfg.Bundle.URL != "" {
+		bundle = gitsync.NewBundleSource(cfg.Bundle.URL, cfg.Bundle.Interval, cfg.Git.LocalPath, logs.Logger("bundle"))
+	}
+
+	var federated *federation.Aggregator
+	if len(cfg.Federation.Peers) > 0 {
+		peers := make([]federation.Peer, 0, len(cfg.Federation.Peers))
+		for _, p := range cfg.Federation.Peers {
+			peers = append(peers, federation.Peer{Name: p.Name, URL: p.URL, Token: p.Token})
+		}
+		federated = federation.New(peers, logs.Logger("federation"))
+	}
+
+	rollouts := rollout.New(reg, opampServer, logs.Logger("rollout"))
+
+	var gitPathPrefix string
+	if cfg.Git.Path != "" {
+		gitPathPrefix = strings.Trim(filepath.ToSlash(cfg.Git.Path), "/") + "/"
+	}
+	webhook := gitsync.NewWebhookHandler(syncer, cfg.Git.WebhookSecret, logs.Logger("webhook"))
+	if gitPathPrefix != "" {
+		webhook.SetPathFilter(gitPathPrefix)
+	}
+	statusPoster := gitsync.NewStatusPoster(cfg.Git.URL, cfg.Git.Token)
+	if statusPoster != nil && appAuth != nil {
+		statusPoster.SetTokenSource(appAuth.Token)
+	}
+	var apiKeys map[string]string
+	if len(cfg.API.Keys) > 0 {
+		apiKeys = map[string]string{}
+		for _, k := range cfg.API.Keys {
+			apiKeys[k.Key] = k.Name
+		}
+	}
+	apiServer := api.New(api.Deps{
+		Registry:     reg,
+		Resolver:     resolver,
+		Store:        store,
+		Syncer:       syncer,
+		Pusher:       opampServer,
+		Migrator:     opampServer,
+		Metrics:      opampServer,
+		Packages:     pkgStore,
+		Federation:   federated,
+		Rollouts:     rollouts,
+		Changes:      changes,
+		GitPath:      gitPathPrefix,
+		StatusPoster: statusPoster,
+		Events:       eventStream,
+		Webhook:      webhook,
+		LogLevels:    logs,
+		APIKeys:      apiKeys,
+		Logger:       logs.Logger("api"),
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go pushSched.Run(ctx)
+	if cfg.Reconcile.Interval > 0 {
+		go opampServer.RunReconciler(ctx, cfg.Reconcile.Interval, cfg.Reconcile.BatchSize)
+	}
+
+	errCh := make(chan error, 3+len(envSyncers))
+	go func() {
+		if err := syncer.Start(ctx); err != nil && ctx.Err() == nil {
+			errCh <- fmt.Errorf("gitsync: %w", err)
+		}
+	}()
+	for _, envSyncer := range envSyncers {
+		envSyncer := envSyncer
+		go func() {
+			if err := envSyncer.Start(ctx); err != nil && ctx.Err() == nil {
+				errCh <- fmt.Errorf("gitsync: %w", err)
+			}
+		}()
+	}
+	if exporter != nil {
+		go func() {
+			if err := exporter.Start(ctx); err != nil && ctx.Err() == nil {
+				errCh <- fmt.Errorf("export: %w", err)
+			}
+		}()
+	}
+	if bundle != nil {
+		go func() {
+			if err := bundle.Start(ctx); err != nil && ctx.Err() == nil {
+				errCh <- fmt.Errorf("bundle: %w", err)
+			}
+		}()
+	}
+	if err := opampServer.Start(cfg.Server.OpAMPListenAddr); err != nil {
+		return fmt.Errorf("opamp server: %w", err)
+	}
+	go func() {
+		if err := apiServer.Start(cfg.Server.APIListenAddr); err != nil {
+			errCh <- fmt.Errorf("api server: %w", err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		logger.Info("shutting down")
+	case err := <-errCh:
+		return err
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := apiServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("api shutdown failed", "error", err)
+	}
+	if err := opampServer.Stop(shutdownCtx); err != nil {
+		logger.Error("opamp shutdown failed", "error", err)
+	}
+	return nil
+}
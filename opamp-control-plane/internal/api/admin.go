@@ -0,0 +1,35 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// logLevelRequest adjusts the log level of one subsystem, or of every
+// subsystem when Subsystem is empty.
+type logLevelRequest struct {
+	Level     string `json:"level"`
+	Subsystem string `json:"subsystem,omitempty"`
+}
+
+func (s *Server) handleGetLogLevels(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"levels": s.logLevels.Levels()})
+}
+
+func (s *Server) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Level == "" {
+		writeError(w, http.StatusBadRequest, "level is required")
+		return
+	}
+	if err := s.logLevels.SetLevel(req.Subsystem, req.Level); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.logger.Info("log level changed", "subsystem", req.Subsystem, "level", req.Level)
+	writeJSON(w, http.StatusOK, map[string]any{"levels": s.logLevels.Levels()})
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+	"net/http"
+)
+
+// apiKeyName is the context key under which requireAPIKey stores the name of
+// the authenticated key, so audited changes are attributed to it.
+type apiKeyName struct{}
+
+// requireAPIKey guards mutating requests behind the configured static API
+// keys, sent in the X-API-Key header. Reads stay open so dashboards work
+// without credentials; the health check is mounted outside this middleware.
+func (s *Server) requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		name, ok := s.apiKeys[r.Header.Get("X-API-Key")]
+		if !ok {
+			s.logger.Warn("rejected unauthenticated request",
+				"method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+			writeError(w, http.StatusUnauthorized, "missing or invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyName{}, name)))
+	})
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/opamp"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// ConnectionMigrator offers new OpAMP connection settings to connected
+// agents. Implemented by the opamp server.
+type ConnectionMigrator interface {
+	OfferConnectionSettings(ctx context.Context, uids []string, cs opamp.ConnectionSettings) map[string]string
+}
+
+// offerConnectionSettingsRequest migrates a set of agents, named explicitly
+// or by label selection, to a new OpAMP endpoint.
+type offerConnectionSettingsRequest struct {
+	opamp.ConnectionSettings
+	Agents []string          `json:"agents,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func (s *Server) handleOfferConnectionSettings(w http.ResponseWriter, r *http.Request) {
+	var req offerConnectionSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Endpoint == "" {
+		writeError(w, http.StatusBadRequest, "endpoint is required")
+		return
+	}
+	if len(req.Agents) == 0 && len(req.Labels) == 0 {
+		writeError(w, http.StatusBadRequest, "either agents or labels must be given")
+		return
+	}
+
+	uids := req.Agents
+	if len(uids) == 0 {
+		agents, err := s.registry.List(registry.AgentFilter{
+			Labels: req.Labels,
+			Status: registry.StatusConnected,
+		})
+		if err != nil {
+			s.internalError(w, "list agents", err)
+			return
+		}
+		for _, a := range agents {
+			uids = append(uids, a.InstanceUID)
+		}
+	}
+
+	results := s.migrator.OfferConnectionSettings(r.Context(), uids, req.ConnectionSettings)
+	sent := 0
+	failed := map[string]string{}
+	for uid, errMsg := range results {
+		if errMsg == "" {
+			sent++
+		} else {
+			failed[uid] = errMsg
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"sent": sent, "failed": failed})
+}
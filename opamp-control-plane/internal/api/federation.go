@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/federation"
+)
+
+// federatedQueryParams are the listing filters forwarded to downstream
+// peers; everything else in the query string is dropped.
+var federatedQueryParams = []string{"tenant", "status", "config_state", "health", "limit", "offset"}
+
+// handleFederatedAgents aggregates the agent listings of all configured
+// downstream control planes into one view.
+func (s *Server) handleFederatedAgents(w http.ResponseWriter, r *http.Request) {
+	query := url.Values{}
+	for _, name := range federatedQueryParams {
+		if v := r.URL.Query().Get(name); v != "" {
+			query.Set(name, v)
+		}
+	}
+	agents, errs := s.federation.Agents(r.Context(), query)
+	if agents == nil {
+		agents = []*federation.Agent{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"agents": agents, "errors": errs})
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// labelReport summarizes one label key across the fleet, to help spot
+// typoed labels (env=prodcution) that silently exclude agents from
+// selectors.
+type labelReport struct {
+	Key string `json:"key"`
+	// Agents is how many agents carry the key.
+	Agents int `json:"agents"`
+	// DistinctValues is how many different values are in use.
+	DistinctValues int `json:"distinct_values"`
+	// Values lists the values in use with their agent counts.
+	Values map[string]int `json:"values"`
+	// Selectors names the selectors whose match criteria reference the key.
+	Selectors []string `json:"selectors,omitempty"`
+}
+
+// handleLabels reports every label key in use, its value cardinality, and
+// the selectors depending on it. Scoped by ?tenant= like the agents listing.
+func (s *Server) handleLabels(w http.ResponseWriter, r *http.Request) {
+	tenant := r.URL.Query().Get("tenant")
+	agents, err := s.registry.List(registry.AgentFilter{Tenant: tenant})
+	if err != nil {
+		s.internalError(w, "list agents", err)
+		return
+	}
+
+	reports := map[string]*labelReport{}
+	for _, agent := range agents {
+		for key, value := range agent.Labels {
+			rep, ok := reports[key]
+			if !ok {
+				rep = &labelReport{Key: key, Values: map[string]int{}}
+				reports[key] = rep
+			}
+			rep.Agents++
+			rep.Values[value]++
+		}
+	}
+
+	for _, sel := range s.store.Selectors(tenant) {
+		for key := range sel.Match.Labels {
+			rep, ok := reports[key]
+			if !ok {
+				rep = &labelReport{Key: key, Values: map[string]int{}}
+				reports[key] = rep
+			}
+			rep.Selectors = append(rep.Selectors, sel.Name)
+		}
+	}
+
+	out := make([]*labelReport, 0, len(reports))
+	for _, rep := range reports {
+		rep.DistinctValues = len(rep.Values)
+		sort.Strings(rep.Selectors)
+		out = append(out, rep)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	writeJSON(w, http.StatusOK, map[string]any{"labels": out})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/packages"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// handleListPackages lists the artifacts in the package store.
+func (s *Server) handleListPackages(w http.ResponseWriter, _ *http.Request) {
+	artifacts, err := s.packages.List()
+	if err != nil {
+		s.internalError(w, "list packages", err)
+		return
+	}
+	if artifacts == nil {
+		artifacts = []packages.Artifact{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"packages": artifacts})
+}
+
+// targetPackageRequest targets a package version at a set of agents, named
+// either explicitly or by label selection.
+type targetPackageRequest struct {
+	Package string            `json:"package"`
+	Version string            `json:"version"`
+	Agents  []string          `json:"agents,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+func (s *Server) handleTargetPackage(w http.ResponseWriter, r *http.Request) {
+	var req targetPackageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if req.Package == "" || req.Version == "" {
+		writeError(w, http.StatusBadRequest, "package and version are required")
+		return
+	}
+	if len(req.Agents) == 0 && len(req.Labels) == 0 {
+		writeError(w, http.StatusBadRequest, "either agents or labels must be given")
+		return
+	}
+	if _, err := s.packages.Lookup(req.Package, req.Version); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	uids := req.Agents
+	if len(uids) == 0 {
+		agents, err := s.registry.List(registry.AgentFilter{Labels: req.Labels})
+		if err != nil {
+			s.internalError(w, "list agents", err)
+			return
+		}
+		for _, a := range agents {
+			uids = append(uids, a.InstanceUID)
+		}
+	}
+	if err := s.registry.AssignPackage(uids, req.Package, req.Version); err != nil {
+		s.internalError(w, "assign package", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"assigned": len(uids)})
+}
+
+// handleDownloadPackage serves artifact bytes to agents.
+func (s *Server) handleDownloadPackage(w http.ResponseWriter, r *http.Request) {
+	path, err := s.packages.FilePath(chi.URLParam(r, "package"), chi.URLParam(r, "version"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeFile(w, r, path)
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/rollout"
+)
+
+// handleListRollouts returns all rollouts, newest first.
+func (s *Server) handleListRollouts(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"rollouts": s.rollouts.List()})
+}
+
+// handleStartRollout begins a staged rollout over the matching agents.
+func (s *Server) handleStartRollout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Tenant           string            `json:"tenant"`
+		Labels           map[string]string `json:"labels"`
+		BatchSize        int               `json:"batch_size"`
+		BatchTimeout     string            `json:"batch_timeout"`
+		FailureThreshold float64           `json:"failure_threshold"`
+		RequireHealthy   bool              `json:"require_healthy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	opts := rollout.Options{
+		Tenant:           req.Tenant,
+		Labels:           req.Labels,
+		BatchSize:        req.BatchSize,
+		FailureThreshold: req.FailureThreshold,
+		RequireHealthy:   req.RequireHealthy,
+	}
+	if req.BatchTimeout != "" {
+		timeout, err := time.ParseDuration(req.BatchTimeout)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid batch_timeout: "+err.Error())
+			return
+		}
+		opts.BatchTimeout = timeout
+	}
+	started, err := s.rollouts.Start(opts)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err := s.registry.RecordChange(actorFromRequest(r), registry.EventRolloutStarted, started.ID); err != nil {
+		s.logger.Error("failed to record change", "error", err)
+	}
+	writeJSON(w, http.StatusCreated, started)
+}
+
+// handleGetRollout returns one rollout by ID.
+func (s *Server) handleGetRollout(w http.ResponseWriter, r *http.Request) {
+	ro, ok := s.rollouts.Get(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "unknown rollout")
+		return
+	}
+	writeJSON(w, http.StatusOK, ro)
+}
+
+// handlePauseRollout stops a running rollout before its next batch.
+func (s *Server) handlePauseRollout(w http.ResponseWriter, r *http.Request) {
+	s.rolloutTransition(w, r, func(id string) error {
+		return s.rollouts.Pause(id, "paused by "+actorFromRequest(r))
+	})
+}
+
+// handleResumeRollout continues a paused rollout.
+func (s *Server) handleResumeRollout(w http.ResponseWriter, r *http.Request) {
+	s.rolloutTransition(w, r, s.rollouts.Resume)
+}
+
+// handleCancelRollout abandons a rollout.
+func (s *Server) handleCancelRollout(w http.ResponseWriter, r *http.Request) {
+	s.rolloutTransition(w, r, s.rollouts.Cancel)
+}
+
+// rolloutTransition applies a state change to the rollout in the URL and
+// returns its new state.
+func (s *Server) rolloutTransition(w http.ResponseWriter, r *http.Request, fn func(id string) error) {
+	id := chi.URLParam(r, "id")
+	err := fn(id)
+	if errors.Is(err, registry.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "unknown rollout")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	ro, _ := s.rollouts.Get(id)
+	writeJSON(w, http.StatusOK, ro)
+}
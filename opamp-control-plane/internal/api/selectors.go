@@ -0,0 +1,120 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/config"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// selectorNamePattern constrains API-managed selector names to the forms
+// used in selectors files.
+var selectorNamePattern = regexp.MustCompile(`^[0-9a-zA-Z][0-9a-zA-Z._-]*$`)
+
+// handleListSelectors lists the selectors of a config tree, merged across
+// sources: git-defined ones from the current snapshot and API-managed ones
+// stored in the registry.
+func (s *Server) handleListSelectors(w http.ResponseWriter, r *http.Request) {
+	managed, err := s.registry.APISelectors()
+	if err != nil {
+		s.internalError(w, "list api selectors", err)
+		return
+	}
+	type entry struct {
+		Name     string `json:"name"`
+		Priority int    `json:"priority"`
+		Source   string `json:"source"`
+	}
+	selectors := []entry{}
+	for _, sel := range s.store.Selectors(r.URL.Query().Get("tenant")) {
+		source := "git"
+		if _, ok := managed[sel.Name]; ok {
+			source = "api"
+		}
+		selectors = append(selectors, entry{Name: sel.Name, Priority: sel.Priority, Source: source})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"selectors": selectors})
+}
+
+// handlePutSelector creates or replaces an API-managed selector from a YAML
+// body in the selectors-file entry shape. The selector takes effect
+// immediately, shadowing a git-defined selector of the same name, so simple
+// routing changes do not need a full git workflow.
+func (s *Server) handlePutSelector(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	if !selectorNamePattern.MatchString(name) {
+		writeError(w, http.StatusBadRequest, "invalid selector name")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "reading body failed")
+		return
+	}
+	if _, err := config.ParseAPISelector(name, body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.registry.PutAPISelector(name, string(body)); err != nil {
+		s.internalError(w, "put api selector", err)
+		return
+	}
+	if err := s.refreshAPISelectors(); err != nil {
+		s.internalError(w, "refresh api selectors", err)
+		return
+	}
+	if err := s.registry.RecordChange(actorFromRequest(r), registry.EventSelectorUpdated, name); err != nil {
+		s.logger.Error("recording selector update failed", "selector", name, "error", err)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"name": name, "source": "api"})
+}
+
+// handleDeleteSelector removes an API-managed selector. Git-defined
+// selectors cannot be deleted here; a previously shadowed one becomes
+// effective again.
+func (s *Server) handleDeleteSelector(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	err := s.registry.DeleteAPISelector(name)
+	if errors.Is(err, registry.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "no API-managed selector with that name")
+		return
+	}
+	if err != nil {
+		s.internalError(w, "delete api selector", err)
+		return
+	}
+	if err := s.refreshAPISelectors(); err != nil {
+		s.internalError(w, "refresh api selectors", err)
+		return
+	}
+	if err := s.registry.RecordChange(actorFromRequest(r), registry.EventSelectorDeleted, name); err != nil {
+		s.logger.Error("recording selector delete failed", "selector", name, "error", err)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"name": name, "deleted": true})
+}
+
+// refreshAPISelectors reloads every stored API-managed selector into the
+// config store. A stored definition that no longer parses is skipped with a
+// log line rather than blocking the rest.
+func (s *Server) refreshAPISelectors() error {
+	defs, err := s.registry.APISelectors()
+	if err != nil {
+		return err
+	}
+	selectors := make([]config.Selector, 0, len(defs))
+	for name, def := range defs {
+		sel, err := config.ParseAPISelector(name, []byte(def))
+		if err != nil {
+			s.logger.Error("stored api selector is invalid", "selector", name, "error", err)
+			continue
+		}
+		selectors = append(selectors, sel)
+	}
+	s.store.SetExtraSelectors(selectors)
+	return nil
+}
@@ -0,0 +1,1044 @@
+// Package api exposes the operator-facing REST API: fleet inventory,
+// per-agent detail, and sync control.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/config"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/federation"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/gitsync"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/logging"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/opamp"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/packages"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/rollout"
+)
+
+// instanceUIDPattern constrains agent IDs in URLs to printable UID forms
+// (ULID, UUID, or similar).
+var instanceUIDPattern = regexp.MustCompile(`^[0-9a-zA-Z-]{16,64}$`)
+
+// ConfigPusher pushes the current resolved config to connected agents.
+// Implemented by the opamp server.
+type ConfigPusher interface {
+	PushConfigToAgent(ctx context.Context, uid string) error
+	PushConfigToAgents(ctx context.Context, uids []string) opamp.PushSummary
+	PushConfigToAll(ctx context.Context) opamp.PushSummary
+}
+
+// MetricsSource reports OpAMP server traffic counters. Implemented by the
+// opamp server.
+type MetricsSource interface {
+	Metrics() opamp.Metrics
+}
+
+// Deps are the components the API server exposes. Optional fields may be
+// nil, which disables the corresponding routes.
+type Deps struct {
+	Registry *registry.Registry
+	Resolver *config.Resolver
+	Store    *config.Store
+	Syncer   *gitsync.Syncer
+	Pusher   ConfigPusher
+	// Migrator enables the connection settings offer endpoint.
+	Migrator ConnectionMigrator
+	// Metrics enables the OpAMP server metrics endpoint.
+	Metrics MetricsSource
+	// Packages enables the artifact listing/targeting/download routes.
+	Packages *packages.Store
+	// Federation enables the federated agents view over downstream peers.
+	Federation *federation.Aggregator
+	// Rollouts enables the staged rollout routes.
+	Rollouts *rollout.Controller
+	// Changes enables the change set approval routes (git.require_approval).
+	Changes *config.ChangeQueue
+	// GitPath is the repository path prefix of the config root
+	// ("/"-separated, trailing slash included), "" at the repository root.
+	// It scopes the changed-file lists in the commit history.
+	GitPath string
+	// StatusPoster reports pull request validation results back to GitHub
+	// as commit statuses. Nil disables status posting.
+	StatusPoster *gitsync.StatusPoster
+	// Events enables the live SSE event stream.
+	Events *EventStream
+	// Webhook is mounted at POST /webhook/git.
+	Webhook http.Handler
+	// LogLevels enables the runtime log level admin endpoint.
+	LogLevels *logging.Manager
+	// APIKeys maps static API keys to holder names. With at least one key
+	// configured, mutating endpoints require a matching X-API-Key header;
+	// reads and the health check stay open. Nil leaves the API open.
+	APIKeys map[string]string
+	Logger  *slog.Logger
+}
+
+// Server is the REST API server.
+type Server struct {
+	registry   *registry.Registry
+	resolver   *config.Resolver
+	store      *config.Store
+	syncer     *gitsync.Syncer
+	pusher     ConfigPusher
+	migrator   ConnectionMigrator
+	metrics    MetricsSource
+	packages   *packages.Store
+	federation *federation.Aggregator
+	rollouts   *rollout.Controller
+	changes    *config.ChangeQueue
+	gitPath    string
+	statuses   *gitsync.StatusPoster
+	events     *EventStream
+	logLevels  *logging.Manager
+	apiKeys    map[string]string
+	logger     *slog.Logger
+
+	httpServer *http.Server
+}
+
+// New returns an API server wired to the given components.
+func New(deps Deps) *Server {
+	s := &Server{
+		registry:   deps.Registry,
+		resolver:   deps.Resolver,
+		store:      deps.Store,
+		syncer:     deps.Syncer,
+		pusher:     deps.Pusher,
+		migrator:   deps.Migrator,
+		metrics:    deps.Metrics,
+		packages:   deps.Packages,
+		federation: deps.Federation,
+		rollouts:   deps.Rollouts,
+		changes:    deps.Changes,
+		gitPath:    deps.GitPath,
+		statuses:   deps.StatusPoster,
+		events:     deps.Events,
+		logLevels:  deps.LogLevels,
+		apiKeys:    deps.APIKeys,
+		logger:     deps.Logger,
+	}
+
+	r := chi.NewRouter()
+	r.Get("/healthz", s.handleHealth)
+	r.Route("/api/v1", func(r chi.Router) {
+		if len(s.apiKeys) > 0 {
+			r.Use(s.requireAPIKey)
+		}
+		r.Get("/agents", s.handleListAgents)
+		r.Get("/agents/{id}", s.handleGetAgent)
+		r.Get("/agents/{id}/events", s.handleAgentEvents)
+		r.Get("/agents/{id}/status/history", s.handleAgentStatusHistory)
+		r.Get("/agents/{id}/config", s.handleAgentConfig)
+		r.Get("/agents/{id}/config/diff", s.handleAgentConfigDiff)
+		r.Get("/agents/{id}/config/provenance", s.handleAgentConfigProvenance)
+		if s.pusher != nil {
+			r.Post("/agents/{id}/config/push", s.handlePushAgentConfig)
+		}
+		r.Put("/agents/{id}/unmanaged", s.handleSetUnmanaged)
+		r.Put("/agents/{id}/approval", s.handleSetApproval)
+		r.Get("/changes", s.handleChanges)
+		if s.changes != nil {
+			r.Get("/changes/pending", s.handlePendingChange)
+			r.Post("/changes/{id}/approve", s.handleApproveChange)
+			r.Post("/changes/{id}/reject", s.handleRejectChange)
+		}
+		r.Get("/configs/versions", s.handleConfigVersions)
+		r.Get("/configs/versions/{hash}", s.handleConfigVersion)
+		r.Get("/labels", s.handleLabels)
+		r.Get("/stats", s.handleStats)
+		r.Get("/selectors", s.handleListSelectors)
+		r.Put("/selectors/{name}", s.handlePutSelector)
+		r.Delete("/selectors/{name}", s.handleDeleteSelector)
+		r.Get("/selectors/lint", s.handleSelectorLint)
+		r.Get("/selectors/{name}/config", s.handleSelectorConfig)
+		r.Get("/selectors/suggestions", s.handleSelectorSuggestions)
+		if s.events != nil {
+			r.Get("/events/stream", s.handleEventStream)
+			r.Get("/events/ws", s.handleEventsWS)
+		}
+		r.Get("/deadletters", s.handleDeadLetters)
+		r.Get("/deliveries/pending", s.handlePendingDeliveries)
+		if s.pusher != nil {
+			r.Post("/config/push", s.handlePushConfig)
+		}
+		r.Post("/resolve", s.handleResolve)
+		r.Post("/validate", s.handleValidate)
+		r.Post("/rollback", s.handleRollback)
+		r.Post("/sync", s.handleSync)
+		r.Get("/sync/status", s.handleSyncStatus)
+		r.Get("/git/commits", s.handleGitCommits)
+		r.Post("/git/validate", s.handleGitValidate)
+		if s.packages != nil {
+			r.Get("/packages", s.handleListPackages)
+			r.Post("/packages/target", s.handleTargetPackage)
+		}
+		if s.federation != nil {
+			r.Get("/federated/agents", s.handleFederatedAgents)
+		}
+		if s.rollouts != nil {
+			r.Get("/rollouts", s.handleListRollouts)
+			r.Post("/rollouts", s.handleStartRollout)
+			r.Get("/rollouts/{id}", s.handleGetRollout)
+			r.Post("/rollouts/{id}/pause", s.handlePauseRollout)
+			r.Post("/rollouts/{id}/resume", s.handleResumeRollout)
+			r.Post("/rollouts/{id}/cancel", s.handleCancelRollout)
+		}
+		if s.metrics != nil {
+			r.Get("/metrics", s.handleMetrics)
+		}
+		if s.migrator != nil {
+			r.Post("/connection-settings/offer", s.handleOfferConnectionSettings)
+		}
+		if s.logLevels != nil {
+			r.Get("/admin/loglevel", s.handleGetLogLevels)
+			r.Put("/admin/loglevel", s.handleSetLogLevel)
+		}
+	})
+	if s.packages != nil {
+		r.Get("/packages/{package}/{version}", s.handleDownloadPackage)
+	}
+	if deps.Webhook != nil {
+		r.Method(http.MethodPost, "/webhook/git", deps.Webhook)
+	}
+	s.httpServer = &http.Server{Handler: r}
+	return s
+}
+
+// Start begins serving the API on addr. It blocks until the listener fails
+// or Shutdown is called.
+func (s *Server) Start(addr string) error {
+	s.httpServer.Addr = addr
+	err := s.httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown gracefully stops the API server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleListAgents(w http.ResponseWriter, r *http.Request) {
+	limit := queryInt(r, "limit", 100)
+	if limit < 1 || limit > 10000 {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and 10000")
+		return
+	}
+	filter := registry.AgentFilter{
+		Tenant:      r.URL.Query().Get("tenant"),
+		Status:      registry.AgentStatus(r.URL.Query().Get("status")),
+		ConfigState: registry.ConfigState(r.URL.Query().Get("config_state")),
+		Health:      registry.HealthState(r.URL.Query().Get("health")),
+		Limit:       limit,
+		Offset:      queryInt(r, "offset", 0),
+	}
+	if raw := r.URL.Query().Get("labels"); raw != "" {
+		selector, err := registry.ParseLabelSelector(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.Selector = selector
+	}
+	var ok bool
+	if filter.LastSeenBefore, ok = queryTime(r, "last_seen_before"); !ok {
+		writeError(w, http.StatusBadRequest, "last_seen_before must be an RFC 3339 timestamp")
+		return
+	}
+	if filter.LastSeenAfter, ok = queryTime(r, "last_seen_after"); !ok {
+		writeError(w, http.StatusBadRequest, "last_seen_after must be an RFC 3339 timestamp")
+		return
+	}
+	// A cursor supersedes the offset: it pins iteration to the UID ordering,
+	// so pages stay stable while agents churn.
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		after, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		filter.After = string(after)
+		filter.Offset = 0
+	}
+	// Fetch one past the page to learn whether a next page exists.
+	filter.Limit = limit + 1
+	agents, err := s.registry.List(filter)
+	if err != nil {
+		s.internalError(w, "list agents", err)
+		return
+	}
+	var nextCursor string
+	if len(agents) > limit {
+		agents = agents[:limit]
+		nextCursor = base64.RawURLEncoding.EncodeToString([]byte(agents[limit-1].InstanceUID))
+		q := r.URL.Query()
+		q.Set("cursor", nextCursor)
+		q.Del("offset")
+		w.Header().Set("Link", fmt.Sprintf("<%s?%s>; rel=%q", r.URL.Path, q.Encode(), "next"))
+	}
+	if agents == nil {
+		agents = []*registry.Agent{}
+	}
+	resp := map[string]any{"agents": agents}
+	if nextCursor != "" {
+		resp["next_cursor"] = nextCursor
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleGetAgent(w http.ResponseWriter, r *http.Request) {
+	agent, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	statuses, err := s.registry.PackageStatuses(agent.InstanceUID)
+	if err != nil {
+		s.internalError(w, "list package statuses", err)
+		return
+	}
+	pending, err := s.registry.PendingDeliveryCount(agent.InstanceUID)
+	if err != nil {
+		s.internalError(w, "count pending deliveries", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		*registry.Agent
+		PackageStatuses   []registry.PackageStatus `json:"package_statuses,omitempty"`
+		PendingDeliveries int                      `json:"pending_deliveries"`
+	}{agent, statuses, pending})
+}
+
+func (s *Server) handleAgentEvents(w http.ResponseWriter, r *http.Request) {
+	agent, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	events, err := s.registry.Events(agent.InstanceUID, queryInt(r, "limit", 100))
+	if err != nil {
+		s.internalError(w, "list events", err)
+		return
+	}
+	if events == nil {
+		events = []*registry.Event{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"events": events})
+}
+
+// handleAgentStatusHistory returns the timeline of the agent's connection
+// and config status transitions, newest first — a filtered view of the event
+// log that skips operator actions and package noise.
+func (s *Server) handleAgentStatusHistory(w http.ResponseWriter, r *http.Request) {
+	agent, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	history, err := s.registry.StatusHistory(agent.InstanceUID, queryInt(r, "limit", 100))
+	if err != nil {
+		s.internalError(w, "list status history", err)
+		return
+	}
+	if history == nil {
+		history = []*registry.Event{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"history": history})
+}
+
+// handleAgentConfig returns the config the control plane currently wants the
+// agent to run.
+func (s *Server) handleAgentConfig(w http.ResponseWriter, r *http.Request) {
+	agent, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	resolved, err := s.resolver.Resolve(agent)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if resolved.Unmanaged {
+		writeError(w, http.StatusConflict, "agent is unmanaged")
+		return
+	}
+	body := resolved.Body
+	// Secret values rendered into the config are for the agent, not for API
+	// callers.
+	for _, secret := range resolved.Secrets {
+		body = bytes.ReplaceAll(body, []byte(secret), []byte("[REDACTED]"))
+	}
+	w.Header().Set("Content-Type", resolved.ContentType)
+	w.Header().Set("X-Config-Hash", resolved.Hash)
+	w.Header().Set("X-Config-Selector", resolved.Selector)
+	w.Write(body)
+}
+
+// handleAgentConfigProvenance reports, per key path in the agent's resolved
+// config, which repository file last set the value, so "where did this come
+// from" does not require reading the whole overlay stack.
+func (s *Server) handleAgentConfigProvenance(w http.ResponseWriter, r *http.Request) {
+	agent, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	resolved, err := s.resolver.Resolve(agent)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if resolved.Unmanaged {
+		writeError(w, http.StatusConflict, "agent is unmanaged")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"selector":   resolved.Selector,
+		"hash":       resolved.Hash,
+		"provenance": resolved.Provenance,
+	})
+}
+
+// handleAgentConfigDiff returns a unified diff between the config last
+// delivered to the agent and the currently resolved desired config, so
+// operators can review what a push would change.
+func (s *Server) handleAgentConfigDiff(w http.ResponseWriter, r *http.Request) {
+	agent, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	resolved, err := s.resolver.Resolve(agent)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if resolved.Unmanaged {
+		writeError(w, http.StatusConflict, "agent is unmanaged")
+		return
+	}
+	runningHash, running, err := s.registry.OfferedConfig(agent.InstanceUID)
+	if err != nil && !errors.Is(err, registry.ErrNotFound) {
+		s.internalError(w, "get offered config", err)
+		return
+	}
+	if runningHash == resolved.Hash {
+		writeJSON(w, http.StatusOK, map[string]any{"changed": false, "hash": resolved.Hash})
+		return
+	}
+	desired := resolved.Body
+	for _, secret := range resolved.Secrets {
+		running = bytes.ReplaceAll(running, []byte(secret), []byte("[REDACTED]"))
+		desired = bytes.ReplaceAll(desired, []byte(secret), []byte("[REDACTED]"))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"changed":   true,
+		"from_hash": runningHash,
+		"to_hash":   resolved.Hash,
+		"diff":      config.UnifiedDiff(running, desired, "running", "desired"),
+	})
+}
+
+// handlePushAgentConfig immediately pushes the currently resolved config to
+// one connected agent, outside the usual debounced push schedule.
+func (s *Server) handlePushAgentConfig(w http.ResponseWriter, r *http.Request) {
+	agent, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	resolved, err := s.resolver.Resolve(agent)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if resolved.Unmanaged {
+		writeError(w, http.StatusConflict, "agent is unmanaged")
+		return
+	}
+	connected := agent.Status == registry.StatusConnected
+	if err := s.pusher.PushConfigToAgent(r.Context(), agent.InstanceUID); err != nil {
+		writeJSON(w, http.StatusConflict, map[string]any{
+			"pushed":    false,
+			"connected": connected,
+			"error":     err.Error(),
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"pushed":    true,
+		"connected": connected,
+		"hash":      resolved.Hash,
+	})
+}
+
+// handleStats returns aggregate fleet counts — by status, config state,
+// selector, collector version, and OS — for dashboards.
+func (s *Server) handleStats(w http.ResponseWriter, _ *http.Request) {
+	stats, err := s.registry.Stats()
+	if err != nil {
+		s.internalError(w, "fleet stats", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handlePushConfig pushes the current config to every connected agent, or —
+// when the body carries labels or a selector name — only to the agents that
+// match. The response summarises how many agents were targeted, pushed,
+// skipped, and failed.
+func (s *Server) handlePushConfig(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Labels   map[string]string `json:"labels"`
+		Selector string            `json:"selector"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+	}
+	if len(req.Labels) == 0 && req.Selector == "" {
+		summary := s.pusher.PushConfigToAll(r.Context())
+		writeJSON(w, http.StatusOK, map[string]any{
+			"targeted": summary.Sent + summary.Skipped + summary.Failed,
+			"summary":  summary,
+		})
+		return
+	}
+	agents, err := s.registry.List(registry.AgentFilter{Limit: 10000})
+	if err != nil {
+		s.internalError(w, "list agents", err)
+		return
+	}
+	var uids []string
+	for _, agent := range agents {
+		matches := true
+		for k, v := range req.Labels {
+			if agent.Labels[k] != v {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		if req.Selector != "" {
+			resolved, err := s.resolver.Resolve(agent)
+			if err != nil || resolved.Selector != req.Selector {
+				continue
+			}
+		}
+		uids = append(uids, agent.InstanceUID)
+	}
+	summary := s.pusher.PushConfigToAgents(r.Context(), uids)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"targeted": len(uids),
+		"summary":  summary,
+	})
+}
+
+// handleResolve dry-runs config resolution for an arbitrary label map, so
+// selector changes can be tested before any agent carries those labels.
+// Resolution failures are part of the answer, not an HTTP error.
+func (s *Server) handleResolve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Tenant     string            `json:"tenant"`
+		Labels     map[string]string `json:"labels"`
+		Attributes map[string]string `json:"attributes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	resolved, err := s.resolver.Resolve(&registry.Agent{
+		InstanceUID: "dry-run",
+		Tenant:      req.Tenant,
+		Labels:      req.Labels,
+		Description: req.Attributes,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusOK, map[string]any{"valid": false, "error": err.Error()})
+		return
+	}
+	if resolved.Unmanaged {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"valid":     true,
+			"selector":  resolved.Selector,
+			"unmanaged": true,
+		})
+		return
+	}
+	body := resolved.Body
+	for _, secret := range resolved.Secrets {
+		body = bytes.ReplaceAll(body, []byte(secret), []byte("[REDACTED]"))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"valid":        true,
+		"selector":     resolved.Selector,
+		"hash":         resolved.Hash,
+		"content_type": resolved.ContentType,
+		"config":       string(body),
+		"provenance":   resolved.Provenance,
+		"warnings":     resolved.Warnings,
+	})
+}
+
+// handleValidate checks a posted YAML config body against the same rules a
+// resolved config must pass — structural validation, the component backend
+// when one is configured, and the lint pass — so CI pipelines and humans can
+// check a config without deploying it. Findings are part of the answer, not
+// an HTTP error.
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "reading body failed")
+		return
+	}
+	if len(body) == 0 {
+		writeError(w, http.StatusBadRequest, "request body must be a YAML config")
+		return
+	}
+	problems, warnings := s.resolver.ValidateConfig(body)
+	if problems == nil {
+		problems = []string{}
+	}
+	if warnings == nil {
+		warnings = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"valid":    len(problems) == 0,
+		"problems": problems,
+		"warnings": warnings,
+	})
+}
+
+// handleSetUnmanaged marks an agent as unmanaged (observed but never offered
+// configs) or returns it to management.
+func (s *Server) handleSetUnmanaged(w http.ResponseWriter, r *http.Request) {
+	agent, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Unmanaged bool `json:"unmanaged"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if err := s.registry.SetUnmanaged(agent.InstanceUID, req.Unmanaged); err != nil {
+		s.internalError(w, "set unmanaged", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"instance_uid": agent.InstanceUID,
+		"unmanaged":    req.Unmanaged,
+	})
+}
+
+// handleSetApproval approves an agent parked by the allowlist, or revokes an
+// approval so the agent is parked again on its next message.
+func (s *Server) handleSetApproval(w http.ResponseWriter, r *http.Request) {
+	agent, ok := s.lookupAgent(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Approved bool `json:"approved"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if err := s.registry.SetPending(agent.InstanceUID, !req.Approved); err != nil {
+		s.internalError(w, "set approval", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"instance_uid": agent.InstanceUID,
+		"approved":     req.Approved,
+	})
+}
+
+// handleDeadLetters lists failed config sends awaiting retry.
+func (s *Server) handleDeadLetters(w http.ResponseWriter, _ *http.Request) {
+	letters, err := s.registry.DeadLetters()
+	if err != nil {
+		s.internalError(w, "list dead letters", err)
+		return
+	}
+	if letters == nil {
+		letters = []*registry.DeadLetter{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"dead_letters": letters})
+}
+
+// handleConfigVersions lists every distinct config the server has offered,
+// newest first, without bodies.
+func (s *Server) handleConfigVersions(w http.ResponseWriter, _ *http.Request) {
+	versions, err := s.registry.ConfigVersions()
+	if err != nil {
+		s.internalError(w, "list config versions", err)
+		return
+	}
+	if versions == nil {
+		versions = []registry.ConfigVersion{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"versions": versions})
+}
+
+// handleConfigVersion returns the exact bytes behind a historical config
+// hash, as agents report them in RemoteConfigStatus.
+func (s *Server) handleConfigVersion(w http.ResponseWriter, r *http.Request) {
+	hash := chi.URLParam(r, "hash")
+	version, err := s.registry.ConfigVersion(hash)
+	if errors.Is(err, registry.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "unknown config hash")
+		return
+	}
+	if err != nil {
+		s.internalError(w, "get config version", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		registry.ConfigVersion
+		Body string `json:"body"`
+	}{ConfigVersion: *version, Body: string(version.Body)})
+}
+
+// handlePendingDeliveries returns the delivery queue depth for every agent
+// with a config change queued while disconnected.
+func (s *Server) handlePendingDeliveries(w http.ResponseWriter, _ *http.Request) {
+	counts, err := s.registry.PendingDeliveryCounts()
+	if err != nil {
+		s.internalError(w, "count pending deliveries", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"pending_deliveries": counts})
+}
+
+// handleChanges returns the fleet change feed: git syncs, manual pushes,
+// and other operator actions in one chronological list.
+func (s *Server) handleChanges(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "since must be RFC 3339")
+			return
+		}
+		since = t
+	}
+	changes, err := s.registry.Changes(since, queryInt(r, "limit", 100))
+	if err != nil {
+		s.internalError(w, "list changes", err)
+		return
+	}
+	if changes == nil {
+		changes = []*registry.Event{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"changes": changes})
+}
+
+// handleMetrics returns the OpAMP server's connection and traffic counters.
+func (s *Server) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.metrics.Metrics())
+}
+
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request) {
+	s.syncer.Trigger()
+	if err := s.registry.RecordChange(actorFromRequest(r), registry.EventSyncTriggered, ""); err != nil {
+		s.logger.Error("failed to record change", "error", err)
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "sync triggered"})
+}
+
+// handlePendingChange returns the change set awaiting approval, if any.
+func (s *Server) handlePendingChange(w http.ResponseWriter, _ *http.Request) {
+	cs := s.changes.Pending()
+	if cs == nil {
+		writeError(w, http.StatusNotFound, "no pending change set")
+		return
+	}
+	writeJSON(w, http.StatusOK, cs)
+}
+
+// handleApproveChange applies the pending change set: configs reload and
+// the push scheduler takes over.
+func (s *Server) handleApproveChange(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := s.changes.Approve(id); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := s.registry.RecordChange(actorFromRequest(r), registry.EventChangeApproved, id); err != nil {
+		s.logger.Error("failed to record change", "error", err)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "approved"})
+}
+
+// handleRejectChange discards the pending change set; the running snapshot
+// stays in place.
+func (s *Server) handleRejectChange(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := s.changes.Reject(id); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := s.registry.RecordChange(actorFromRequest(r), registry.EventChangeRejected, id); err != nil {
+		s.logger.Error("failed to record change", "error", err)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "rejected"})
+}
+
+// handleRollback pins the config checkout to a commit: the syncer checks it
+// out, configs reload via OnSync, and the push scheduler takes it from
+// there. The response includes the commit that was current before the
+// rollback, so rolling forward is one more call with that commit. An empty
+// commit re-attaches to the tracked branch and resumes pulling.
+func (s *Server) handleRollback(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Commit string `json:"commit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	previous, err := s.syncer.Checkout(r.Context(), req.Commit)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	detail := "to " + req.Commit
+	if req.Commit == "" {
+		detail = "resumed branch tracking"
+	}
+	if err := s.registry.RecordChange(actorFromRequest(r), registry.EventRollback, detail); err != nil {
+		s.logger.Error("failed to record change", "error", err)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"commit":          s.syncer.LastCommit(),
+		"previous_commit": previous,
+	})
+}
+
+// handleSelectorConfig returns the fully merged config a selector would
+// deliver, without needing a live matching agent. The tenant query
+// parameter picks the config tree; an Accept header of application/json
+// converts the artifact to JSON.
+func (s *Server) handleSelectorConfig(w http.ResponseWriter, r *http.Request) {
+	format := ""
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		format = "json"
+	}
+	resolved, err := s.resolver.ResolveSelector(r.URL.Query().Get("tenant"), chi.URLParam(r, "name"), format)
+	if errors.Is(err, config.ErrUnknownSelector) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if resolved.Unmanaged {
+		writeError(w, http.StatusConflict, "selector marks agents unmanaged")
+		return
+	}
+	body := resolved.Body
+	for _, secret := range resolved.Secrets {
+		body = bytes.ReplaceAll(body, []byte(secret), []byte("[REDACTED]"))
+	}
+	w.Header().Set("Content-Type", resolved.ContentType)
+	w.Header().Set("X-Config-Hash", resolved.Hash)
+	w.Header().Set("X-Config-Selector", resolved.Selector)
+	w.Write(body)
+}
+
+// handleSelectorLint reports selectors that can never match or that overlap
+// ambiguously, per config tree.
+func (s *Server) handleSelectorLint(w http.ResponseWriter, _ *http.Request) {
+	conflicts := s.store.SelectorConflicts()
+	if conflicts == nil {
+		conflicts = []config.SelectorConflict{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"conflicts": conflicts})
+}
+
+// handleSyncStatus reports the state of the config snapshot: the commit it
+// came from and what validation found wrong with it. Selector problems
+// surface here instead of failing agents one by one at resolve time.
+func (s *Server) handleSyncStatus(w http.ResponseWriter, _ *http.Request) {
+	selectorProblems := s.store.SelectorProblems()
+	if selectorProblems == nil {
+		selectorProblems = []config.SelectorProblem{}
+	}
+	overlayProblems := s.store.LintOverlays()
+	if overlayProblems == nil {
+		overlayProblems = []config.LintProblem{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"commit":            s.store.Commit(),
+		"files":             len(s.store.Files()),
+		"syncer":            s.syncer.Status(),
+		"selector_problems": selectorProblems,
+		"overlay_problems":  overlayProblems,
+	})
+}
+
+// commitSHAPattern matches a full git commit hash, as sent by PR webhooks.
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// handleGitValidate checks out a proposed commit into a temporary tree and
+// runs the full selector loading and resolution pipeline against it, so
+// broken configs are caught before they merge. With a status poster
+// configured the verdict is also reported to GitHub as a commit status.
+func (s *Server) handleGitValidate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SHA string `json:"sha"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !commitSHAPattern.MatchString(req.SHA) {
+		writeError(w, http.StatusBadRequest, "sha must be a full commit hash")
+		return
+	}
+	dir, cleanup, err := s.syncer.MaterializeRef(r.Context(), req.SHA)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	defer cleanup()
+	root := dir
+	if s.gitPath != "" {
+		root = filepath.Join(dir, filepath.FromSlash(s.gitPath))
+	}
+	problems, err := s.resolver.ValidateTree(root)
+	if err != nil {
+		s.internalError(w, "validate tree", err)
+		return
+	}
+	if s.statuses != nil {
+		state, desc := "success", "all selectors resolve"
+		if len(problems) > 0 {
+			state = "failure"
+			desc = fmt.Sprintf("%d config problem(s)", len(problems))
+		}
+		if err := s.statuses.PostStatus(r.Context(), req.SHA, state, desc); err != nil {
+			s.logger.Error("posting commit status failed", "sha", req.SHA, "error", err)
+		}
+	}
+	if problems == nil {
+		problems = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sha":      req.SHA,
+		"ok":       len(problems) == 0,
+		"problems": problems,
+	})
+}
+
+// handleGitCommits lists recent commits of the config repository so config
+// pushes can be correlated with repo changes without leaving the control
+// plane.
+func (s *Server) handleGitCommits(w http.ResponseWriter, r *http.Request) {
+	limit := queryInt(r, "limit", 20)
+	if limit < 1 || limit > 200 {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and 200")
+		return
+	}
+	commits, err := s.syncer.History(limit, s.gitPath)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"commits": commits})
+}
+
+// actorFromRequest attributes an API action: the authenticated API key's
+// name when key auth is on, otherwise the X-Actor header when the caller
+// identifies itself, otherwise the remote address.
+func actorFromRequest(r *http.Request) string {
+	if name, ok := r.Context().Value(apiKeyName{}).(string); ok {
+		return name
+	}
+	if actor := r.Header.Get("X-Actor"); actor != "" {
+		return actor
+	}
+	return r.RemoteAddr
+}
+
+// lookupAgent validates the {id} parameter and fetches the agent, writing
+// the error response itself when the lookup fails.
+func (s *Server) lookupAgent(w http.ResponseWriter, r *http.Request) (*registry.Agent, bool) {
+	uid := chi.URLParam(r, "id")
+	if !instanceUIDPattern.MatchString(uid) {
+		writeError(w, http.StatusBadRequest, "invalid instance uid")
+		return nil, false
+	}
+	// Canonicalize so operators can use any UUID spelling (dashed, bare hex,
+	// uppercase) and still hit the key the opamp server registered under.
+	uid = registry.NormalizeUID(uid)
+	agent, err := s.registry.Get(uid)
+	if errors.Is(err, registry.ErrNotFound) {
+		writeError(w, http.StatusNotFound, "agent not found")
+		return nil, false
+	}
+	if err != nil {
+		s.internalError(w, "get agent", err)
+		return nil, false
+	}
+	return agent, true
+}
+
+func (s *Server) internalError(w http.ResponseWriter, op string, err error) {
+	s.logger.Error("api request failed", "op", op, "error", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func queryInt(r *http.Request, name string, def int) int {
+	if v := r.URL.Query().Get(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// queryTime parses an RFC 3339 timestamp query parameter. The second return
+// is false when the parameter is present but malformed; an absent parameter
+// yields a zero time.
+func queryTime(r *http.Request, name string) (time.Time, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// streamKeepalive is how often an SSE comment is written to an idle stream,
+// so proxies and load balancers do not cut the connection.
+const streamKeepalive = 30 * time.Second
+
+// EventStream fans registry events out to SSE subscribers. Publish is wired
+// as (part of) the registry's event hook and must not block, so each
+// subscriber gets a buffered channel and a slow consumer drops events rather
+// than stalling the fleet.
+type EventStream struct {
+	mu   sync.Mutex
+	subs map[chan *registry.Event]struct{}
+}
+
+// NewEventStream returns an EventStream with no subscribers.
+func NewEventStream() *EventStream {
+	return &EventStream{subs: map[chan *registry.Event]struct{}{}}
+}
+
+// Publish forwards an event to every subscriber. Never blocks.
+func (es *EventStream) Publish(e *registry.Event) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	for ch := range es.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (es *EventStream) subscribe() chan *registry.Event {
+	ch := make(chan *registry.Event, 64)
+	es.mu.Lock()
+	es.subs[ch] = struct{}{}
+	es.mu.Unlock()
+	return ch
+}
+
+func (es *EventStream) unsubscribe(ch chan *registry.Event) {
+	es.mu.Lock()
+	delete(es.subs, ch)
+	es.mu.Unlock()
+}
+
+// handleEventStream streams registry events as server-sent events, so
+// dashboards and automations follow the fleet without polling. A "types"
+// query parameter with a comma-separated list limits the stream to those
+// event types.
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	var types map[string]bool
+	if raw := r.URL.Query().Get("types"); raw != "" {
+		types = map[string]bool{}
+		for _, t := range strings.Split(raw, ",") {
+			types[strings.TrimSpace(t)] = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+	keepalive := time.NewTicker(streamKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		case event := <-ch:
+			if types != nil && !types[event.Type] {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/config"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// selectorSuggestion is a candidate selector match block derived from a
+// group of agents that currently fall back to the base config.
+type selectorSuggestion struct {
+	// Match is the label combination shared by the group, usable verbatim
+	// as a selector's match block.
+	Match map[string]string `json:"match"`
+	// Agents is how many unmatched agents share this combination.
+	Agents int `json:"agents"`
+	// Sample lists up to five of their instance UIDs.
+	Sample []string `json:"sample"`
+}
+
+// handleSelectorSuggestions clusters agents that no selector matches by
+// their label combinations and proposes candidate match blocks, to speed up
+// onboarding new fleets into explicit configs.
+func (s *Server) handleSelectorSuggestions(w http.ResponseWriter, r *http.Request) {
+	tenant := r.URL.Query().Get("tenant")
+	agents, err := s.registry.List(registry.AgentFilter{Tenant: tenant})
+	if err != nil {
+		s.internalError(w, "list agents", err)
+		return
+	}
+	matcher := config.NewSelectorMatcher(s.store.Selectors(tenant))
+
+	clusters := map[string]*selectorSuggestion{}
+	for _, agent := range agents {
+		if matcher.Match(agent.Labels, agent.Description) != nil {
+			continue
+		}
+		key := labelsKey(agent.Labels)
+		cluster, ok := clusters[key]
+		if !ok {
+			match := make(map[string]string, len(agent.Labels))
+			for k, v := range agent.Labels {
+				match[k] = v
+			}
+			cluster = &selectorSuggestion{Match: match}
+			clusters[key] = cluster
+		}
+		cluster.Agents++
+		if len(cluster.Sample) < 5 {
+			cluster.Sample = append(cluster.Sample, agent.InstanceUID)
+		}
+	}
+
+	out := make([]*selectorSuggestion, 0, len(clusters))
+	for _, cluster := range clusters {
+		out = append(out, cluster)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Agents != out[j].Agents {
+			return out[i].Agents > out[j].Agents
+		}
+		return labelsKey(out[i].Match) < labelsKey(out[j].Match)
+	})
+	writeJSON(w, http.StatusOK, map[string]any{"suggestions": out})
+}
+
+// labelsKey renders a label map into a canonical string for grouping.
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s,", k, labels[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// wsSummaryInterval is how often a fleet summary frame is pushed to every
+// dashboard connection, independent of event traffic.
+const wsSummaryInterval = 10 * time.Second
+
+// wsUpgrader upgrades dashboard connections. Origin is not checked: the API
+// carries no browser credentials and dashboards are commonly served from a
+// different host than the control plane.
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// wsFilter is the per-connection subscription filter. The zero value passes
+// everything; clients narrow it with the initial query parameters or by
+// sending a filter frame at any time.
+type wsFilter struct {
+	// Types limits event frames to the listed event types.
+	Types []string `json:"types"`
+	// InstanceUID limits event frames to one agent.
+	InstanceUID string `json:"instance_uid"`
+}
+
+func (f wsFilter) allows(e *registry.Event) bool {
+	if f.InstanceUID != "" && e.InstanceUID != f.InstanceUID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// wsFrame is the envelope for everything sent to a dashboard: either one
+// registry event or a periodic fleet summary.
+type wsFrame struct {
+	Kind    string          `json:"kind"`
+	Event   *registry.Event `json:"event,omitempty"`
+	Summary *fleetSummary   `json:"summary,omitempty"`
+}
+
+// fleetSummary is a point-in-time census of the fleet for dashboards.
+type fleetSummary struct {
+	Agents      int            `json:"agents"`
+	ByStatus    map[string]int `json:"by_status"`
+	ByState     map[string]int `json:"by_config_state"`
+	ConfigHash  string         `json:"config_commit,omitempty"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+func (s *Server) summarizeFleet() (*fleetSummary, error) {
+	agents, err := s.registry.List(registry.AgentFilter{Limit: 10000})
+	if err != nil {
+		return nil, err
+	}
+	summary := &fleetSummary{
+		Agents:      len(agents),
+		ByStatus:    map[string]int{},
+		ByState:     map[string]int{},
+		ConfigHash:  s.store.Commit(),
+		GeneratedAt: time.Now().UTC(),
+	}
+	for _, agent := range agents {
+		summary.ByStatus[string(agent.Status)]++
+		if agent.ConfigState != "" {
+			summary.ByState[string(agent.ConfigState)]++
+		}
+	}
+	return summary, nil
+}
+
+// handleEventsWS streams agent state changes and periodic fleet summaries
+// over a WebSocket, for live dashboards. The initial filter comes from the
+// "types" (comma-separated) and "instance_uid" query parameters; the client
+// can replace it at any time by sending a JSON frame in the wsFilter shape.
+func (s *Server) handleEventsWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already wrote the HTTP error.
+		return
+	}
+	defer conn.Close()
+
+	var mu sync.Mutex
+	filter := wsFilter{InstanceUID: r.URL.Query().Get("instance_uid")}
+	if raw := r.URL.Query().Get("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			filter.Types = append(filter.Types, strings.TrimSpace(t))
+		}
+	}
+
+	// Reader: the only inbound frames are filter updates; a read error means
+	// the client went away.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var next wsFilter
+			if err := conn.ReadJSON(&next); err != nil {
+				return
+			}
+			mu.Lock()
+			filter = next
+			mu.Unlock()
+		}
+	}()
+
+	send := func(frame wsFrame) bool {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(frame) == nil
+	}
+	if summary, err := s.summarizeFleet(); err == nil {
+		if !send(wsFrame{Kind: "summary", Summary: summary}) {
+			return
+		}
+	}
+
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+	summaries := time.NewTicker(wsSummaryInterval)
+	defer summaries.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-done:
+			return
+		case <-summaries.C:
+			summary, err := s.summarizeFleet()
+			if err != nil {
+				s.logger.Warn("fleet summary failed", "error", err)
+				continue
+			}
+			if !send(wsFrame{Kind: "summary", Summary: summary}) {
+				return
+			}
+		case event := <-ch:
+			mu.Lock()
+			allowed := filter.allows(event)
+			mu.Unlock()
+			if !allowed {
+				continue
+			}
+			if !send(wsFrame{Kind: "event", Event: event}) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package certs signs agent certificate signing requests with a local CA,
+// backing the OpAMP client certificate provisioning flow.
+package certs
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+)
+
+// CA is a certificate authority that issues client certificates from CSRs.
+type CA struct {
+	cert     *x509.Certificate
+	key      crypto.Signer
+	certPEM  []byte
+	validity time.Duration
+}
+
+// Load reads a PEM-encoded CA certificate and private key from disk. Issued
+// certificates are valid for the given duration.
+func Load(certPath, keyPath string, validity time.Duration) (*CA, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("read ca certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read ca key: %w", err)
+	}
+	return New(certPEM, keyPEM, validity)
+}
+
+// New builds a CA from PEM-encoded certificate and private key bytes.
+func New(certPEM, keyPEM []byte, validity time.Duration) (*CA, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("ca certificate: no CERTIFICATE block found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse ca certificate: %w", err)
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parse ca key: %w", err)
+	}
+	return &CA{cert: cert, key: key, certPEM: certPEM, validity: validity}, nil
+}
+
+// CertPEM returns the CA certificate in PEM form, for delivery to agents as
+// the trust anchor alongside their issued certificate.
+func (ca *CA) CertPEM() []byte {
+	return ca.certPEM
+}
+
+// Sign validates a PEM-encoded CSR and issues a client certificate for it.
+// The subject and SANs are taken from the CSR; key usage is restricted to
+// client authentication.
+func (ca *CA) Sign(csrPEM []byte) (certPEM []byte, notAfter time.Time, err error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, time.Time{}, fmt.Errorf("no CERTIFICATE REQUEST block found")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("parse csr: %w", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, time.Time{}, fmt.Errorf("csr signature invalid: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("generate serial: %w", err)
+	}
+	now := time.Now()
+	notAfter = now.Add(ca.validity)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		IPAddresses:  csr.IPAddresses,
+		URIs:         csr.URIs,
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, csr.PublicKey, ca.key)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("sign certificate: %w", err)
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return certPEM, notAfter, nil
+}
+
+// parsePrivateKey handles the common PEM private key encodings.
+func parsePrivateKey(keyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key does not implement crypto.Signer")
+	}
+	return signer, nil
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseAPISelector parses one API-managed selector definition: the same YAML
+// shape as an entry in the selectors file. The stored name wins over any
+// name inside the definition so renames cannot happen by accident.
+func ParseAPISelector(name string, definition []byte) (Selector, error) {
+	var sel Selector
+	if err := yaml.Unmarshal(definition, &sel); err != nil {
+		return Selector{}, fmt.Errorf("parse selector %s: %w", name, err)
+	}
+	sel.Name = name
+	return sel, nil
+}
+
+// SetExtraSelectors replaces the store's API-managed selectors. They are
+// merged into the root config tree's selectors, shadowing a git-defined
+// selector of the same name, and survive LoadConfigs reloads. The generation
+// is bumped so resolver caches invalidate.
+func (s *Store) SetExtraSelectors(selectors []Selector) {
+	extras := make([]Selector, len(selectors))
+	copy(extras, selectors)
+	s.mu.Lock()
+	s.extraSelectors = extras
+	s.generation++
+	s.mu.Unlock()
+}
@@ -0,0 +1,58 @@
+package config
+
+import "testing"
+
+func TestParseAPISelector(t *testing.T) {
+	sel, err := ParseAPISelector("edge", []byte("name: ignored\npriority: 5\nmatch:\n  labels:\n    role: edge\nconfig: configs/edge.yaml\n"))
+	if err != nil {
+		t.Fatalf("ParseAPISelector: %v", err)
+	}
+	if sel.Name != "edge" {
+		t.Errorf("Name = %q, the stored name should win over the definition", sel.Name)
+	}
+	if sel.Priority != 5 || sel.Match.Labels["role"] != "edge" || sel.Config != "configs/edge.yaml" {
+		t.Errorf("parsed selector = %+v", sel)
+	}
+	if _, err := ParseAPISelector("bad", []byte("{")); err == nil {
+		t.Error("ParseAPISelector accepted invalid YAML")
+	}
+}
+
+func TestSetExtraSelectors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"_selectors.yaml": "selectors:\n  - name: edge\n    config: configs/git.yaml\n  - name: core\n    config: configs/core.yaml\n",
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	gen := store.Generation()
+	store.SetExtraSelectors([]Selector{{Name: "edge", Config: "configs/api.yaml"}, {Name: "extra", Config: "configs/extra.yaml"}})
+	if store.Generation() == gen {
+		t.Error("SetExtraSelectors did not bump the generation")
+	}
+
+	check := func() {
+		t.Helper()
+		byName := map[string]string{}
+		for _, sel := range store.SelectorsAt("") {
+			byName[sel.Name] = sel.Config
+		}
+		if len(byName) != 3 {
+			t.Fatalf("SelectorsAt returned %v, want edge, core and extra", byName)
+		}
+		if byName["edge"] != "configs/api.yaml" {
+			t.Errorf("edge = %q, the API-managed selector should shadow the git one", byName["edge"])
+		}
+		if byName["extra"] != "configs/extra.yaml" || byName["core"] != "configs/core.yaml" {
+			t.Errorf("SelectorsAt = %v", byName)
+		}
+	}
+	check()
+
+	// Extras survive a snapshot reload.
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	check()
+}
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileDiff is one changed file in a proposed change set.
+type FileDiff struct {
+	Path string `json:"path"`
+	// Status is "added", "removed", or "modified".
+	Status string `json:"status"`
+	Diff   string `json:"diff"`
+}
+
+// ChangeSet is a synced commit held for operator approval: what changed on
+// disk, which selectors the change reaches, and how many agents that is.
+type ChangeSet struct {
+	ID     string `json:"id"`
+	Commit string `json:"commit,omitempty"`
+	// State is "pending", "approved", "rejected", or "superseded" (a newer
+	// sync replaced it before a decision).
+	State string     `json:"state"`
+	Files []FileDiff `json:"files"`
+	// Selectors are the affected selectors as "name" for the default tenant
+	// and "tenant/name" otherwise.
+	Selectors      []string  `json:"selectors"`
+	AffectedAgents int       `json:"affected_agents"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// ChangeQueue gates config reloads behind operator approval. Propose diffs
+// a freshly synced tree against the current snapshot and parks the result;
+// nothing is loaded or pushed until Approve fires the registered callback.
+// At most one change set is pending: a newer sync supersedes it.
+type ChangeQueue struct {
+	store  *Store
+	logger *slog.Logger
+
+	// countAgents, when set, estimates how many agents the affected
+	// selectors currently cover.
+	countAgents func(selectors []string) int
+	// onApprove applies an approved change set's commit.
+	onApprove func(commit string)
+
+	mu      sync.Mutex
+	seq     int
+	pending *ChangeSet
+	history []*ChangeSet
+}
+
+// NewChangeQueue returns a ChangeQueue diffing against store's snapshot.
+func NewChangeQueue(store *Store, logger *slog.Logger) *ChangeQueue {
+	return &ChangeQueue{store: store, logger: logger}
+}
+
+// SetAgentCounter registers the affected-agent estimator.
+func (q *ChangeQueue) SetAgentCounter(fn func(selectors []string) int) {
+	q.countAgents = fn
+}
+
+// OnApprove registers the callback invoked with the change set's commit
+// when an operator approves it. Must be set before Propose.
+func (q *ChangeQueue) OnApprove(fn func(commit string)) {
+	q.onApprove = fn
+}
+
+// Propose diffs the tree at dir against the current snapshot and parks the
+// result as the pending change set. It returns nil when the tree is
+// identical to the snapshot, in which case no approval is needed.
+func (q *ChangeQueue) Propose(dir, commit string) (*ChangeSet, error) {
+	next, err := q.store.readTree(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", dir, err)
+	}
+	current := map[string][]byte{}
+	for _, name := range q.store.Files() {
+		data, _ := q.store.File(name)
+		current[name] = data
+	}
+
+	diffs := diffTrees(current, next)
+	if len(diffs) == 0 {
+		return nil, nil
+	}
+	var changed []string
+	for _, d := range diffs {
+		changed = append(changed, d.Path)
+	}
+	selectors := affectedSelectors(next, changed)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending != nil {
+		q.pending.State = "superseded"
+		q.history = append(q.history, q.pending)
+	}
+	q.seq++
+	cs := &ChangeSet{
+		ID:        fmt.Sprintf("change-%d", q.seq),
+		Commit:    commit,
+		State:     "pending",
+		Files:     diffs,
+		Selectors: selectors,
+		CreatedAt: time.Now().UTC(),
+	}
+	if q.countAgents != nil {
+		cs.AffectedAgents = q.countAgents(selectors)
+	}
+	q.pending = cs
+	return cs, nil
+}
+
+// Pending returns the change set awaiting a decision, if any.
+func (q *ChangeQueue) Pending() *ChangeSet {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending
+}
+
+// Approve applies the pending change set: the registered callback reloads
+// the snapshot and triggers pushes.
+func (q *ChangeQueue) Approve(id string) error {
+	q.mu.Lock()
+	cs := q.pending
+	if cs == nil || cs.ID != id {
+		q.mu.Unlock()
+		return fmt.Errorf("no pending change set %s", id)
+	}
+	cs.State = "approved"
+	q.history = append(q.history, cs)
+	q.pending = nil
+	fn := q.onApprove
+	q.mu.Unlock()
+	if fn != nil {
+		fn(cs.Commit)
+	}
+	return nil
+}
+
+// Reject discards the pending change set; the snapshot stays as it is
+// until the next sync proposes a new one.
+func (q *ChangeQueue) Reject(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending == nil || q.pending.ID != id {
+		return fmt.Errorf("no pending change set %s", id)
+	}
+	q.pending.State = "rejected"
+	q.history = append(q.history, q.pending)
+	q.pending = nil
+	return nil
+}
+
+// diffTrees returns per-file diffs between two path→contents maps, sorted
+// by path.
+func diffTrees(current, next map[string][]byte) []FileDiff {
+	var diffs []FileDiff
+	for path, data := range next {
+		old, ok := current[path]
+		switch {
+		case !ok:
+			diffs = append(diffs, FileDiff{Path: path, Status: "added", Diff: UnifiedDiff(nil, data, "current", "proposed")})
+		case string(old) != string(data):
+			diffs = append(diffs, FileDiff{Path: path, Status: "modified", Diff: UnifiedDiff(old, data, "current", "proposed")})
+		}
+	}
+	for path, old := range current {
+		if _, ok := next[path]; !ok {
+			diffs = append(diffs, FileDiff{Path: path, Status: "removed", Diff: UnifiedDiff(old, nil, "current", "proposed")})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+// affectedSelectors parses the selectors of the proposed tree and returns
+// the ones whose file stack, tenant vars, base config, or selectors file
+// changed.
+func affectedSelectors(next map[string][]byte, changed []string) []string {
+	// Group changed paths by tenant, stripping the tenant prefix.
+	changedByTenant := map[string][]string{}
+	for _, path := range changed {
+		tenant := ""
+		rel := path
+		if rest, ok := strings.CutPrefix(path, TenantsDir+"/"); ok {
+			if t, file, ok := strings.Cut(rest, "/"); ok {
+				tenant, rel = t, file
+			}
+		}
+		changedByTenant[tenant] = append(changedByTenant[tenant], rel)
+	}
+
+	var affected []string
+	for name, raw := range next {
+		tenant, ok := tenantOfSelectorsFile(name)
+		if !ok {
+			continue
+		}
+		paths := changedByTenant[tenant]
+		if len(paths) == 0 {
+			continue
+		}
+		var sf selectorsFile
+		if err := yaml.Unmarshal(raw, &sf); err != nil {
+			continue
+		}
+		// A selectors file or vars change can reroute or re-render anything
+		// in the tenant.
+		tenantWide := false
+		for _, p := range paths {
+			if p == SelectorsFile || strings.HasPrefix(p, VarsDir+"/") {
+				tenantWide = true
+				break
+			}
+		}
+		for _, sel := range sf.Selectors {
+			files := map[string]bool{BaseConfigFile: true}
+			if stack, ok := sf.Layers[sel.Layers]; sel.Layers != "" && ok {
+				for _, f := range stack {
+					files[f] = true
+				}
+			}
+			if sel.Config != "" {
+				files[sel.Config] = true
+			}
+			for _, f := range sel.Overlays {
+				files[f] = true
+			}
+			hit := tenantWide
+			for _, p := range paths {
+				if files[p] {
+					hit = true
+					break
+				}
+			}
+			if hit {
+				key := sel.Name
+				if tenant != "" {
+					key = tenant + "/" + sel.Name
+				}
+				affected = append(affected, key)
+			}
+		}
+	}
+	sort.Strings(affected)
+	return affected
+}
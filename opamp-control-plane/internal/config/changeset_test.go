@@ -0,0 +1,106 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestChangeQueueProposeApprove(t *testing.T) {
+	current := writeFiles(t, map[string]string{
+		"_selectors.yaml":   "selectors:\n  - name: prod\n    match: {labels: {env: prod}}\n    config: configs/prod.yaml\n  - name: dev\n    match: {labels: {env: dev}}\n    config: configs/dev.yaml\n",
+		"configs/prod.yaml": "receivers: {otlp: {}}\n",
+		"configs/dev.yaml":  "receivers: {otlp: {}}\n",
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(current); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	queue := NewChangeQueue(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	queue.SetAgentCounter(func(selectors []string) int { return 7 * len(selectors) })
+	var approvedCommit string
+	queue.OnApprove(func(commit string) { approvedCommit = commit })
+
+	// An identical tree needs no approval.
+	cs, err := queue.Propose(current, "same")
+	if err != nil {
+		t.Fatalf("Propose: %v", err)
+	}
+	if cs != nil {
+		t.Fatalf("expected no change set for identical tree, got %+v", cs)
+	}
+
+	next := writeFiles(t, map[string]string{
+		"_selectors.yaml":   "selectors:\n  - name: prod\n    match: {labels: {env: prod}}\n    config: configs/prod.yaml\n  - name: dev\n    match: {labels: {env: dev}}\n    config: configs/dev.yaml\n",
+		"configs/prod.yaml": "receivers: {otlp: {}, filelog: {}}\n",
+		"configs/dev.yaml":  "receivers: {otlp: {}}\n",
+	})
+	cs, err = queue.Propose(next, "abc123")
+	if err != nil {
+		t.Fatalf("Propose: %v", err)
+	}
+	if cs == nil {
+		t.Fatal("expected a pending change set")
+	}
+	if len(cs.Files) != 1 || cs.Files[0].Path != "configs/prod.yaml" || cs.Files[0].Status != "modified" {
+		t.Fatalf("unexpected file diffs: %+v", cs.Files)
+	}
+	if !strings.Contains(cs.Files[0].Diff, "filelog") {
+		t.Errorf("diff does not show the change: %s", cs.Files[0].Diff)
+	}
+	if len(cs.Selectors) != 1 || cs.Selectors[0] != "prod" {
+		t.Fatalf("expected only prod affected, got %v", cs.Selectors)
+	}
+	if cs.AffectedAgents != 7 {
+		t.Errorf("expected 7 affected agents, got %d", cs.AffectedAgents)
+	}
+
+	if err := queue.Approve("change-wrong"); err == nil {
+		t.Fatal("expected approving an unknown id to fail")
+	}
+	if err := queue.Approve(cs.ID); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if approvedCommit != "abc123" {
+		t.Fatalf("approve callback got commit %q", approvedCommit)
+	}
+	if queue.Pending() != nil {
+		t.Fatal("change set still pending after approval")
+	}
+}
+
+func TestChangeQueueRejectAndSupersede(t *testing.T) {
+	current := writeFiles(t, map[string]string{"base.yaml": "receivers: {otlp: {}}\n"})
+	store := NewStore()
+	if err := store.LoadConfigs(current); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	queue := NewChangeQueue(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	next := writeFiles(t, map[string]string{"base.yaml": "receivers: {jaeger: {}}\n"})
+	first, err := queue.Propose(next, "c1")
+	if err != nil || first == nil {
+		t.Fatalf("Propose: %v %v", first, err)
+	}
+
+	// A newer sync supersedes the pending set.
+	newer := writeFiles(t, map[string]string{"base.yaml": "receivers: {zipkin: {}}\n"})
+	second, err := queue.Propose(newer, "c2")
+	if err != nil || second == nil {
+		t.Fatalf("Propose: %v %v", second, err)
+	}
+	if first.State != "superseded" {
+		t.Fatalf("expected first change set superseded, got %s", first.State)
+	}
+	if err := queue.Approve(first.ID); err == nil {
+		t.Fatal("expected approving a superseded change set to fail")
+	}
+
+	if err := queue.Reject(second.ID); err != nil {
+		t.Fatalf("Reject: %v", err)
+	}
+	if queue.Pending() != nil {
+		t.Fatal("change set still pending after rejection")
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := []byte("receivers:\n  otlp: {}\nexporters:\n  otlp:\n    endpoint: old:4317\n")
+	b := []byte("receivers:\n  otlp: {}\nexporters:\n  otlp:\n    endpoint: new:4317\n")
+	diff := UnifiedDiff(a, b, "running", "desired")
+
+	if !strings.HasPrefix(diff, "--- running\n+++ desired\n") {
+		t.Errorf("missing header:\n%s", diff)
+	}
+	if !strings.Contains(diff, "-    endpoint: old:4317\n") {
+		t.Errorf("missing removed line:\n%s", diff)
+	}
+	if !strings.Contains(diff, "+    endpoint: new:4317\n") {
+		t.Errorf("missing added line:\n%s", diff)
+	}
+	if !strings.Contains(diff, " receivers:\n") {
+		t.Errorf("missing context line:\n%s", diff)
+	}
+}
+
+func TestUnifiedDiffIdentical(t *testing.T) {
+	doc := []byte("receivers:\n  otlp: {}\n")
+	if diff := UnifiedDiff(doc, doc, "a", "b"); diff != "" {
+		t.Errorf("identical inputs produced a diff:\n%s", diff)
+	}
+}
+
+func TestUnifiedDiffFromEmpty(t *testing.T) {
+	diff := UnifiedDiff(nil, []byte("receivers:\n  otlp: {}\n"), "running", "desired")
+	if !strings.Contains(diff, "+receivers:\n") {
+		t.Errorf("all lines should be additions:\n%s", diff)
+	}
+	if strings.Contains(diff, "\n-") {
+		t.Errorf("unexpected removals diffing from empty:\n%s", diff)
+	}
+}
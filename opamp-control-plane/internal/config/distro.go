@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DistrosFile is the well-known name of the distribution manifests file. It
+// sits next to the selectors file and declares, per named distribution, the
+// component types that distribution ships.
+const DistrosFile = "_distros.yaml"
+
+// DistroManifest lists the component types one collector distribution
+// ships, per section. A selector declaring the distro gets its merged
+// configs checked against the manifest, so a config referencing a
+// contrib-only receiver is rejected before it reaches a core-distro agent.
+type DistroManifest struct {
+	Receivers  []string `yaml:"receivers"`
+	Processors []string `yaml:"processors"`
+	Exporters  []string `yaml:"exporters"`
+	Extensions []string `yaml:"extensions"`
+}
+
+type distrosFile struct {
+	Distros map[string]DistroManifest `yaml:"distros"`
+}
+
+// Check returns a problem per component in cfg whose type the distribution
+// does not ship. Component IDs are matched by their type, the part before
+// any "/" instance suffix.
+func (m DistroManifest) Check(cfg []byte) []string {
+	var root map[string]any
+	if err := yaml.Unmarshal(cfg, &root); err != nil {
+		// The structural validator already rejected unparsable configs.
+		return nil
+	}
+	shipped := map[string]map[string]bool{
+		"receivers":  stringSet(m.Receivers),
+		"processors": stringSet(m.Processors),
+		"exporters":  stringSet(m.Exporters),
+		"extensions": stringSet(m.Extensions),
+	}
+	var problems []string
+	for _, section := range []string{"receivers", "processors", "exporters", "extensions"} {
+		ids := componentIDs(root[section])
+		for _, id := range sortedSetKeys(ids) {
+			kind, _, _ := strings.Cut(id, "/")
+			if !shipped[section][kind] {
+				problems = append(problems, fmt.Sprintf("%s %q is not shipped by the distribution",
+					strings.TrimSuffix(section, "s"), id))
+			}
+		}
+	}
+	return problems
+}
+
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func sortedSetKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DistroAt returns the named distribution manifest of the config tree rooted
+// at prefix.
+func (s *Store) DistroAt(prefix, name string) (DistroManifest, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	manifest, ok := s.distros[prefix][name]
+	return manifest, ok
+}
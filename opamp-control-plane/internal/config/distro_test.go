@@ -0,0 +1,58 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+func TestDistroManifestCheck(t *testing.T) {
+	m := DistroManifest{
+		Receivers: []string{"otlp"},
+		Exporters: []string{"debug", "otlphttp"},
+	}
+	cfg := []byte(`
+receivers:
+  otlp/2: {}
+  filelog: {}
+exporters:
+  debug: {}
+`)
+	problems := m.Check(cfg)
+	if len(problems) != 1 || !strings.Contains(problems[0], "filelog") {
+		t.Fatalf("problems = %v, want one about filelog", problems)
+	}
+}
+
+func TestResolveChecksSelectorDistro(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": "receivers: {filelog: {}}\nexporters: {debug: {}}\nservice: {pipelines: {logs: {receivers: [filelog], exporters: [debug]}}}\n",
+		"_distros.yaml": `distros:
+  core:
+    receivers: [otlp]
+    exporters: [debug]
+`,
+		"_selectors.yaml": `selectors:
+  - name: core-fleet
+    match:
+      labels:
+        distro: core
+    config: base.yaml
+    distro: core
+`,
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	resolver := NewResolver(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	agent := &registry.Agent{InstanceUID: "agent-1", Labels: map[string]string{"distro": "core"}}
+	_, err := resolver.Resolve(agent)
+	if err == nil || !strings.Contains(err.Error(), "filelog") {
+		t.Fatalf("expected distro check failure mentioning filelog, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Hasher computes the hash identifying a resolved config. Agents echo the
+// hash back in their RemoteConfigStatus, and the push scheduler skips agents
+// whose reported hash already matches, so what goes into the hash decides
+// what counts as "the same config".
+type Hasher struct {
+	algorithm string
+}
+
+// NewHasher returns a Hasher using the given algorithm: "sha256" (the
+// default when empty) or "sha512".
+func NewHasher(algorithm string) (*Hasher, error) {
+	switch algorithm {
+	case "", "sha256":
+		algorithm = "sha256"
+	case "sha512":
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm %q", algorithm)
+	}
+	return &Hasher{algorithm: algorithm}, nil
+}
+
+func (h *Hasher) new() hash.Hash {
+	if h.algorithm == "sha512" {
+		return sha512.New()
+	}
+	return sha256.New()
+}
+
+// Sum hashes a resolved config. The selector name and the source file stack
+// are part of the identity, so moving identical bytes between selectors or
+// restructuring the overlay stack offers the config again. YAML bodies are
+// canonicalized first — decoded and re-encoded, which sorts map keys — so
+// reordering keys without changing the document does not change the hash.
+func (h *Hasher) Sum(selector string, sources []string, contentType string, body []byte) string {
+	hw := h.new()
+	fmt.Fprintf(hw, "%s\x00", selector)
+	for _, src := range sources {
+		fmt.Fprintf(hw, "%s\x00", src)
+	}
+	hw.Write(canonicalBody(contentType, body))
+	return hex.EncodeToString(hw.Sum(nil))
+}
+
+// canonicalBody returns the canonical form of a YAML body, or the body
+// unchanged for other content types (their rendering is already canonical)
+// and for YAML that does not parse.
+func canonicalBody(contentType string, body []byte) []byte {
+	if contentType != "text/yaml" {
+		return body
+	}
+	var v any
+	if err := yaml.Unmarshal(body, &v); err != nil {
+		return body
+	}
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return out
+}
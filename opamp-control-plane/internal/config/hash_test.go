@@ -0,0 +1,45 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHasherStableAcrossKeyReordering(t *testing.T) {
+	h, err := NewHasher("")
+	if err != nil {
+		t.Fatalf("NewHasher: %v", err)
+	}
+	a := []byte("receivers:\n  otlp: {}\nexporters:\n  debug: {}\n")
+	b := []byte("exporters:\n  debug: {}\nreceivers:\n  otlp: {}\n")
+	sources := []string{"base.yaml"}
+	if h.Sum("prod", sources, "text/yaml", a) != h.Sum("prod", sources, "text/yaml", b) {
+		t.Error("reordered YAML keys changed the hash")
+	}
+}
+
+func TestHasherIdentityIncludesSelectorAndSources(t *testing.T) {
+	h, _ := NewHasher("sha256")
+	body := []byte("receivers: {otlp: {}}\n")
+	base := h.Sum("prod", []string{"base.yaml"}, "text/yaml", body)
+	if h.Sum("dev", []string{"base.yaml"}, "text/yaml", body) == base {
+		t.Error("selector name is not part of the hash")
+	}
+	if h.Sum("prod", []string{"base.yaml", "eu.yaml"}, "text/yaml", body) == base {
+		t.Error("source stack is not part of the hash")
+	}
+}
+
+func TestHasherAlgorithms(t *testing.T) {
+	h512, err := NewHasher("sha512")
+	if err != nil {
+		t.Fatalf("NewHasher(sha512): %v", err)
+	}
+	sum := h512.Sum("prod", nil, "text/yaml", []byte("a: 1\n"))
+	if len(sum) != 128 {
+		t.Errorf("sha512 hex length = %d, want 128", len(sum))
+	}
+	if _, err := NewHasher("md5"); err == nil || !strings.Contains(err.Error(), "md5") {
+		t.Errorf("expected error for unknown algorithm, got %v", err)
+	}
+}
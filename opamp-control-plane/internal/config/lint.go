@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// otelTopLevelKeys are the sections a collector config may contain at the
+// top level.
+var otelTopLevelKeys = map[string]bool{
+	"receivers":  true,
+	"processors": true,
+	"exporters":  true,
+	"extensions": true,
+	"connectors": true,
+	"service":    true,
+}
+
+// otelServiceKeys are the keys allowed under the service section.
+var otelServiceKeys = map[string]bool{
+	"extensions": true,
+	"pipelines":  true,
+	"telemetry":  true,
+}
+
+// LintProblem is one suspicious key found while linting an overlay.
+type LintProblem struct {
+	File    string `json:"file"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+func (p LintProblem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.File, p.Key, p.Message)
+}
+
+// LintOverlay checks one overlay document in isolation for keys that exist
+// neither in the OTel config schema nor in the base document it will be
+// merged onto. Deep-merge silently accepts unknown keys, so a typo like
+// "proccessors:" otherwise only surfaces as a broken agent much later.
+func LintOverlay(name string, overlay, base []byte) []LintProblem {
+	var doc map[string]any
+	if err := yaml.Unmarshal(overlay, &doc); err != nil {
+		return []LintProblem{{File: name, Key: "", Message: "not valid YAML: " + err.Error()}}
+	}
+	var baseDoc map[string]any
+	yaml.Unmarshal(base, &baseDoc)
+
+	var problems []LintProblem
+	for _, key := range sortedKeys(doc) {
+		if otelTopLevelKeys[key] {
+			continue
+		}
+		if _, ok := baseDoc[key]; ok {
+			continue
+		}
+		problems = append(problems, LintProblem{
+			File:    name,
+			Key:     key,
+			Message: unknownKeyMessage(key, otelTopLevelKeys, "top-level section"),
+		})
+	}
+	if service, ok := doc["service"].(map[string]any); ok {
+		baseService, _ := baseDoc["service"].(map[string]any)
+		for _, key := range sortedKeys(service) {
+			if otelServiceKeys[key] {
+				continue
+			}
+			if _, ok := baseService[key]; ok {
+				continue
+			}
+			problems = append(problems, LintProblem{
+				File:    name,
+				Key:     "service." + key,
+				Message: unknownKeyMessage(key, otelServiceKeys, "service key"),
+			})
+		}
+	}
+	return problems
+}
+
+// LintOverlays lints every overlay referenced by a selector, in every
+// tenant, against the base document of its tree.
+func (s *Store) LintOverlays() []LintProblem {
+	var problems []LintProblem
+	tenants := s.Tenants()
+	if len(tenants) == 0 || tenants[0] != "" {
+		tenants = append([]string{""}, tenants...)
+	}
+	for _, tenant := range tenants {
+		prefix := TenantPrefix(tenant)
+		base, _ := s.File(prefix + BaseConfigFile)
+		seen := map[string]bool{}
+		for _, sel := range s.Selectors(tenant) {
+			for _, overlay := range sel.Overlays {
+				path := prefix + overlay
+				if seen[path] {
+					continue
+				}
+				seen[path] = true
+				data, ok := s.File(path)
+				if !ok {
+					continue
+				}
+				problems = append(problems, LintOverlay(path, data, base)...)
+			}
+		}
+	}
+	return problems
+}
+
+// unknownKeyMessage explains the problem, suggesting the closest known key
+// when one is plausibly a typo away.
+func unknownKeyMessage(key string, known map[string]bool, kind string) string {
+	best, bestDist := "", 3
+	for candidate := range known {
+		if d := editDistance(key, candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("unknown %s (did you mean %q?)", kind, best)
+	}
+	return fmt.Sprintf("unknown %s", kind)
+}
+
+// editDistance is the Levenshtein distance between two short keys.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(curr[j-1]+1, prev[j]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,57 @@
+package config
+
+import "testing"
+
+func TestLintOverlayCatchesTypo(t *testing.T) {
+	overlay := []byte("proccessors:\n  batch: {}\n")
+	problems := LintOverlay("overlays/eu.yaml", overlay, nil)
+	if len(problems) != 1 {
+		t.Fatalf("got %d problems, want 1: %v", len(problems), problems)
+	}
+	p := problems[0]
+	if p.Key != "proccessors" {
+		t.Errorf("key = %q, want proccessors", p.Key)
+	}
+	if want := `unknown top-level section (did you mean "processors"?)`; p.Message != want {
+		t.Errorf("message = %q, want %q", p.Message, want)
+	}
+}
+
+func TestLintOverlayAcceptsKnownAndBaseKeys(t *testing.T) {
+	base := []byte("custom_section:\n  a: 1\n")
+	overlay := []byte("receivers:\n  otlp: {}\ncustom_section:\n  a: 2\n")
+	if problems := LintOverlay("o.yaml", overlay, base); len(problems) != 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestLintOverlayServiceKeys(t *testing.T) {
+	overlay := []byte("service:\n  pipelnes:\n    traces: {}\n")
+	problems := LintOverlay("o.yaml", overlay, nil)
+	if len(problems) != 1 {
+		t.Fatalf("got %d problems, want 1: %v", len(problems), problems)
+	}
+	if problems[0].Key != "service.pipelnes" {
+		t.Errorf("key = %q, want service.pipelnes", problems[0].Key)
+	}
+}
+
+func TestLintOverlaysWalksSelectors(t *testing.T) {
+	store := NewStore()
+	dir := writeFiles(t, map[string]string{
+		"_selectors.yaml":   "selectors:\n  - name: s\n    config: configs/a.yaml\n    overlays: [overlays/bad.yaml]\n",
+		"base.yaml":         "receivers:\n  otlp: {}\n",
+		"configs/a.yaml":    "exporters:\n  otlp: {}\n",
+		"overlays/bad.yaml": "exportres:\n  otlp: {}\n",
+	})
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatal(err)
+	}
+	problems := store.LintOverlays()
+	if len(problems) != 1 {
+		t.Fatalf("got %d problems, want 1: %v", len(problems), problems)
+	}
+	if problems[0].File != "overlays/bad.yaml" {
+		t.Errorf("file = %q, want overlays/bad.yaml", problems[0].File)
+	}
+}
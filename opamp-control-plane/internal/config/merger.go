@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Merger deep-merges YAML documents. Later documents win: maps are merged
+// recursively, while scalars and sequences from a later document replace the
+// earlier value outright. A value tagged !delete removes the key from the
+// merge result, so an overlay can drop a default receiver or exporter rather
+// than only adding and overriding. A mapping tagged !replace is taken
+// wholesale instead of deep-merged, so an overlay can rewrite a subtree
+// without inheriting any earlier keys under it. The Kubernetes
+// strategic-merge-patch spellings ($patch: delete, $patch: replace) are
+// accepted as synonyms.
+//
+// An overlay whose document root is a sequence is an RFC 6902 JSON Patch
+// (authored in YAML or JSON) applied to the merge result so far, for
+// surgical edits — list element changes, moves — that deep-merge semantics
+// cannot express.
+//
+// Merging operates on yaml.Node trees rather than plain maps, so comments,
+// anchors, and key order from the source files survive into the merged
+// output — the effective config stays readable for the humans debugging it.
+//
+// Output is deterministic: node trees are walked in document order and no
+// Go map is iterated anywhere on the path from source bytes to merged
+// bytes, so the same inputs produce the same output across runs and Go
+// versions and config hashes only change when the config does.
+type Merger struct{}
+
+// NewMerger returns a Merger.
+func NewMerger() *Merger {
+	return &Merger{}
+}
+
+// Provenance maps dotted key paths in a merged config to the name of the
+// source document that last set the value there. Only leaf values are
+// recorded; intermediate maps built up across documents have no single
+// source.
+type Provenance map[string]string
+
+// Merge combines the documents in order and returns the merged YAML.
+func (m *Merger) Merge(docs ...[]byte) ([]byte, error) {
+	out, _, err := m.MergeTraced(nil, docs...)
+	return out, err
+}
+
+// MergeTraced merges like Merge and additionally reports, for each leaf
+// value in the result, which document last set it. names parallels docs
+// and labels the provenance entries; documents beyond len(names) are
+// labelled by index.
+func (m *Merger) MergeTraced(names []string, docs ...[]byte) ([]byte, Provenance, error) {
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	prov := Provenance{}
+	for i, doc := range docs {
+		layer, err := parseLayer(doc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("merge: parse document %d: %w", i, err)
+		}
+		if layer == nil {
+			continue
+		}
+		name := fmt.Sprintf("document %d", i)
+		if i < len(names) {
+			name = names[i]
+		}
+		if layer.Kind == yaml.SequenceNode {
+			ops, err := decodePatch(layer)
+			if err != nil {
+				return nil, nil, fmt.Errorf("merge: parse document %d: %w", i, err)
+			}
+			if err := applyPatch(merged, ops, name, prov); err != nil {
+				return nil, nil, fmt.Errorf("merge: document %d: %w", i, err)
+			}
+			continue
+		}
+		mergeNodes(merged, layer, "", name, prov)
+	}
+	out, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, nil, fmt.Errorf("merge: marshal result: %w", err)
+	}
+	return out, prov, nil
+}
+
+// parseLayer decodes one document into its root node — a mapping to
+// deep-merge or a sequence of RFC 6902 patch operations — or nil for an
+// empty document. An explicit null under a key stays a null: collector
+// configs routinely use "batch:" with no value to mean "enable with
+// defaults".
+func parseLayer(doc []byte) (*yaml.Node, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+	layer := root.Content[0]
+	if layer.Kind != yaml.MappingNode && layer.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("document is not a mapping or a patch")
+	}
+	return layer, nil
+}
+
+// mergeNodes merges the overlay mapping into the base mapping in place.
+// Nested mappings are merged recursively; a !delete-tagged value removes the
+// key; any other overlay value replaces the base value, node and all, so its
+// comments and anchors come along. Keys new to base are appended in overlay
+// order. Each key the overlay sets or removes is recorded in prov under its
+// dotted path, attributed to name.
+func mergeNodes(base, overlay *yaml.Node, path, name string, prov Provenance) {
+	for i := 0; i < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		childPath := key.Value
+		if path != "" {
+			childPath = path + "." + key.Value
+		}
+		idx := mappingIndex(base, key.Value)
+		if value.Tag == "!delete" {
+			if idx >= 0 {
+				base.Content = append(base.Content[:idx], base.Content[idx+2:]...)
+			}
+			prov.dropSubtree(childPath)
+			continue
+		}
+		replace := false
+		if value.Tag == "!replace" {
+			// Strip the directive so it neither survives into the output
+			// nor forces replacement again in a later merge.
+			value.Tag = ""
+			replace = true
+		}
+		switch stripStrategicDirective(value) {
+		case "delete":
+			if idx >= 0 {
+				base.Content = append(base.Content[:idx], base.Content[idx+2:]...)
+			}
+			prov.dropSubtree(childPath)
+			continue
+		case "replace":
+			replace = true
+		}
+		if !replace && idx >= 0 && base.Content[idx+1].Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			mergeNodes(base.Content[idx+1], value, childPath, name, prov)
+			continue
+		}
+		if idx >= 0 {
+			base.Content[idx+1] = value
+		} else {
+			base.Content = append(base.Content, key, value)
+		}
+		prov.dropSubtree(childPath)
+		recordNode(prov, childPath, value, name)
+	}
+}
+
+// stripStrategicDirective removes a Kubernetes-style "$patch" key from a
+// mapping and returns its value ("delete" or "replace"), so
+// strategic-merge-patch documents work alongside the native !delete and
+// !replace tags.
+func stripStrategicDirective(n *yaml.Node) string {
+	if n.Kind != yaml.MappingNode {
+		return ""
+	}
+	idx := mappingIndex(n, "$patch")
+	if idx < 0 {
+		return ""
+	}
+	directive := n.Content[idx+1].Value
+	n.Content = append(n.Content[:idx], n.Content[idx+2:]...)
+	return directive
+}
+
+// mappingIndex returns the Content index of the key node with the given
+// value, or -1.
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// recordNode attributes every leaf under the node to name. Scalars,
+// sequences, and empty mappings are leaves; sequences replace wholesale on
+// merge, so they get a single entry rather than one per element.
+func recordNode(prov Provenance, path string, n *yaml.Node, name string) {
+	if n.Kind == yaml.MappingNode && len(n.Content) > 0 {
+		for i := 0; i < len(n.Content); i += 2 {
+			recordNode(prov, path+"."+n.Content[i].Value, n.Content[i+1], name)
+		}
+		return
+	}
+	prov[path] = name
+}
+
+// dropSubtree removes the entry at path and everything below it, for when a
+// later document replaces or deletes a whole subtree.
+func (p Provenance) dropSubtree(path string) {
+	delete(p, path)
+	prefix := path + "."
+	for k := range p {
+		if strings.HasPrefix(k, prefix) {
+			delete(p, k)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeNestedMaps(t *testing.T) {
+	base := []byte(`
+exporters:
+  otlp:
+    endpoint: collector:4317
+    compression: gzip
+service:
+  pipelines:
+    traces:
+      receivers: [otlp]
+      exporters: [otlp]
+`)
+	overlay := []byte(`
+exporters:
+  otlp:
+    endpoint: eu.collector:4317
+`)
+	out, err := NewMerger().Merge(base, overlay)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	var result map[string]any
+	if err := yaml.Unmarshal(out, &result); err != nil {
+		t.Fatalf("unmarshal merged: %v", err)
+	}
+	otlp := result["exporters"].(map[string]any)["otlp"].(map[string]any)
+	if otlp["endpoint"] != "eu.collector:4317" {
+		t.Errorf("endpoint = %v, want eu.collector:4317", otlp["endpoint"])
+	}
+	if otlp["compression"] != "gzip" {
+		t.Errorf("compression = %v, want gzip (base value should survive)", otlp["compression"])
+	}
+	if !strings.Contains(string(out), "pipelines") {
+		t.Errorf("merged output lost the service section:\n%s", out)
+	}
+}
+
+func TestMergeDeleteMarker(t *testing.T) {
+	base := []byte(`
+receivers:
+  otlp: {}
+  jaeger:
+    protocols:
+      grpc: {}
+processors:
+  batch:
+`)
+	overlay := []byte(`
+receivers:
+  jaeger: !delete
+`)
+	out, err := NewMerger().Merge(base, overlay)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	var result map[string]any
+	if err := yaml.Unmarshal(out, &result); err != nil {
+		t.Fatalf("unmarshal merged: %v", err)
+	}
+	receivers := result["receivers"].(map[string]any)
+	if _, ok := receivers["jaeger"]; ok {
+		t.Errorf("jaeger receiver survived deletion:\n%s", out)
+	}
+	if _, ok := receivers["otlp"]; !ok {
+		t.Errorf("otlp receiver was lost:\n%s", out)
+	}
+	// Explicit nulls are not deletions: "batch:" means enable with defaults.
+	if _, ok := result["processors"].(map[string]any)["batch"]; !ok {
+		t.Errorf("null-valued key was dropped:\n%s", out)
+	}
+}
+
+func TestMergeDeleteMissingKeyIsNoop(t *testing.T) {
+	out, err := NewMerger().Merge(
+		[]byte("receivers:\n  otlp: {}\n"),
+		[]byte("receivers:\n  jaeger: !delete\n"),
+	)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if strings.Contains(string(out), "jaeger") {
+		t.Errorf("delete marker leaked into output:\n%s", out)
+	}
+}
+
+func TestMergeSequencesReplace(t *testing.T) {
+	base := []byte("service:\n  pipelines:\n    traces:\n      receivers: [otlp, jaeger]\n")
+	overlay := []byte("service:\n  pipelines:\n    traces:\n      receivers: [otlp]\n")
+	out, err := NewMerger().Merge(base, overlay)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if strings.Contains(string(out), "jaeger") {
+		t.Errorf("overlay sequence should replace base sequence, got:\n%s", out)
+	}
+}
+
+func TestMergeTracedProvenance(t *testing.T) {
+	base := []byte(`
+exporters:
+  otlp:
+    endpoint: collector:4317
+    compression: gzip
+receivers:
+  jaeger: {}
+`)
+	overlay := []byte(`
+exporters:
+  otlp:
+    endpoint: eu.collector:4317
+receivers:
+  jaeger: !delete
+`)
+	_, prov, err := NewMerger().MergeTraced([]string{"base.yaml", "eu.yaml"}, base, overlay)
+	if err != nil {
+		t.Fatalf("MergeTraced: %v", err)
+	}
+	if got := prov["exporters.otlp.endpoint"]; got != "eu.yaml" {
+		t.Errorf("endpoint provenance = %q, want eu.yaml", got)
+	}
+	if got := prov["exporters.otlp.compression"]; got != "base.yaml" {
+		t.Errorf("compression provenance = %q, want base.yaml", got)
+	}
+	if _, ok := prov["receivers.jaeger"]; ok {
+		t.Error("deleted key kept its provenance entry")
+	}
+}
+
+func TestMergePreservesCommentsAndOrder(t *testing.T) {
+	base := []byte(`# fleet defaults
+receivers:
+  otlp: {}
+exporters:
+  # primary backend
+  otlphttp:
+    endpoint: collector:4318
+service:
+  pipelines:
+    traces:
+      receivers: [otlp]
+      exporters: [otlphttp]
+`)
+	overlay := []byte(`exporters:
+  otlphttp:
+    endpoint: eu.collector:4318
+`)
+	out, err := NewMerger().Merge(base, overlay)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	s := string(out)
+	if !strings.Contains(s, "# fleet defaults") || !strings.Contains(s, "# primary backend") {
+		t.Errorf("comments from the base file were lost:\n%s", s)
+	}
+	if !strings.Contains(s, "eu.collector:4318") {
+		t.Errorf("overlay value missing:\n%s", s)
+	}
+	if strings.Index(s, "receivers:") > strings.Index(s, "service:") {
+		t.Errorf("source key order was not preserved:\n%s", s)
+	}
+}
+
+func TestMergeReplaceDirective(t *testing.T) {
+	base := []byte(`
+processors:
+  batch: {}
+  memory_limiter:
+    limit_mib: 512
+`)
+	overlay := []byte(`
+processors: !replace
+  batch:
+    timeout: 5s
+`)
+	out, err := NewMerger().Merge(base, overlay)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if strings.Contains(string(out), "memory_limiter") {
+		t.Errorf("!replace subtree kept keys from the base:\n%s", out)
+	}
+	if strings.Contains(string(out), "!replace") {
+		t.Errorf("directive leaked into the output:\n%s", out)
+	}
+	if !strings.Contains(string(out), "timeout: 5s") {
+		t.Errorf("replacement content missing:\n%s", out)
+	}
+}
+
+func TestMergeOutputDeterministic(t *testing.T) {
+	base := []byte(`
+receivers:
+  otlp: {}
+  jaeger: {}
+  zipkin: {}
+exporters:
+  otlphttp: {}
+  debug: {}
+service:
+  pipelines:
+    traces:
+      receivers: [otlp, jaeger, zipkin]
+      exporters: [otlphttp]
+`)
+	overlay := []byte(`
+exporters:
+  debug: !delete
+  otlphttp:
+    endpoint: collector:4318
+`)
+	first, err := NewMerger().Merge(base, overlay)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		out, err := NewMerger().Merge(base, overlay)
+		if err != nil {
+			t.Fatalf("Merge: %v", err)
+		}
+		if string(out) != string(first) {
+			t.Fatalf("merge output varied between runs:\n%s\nvs\n%s", first, out)
+		}
+	}
+}
+
+func TestMergeJSONPatchOverlay(t *testing.T) {
+	base := []byte(`
+service:
+  pipelines:
+    traces:
+      receivers: [otlp, jaeger]
+      exporters: [otlphttp]
+`)
+	patch := []byte(`
+- op: remove
+  path: /service/pipelines/traces/receivers/1
+- op: add
+  path: /service/pipelines/traces/exporters/-
+  value: debug
+`)
+	out, err := NewMerger().Merge(base, patch)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if strings.Contains(string(out), "jaeger") {
+		t.Errorf("remove op did not drop the element:\n%s", out)
+	}
+	if !strings.Contains(string(out), "debug") {
+		t.Errorf("add op did not append the element:\n%s", out)
+	}
+}
+
+func TestMergeJSONPatchFailsOnMissingPath(t *testing.T) {
+	base := []byte("receivers: {otlp: {}}\n")
+	patch := []byte("- op: replace\n  path: /exporters/debug\n  value: {}\n")
+	if _, err := NewMerger().Merge(base, patch); err == nil || !strings.Contains(err.Error(), "exporters") {
+		t.Fatalf("expected patch failure, got %v", err)
+	}
+}
+
+func TestMergeStrategicDirectives(t *testing.T) {
+	base := []byte(`
+processors:
+  batch: {}
+  memory_limiter:
+    limit_mib: 512
+`)
+	overlay := []byte(`
+processors:
+  memory_limiter:
+    $patch: delete
+  batch:
+    $patch: replace
+    timeout: 5s
+`)
+	out, err := NewMerger().Merge(base, overlay)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	s := string(out)
+	if strings.Contains(s, "memory_limiter") {
+		t.Errorf("$patch: delete did not remove the key:\n%s", s)
+	}
+	if strings.Contains(s, "$patch") {
+		t.Errorf("directive leaked into the output:\n%s", s)
+	}
+	if !strings.Contains(s, "timeout: 5s") {
+		t.Errorf("replacement content missing:\n%s", s)
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ComponentValidator checks a merged config against real collector component
+// schemas, catching typos inside component settings that the structural
+// validator cannot see.
+type ComponentValidator interface {
+	Validate(cfg []byte) error
+}
+
+// SetBackend wires a component-aware backend into the validator, run after
+// the structural checks pass.
+func (v *Validator) SetBackend(b ComponentValidator) {
+	v.backend = b
+}
+
+// SetValidatorBackend wires a component-aware validation backend (e.g. an
+// otelcol binary) into this resolver's validation step.
+func (r *Resolver) SetValidatorBackend(b ComponentValidator) {
+	r.validator.SetBackend(b)
+}
+
+// OtelcolValidator validates configs by shelling out to a collector binary's
+// validate command, so the exact component set and versions of the deployed
+// distribution decide what is acceptable.
+type OtelcolValidator struct {
+	binary string
+}
+
+// NewOtelcolValidator returns a validator invoking binary, typically a path
+// to the same otelcol build the fleet runs.
+func NewOtelcolValidator(binary string) *OtelcolValidator {
+	return &OtelcolValidator{binary: binary}
+}
+
+// Validate implements ComponentValidator.
+func (o *OtelcolValidator) Validate(cfg []byte) error {
+	tmp, err := os.CreateTemp("", "otelcol-validate-*.yaml")
+	if err != nil {
+		return fmt.Errorf("otelcol validate: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(cfg); err != nil {
+		tmp.Close()
+		return fmt.Errorf("otelcol validate: %w", err)
+	}
+	tmp.Close()
+
+	cmd := exec.Command(o.binary, "validate", "--config", tmp.Name())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		problems := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if len(problems) == 1 && problems[0] == "" {
+			problems = []string{err.Error()}
+		}
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
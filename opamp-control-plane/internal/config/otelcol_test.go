@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func fakeOtelcol(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "otelcol")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOtelcolValidatorAccepts(t *testing.T) {
+	v := NewOtelcolValidator(fakeOtelcol(t, "exit 0\n"))
+	if err := v.Validate([]byte("receivers: {otlp: {}}\n")); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestOtelcolValidatorRejects(t *testing.T) {
+	v := NewOtelcolValidator(fakeOtelcol(t, `echo "error decoding 'exporters': unknown type \"otlphttpx\"" >&2
+exit 1
+`))
+	err := v.Validate([]byte("exporters: {otlphttpx: {}}\n"))
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error type = %T, want *ValidationError", err)
+	}
+	if len(verr.Problems) != 1 || !strings.Contains(verr.Problems[0], "otlphttpx") {
+		t.Errorf("problems = %v", verr.Problems)
+	}
+}
+
+func TestValidatorBackendRunsAfterStructuralChecks(t *testing.T) {
+	v := NewValidator()
+	v.SetBackend(NewOtelcolValidator(fakeOtelcol(t, "echo backend >&2\nexit 1\n")))
+
+	// Structurally broken: the backend must not be consulted.
+	err := v.Validate([]byte("receivers: {otlp: {}}\n"))
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error type = %T, want *ValidationError", err)
+	}
+	for _, p := range verr.Problems {
+		if strings.Contains(p, "backend") {
+			t.Errorf("backend ran on a structurally invalid config: %v", verr.Problems)
+		}
+	}
+
+	// Structurally fine: the backend verdict comes through.
+	sound := []byte(`
+receivers: {otlp: {}}
+exporters: {otlp: {}}
+service:
+  pipelines:
+    traces: {receivers: [otlp], exporters: [otlp]}
+`)
+	err = v.Validate(sound)
+	if !errors.As(err, &verr) || len(verr.Problems) != 1 || verr.Problems[0] != "backend" {
+		t.Errorf("backend verdict not surfaced: %v", err)
+	}
+}
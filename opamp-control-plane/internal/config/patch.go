@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// patchOp is one RFC 6902 operation, authored in YAML or JSON.
+type patchOp struct {
+	Op    string    `yaml:"op"`
+	Path  string    `yaml:"path"`
+	From  string    `yaml:"from"`
+	Value yaml.Node `yaml:"value"`
+}
+
+// decodePatch decodes a sequence node into RFC 6902 operations.
+func decodePatch(seq *yaml.Node) ([]patchOp, error) {
+	var ops []patchOp
+	if err := seq.Decode(&ops); err != nil {
+		return nil, fmt.Errorf("not a JSON Patch document: %w", err)
+	}
+	return ops, nil
+}
+
+// applyPatch applies RFC 6902 operations to the merged node tree in place,
+// recording provenance for the paths each operation touches.
+func applyPatch(root *yaml.Node, ops []patchOp, name string, prov Provenance) error {
+	for i, op := range ops {
+		if err := applyPatchOp(root, op, name, prov); err != nil {
+			return fmt.Errorf("patch op %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return nil
+}
+
+func applyPatchOp(root *yaml.Node, op patchOp, name string, prov Provenance) error {
+	switch op.Op {
+	case "add", "replace":
+		value := op.Value
+		if err := setPointer(root, op.Path, &value, op.Op == "add"); err != nil {
+			return err
+		}
+		prov.dropSubtree(pointerToDotted(op.Path))
+		recordNode(prov, pointerToDotted(op.Path), &value, name)
+	case "remove":
+		if err := removePointer(root, op.Path); err != nil {
+			return err
+		}
+		prov.dropSubtree(pointerToDotted(op.Path))
+	case "move":
+		node, err := getPointer(root, op.From)
+		if err != nil {
+			return err
+		}
+		moved := *node
+		if err := removePointer(root, op.From); err != nil {
+			return err
+		}
+		if err := setPointer(root, op.Path, &moved, true); err != nil {
+			return err
+		}
+		prov.dropSubtree(pointerToDotted(op.From))
+		prov.dropSubtree(pointerToDotted(op.Path))
+		recordNode(prov, pointerToDotted(op.Path), &moved, name)
+	case "copy":
+		node, err := getPointer(root, op.From)
+		if err != nil {
+			return err
+		}
+		copied := *node
+		if err := setPointer(root, op.Path, &copied, true); err != nil {
+			return err
+		}
+		prov.dropSubtree(pointerToDotted(op.Path))
+		recordNode(prov, pointerToDotted(op.Path), &copied, name)
+	case "test":
+		node, err := getPointer(root, op.Path)
+		if err != nil {
+			return err
+		}
+		have, err := yaml.Marshal(node)
+		if err != nil {
+			return err
+		}
+		value := op.Value
+		want, err := yaml.Marshal(&value)
+		if err != nil {
+			return err
+		}
+		if string(have) != string(want) {
+			return fmt.Errorf("test failed: value is %s", strings.TrimSpace(string(have)))
+		}
+	default:
+		return fmt.Errorf("unknown op %q", op.Op)
+	}
+	return nil
+}
+
+// splitPointer parses an RFC 6901 JSON Pointer into its unescaped tokens.
+func splitPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer %q does not start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+	return tokens, nil
+}
+
+// pointerToDotted converts a JSON Pointer to the dotted path form used by
+// Provenance.
+func pointerToDotted(pointer string) string {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return pointer
+	}
+	return strings.Join(tokens, ".")
+}
+
+// getPointer returns the node a pointer addresses.
+func getPointer(root *yaml.Node, pointer string) (*yaml.Node, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	node := root
+	for _, token := range tokens {
+		switch node.Kind {
+		case yaml.MappingNode:
+			idx := mappingIndex(node, token)
+			if idx < 0 {
+				return nil, fmt.Errorf("key %q not found", token)
+			}
+			node = node.Content[idx+1]
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node.Content) {
+				return nil, fmt.Errorf("index %q out of range", token)
+			}
+			node = node.Content[i]
+		default:
+			return nil, fmt.Errorf("cannot descend into scalar at %q", token)
+		}
+	}
+	return node, nil
+}
+
+// setPointer writes value at the pointer. For add, a sequence index inserts
+// and "-" appends; for replace, the target must already exist.
+func setPointer(root *yaml.Node, pointer string, value *yaml.Node, add bool) error {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("cannot replace the document root")
+	}
+	parent, err := getPointer(root, joinPointer(tokens[:len(tokens)-1]))
+	if err != nil {
+		return err
+	}
+	last := tokens[len(tokens)-1]
+	switch parent.Kind {
+	case yaml.MappingNode:
+		if idx := mappingIndex(parent, last); idx >= 0 {
+			parent.Content[idx+1] = value
+			return nil
+		}
+		if !add {
+			return fmt.Errorf("key %q not found", last)
+		}
+		parent.Content = append(parent.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: last}, value)
+	case yaml.SequenceNode:
+		if add && last == "-" {
+			parent.Content = append(parent.Content, value)
+			return nil
+		}
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i > len(parent.Content) || (!add && i == len(parent.Content)) {
+			return fmt.Errorf("index %q out of range", last)
+		}
+		if add {
+			parent.Content = append(parent.Content[:i],
+				append([]*yaml.Node{value}, parent.Content[i:]...)...)
+		} else {
+			parent.Content[i] = value
+		}
+	default:
+		return fmt.Errorf("cannot write under a scalar")
+	}
+	return nil
+}
+
+// removePointer deletes the node a pointer addresses.
+func removePointer(root *yaml.Node, pointer string) error {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("cannot remove the document root")
+	}
+	parent, err := getPointer(root, joinPointer(tokens[:len(tokens)-1]))
+	if err != nil {
+		return err
+	}
+	last := tokens[len(tokens)-1]
+	switch parent.Kind {
+	case yaml.MappingNode:
+		idx := mappingIndex(parent, last)
+		if idx < 0 {
+			return fmt.Errorf("key %q not found", last)
+		}
+		parent.Content = append(parent.Content[:idx], parent.Content[idx+2:]...)
+	case yaml.SequenceNode:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i >= len(parent.Content) {
+			return fmt.Errorf("index %q out of range", last)
+		}
+		parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+	default:
+		return fmt.Errorf("cannot remove from a scalar")
+	}
+	return nil
+}
+
+// joinPointer re-escapes tokens into an RFC 6901 pointer.
+func joinPointer(tokens []string) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	escaped := make([]string, len(tokens))
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~", "~0")
+		escaped[i] = strings.ReplaceAll(token, "/", "~1")
+	}
+	return "/" + strings.Join(escaped, "/")
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// PolicyEngine evaluates governance policies against a resolved config.
+// Evaluate returns the denial messages for the input; any denial blocks
+// delivery. An evaluation failure also blocks delivery — a broken policy
+// must fail closed.
+type PolicyEngine interface {
+	Evaluate(input PolicyInput) ([]string, error)
+}
+
+// PolicyInput is the document policies evaluate against: the merged config
+// plus the agent context that selected it.
+type PolicyInput struct {
+	Selector string            `json:"selector"`
+	Tenant   string            `json:"tenant"`
+	Labels   map[string]string `json:"labels"`
+	Config   map[string]any    `json:"config"`
+}
+
+// SetPolicyEngine wires a policy stage into resolution, run after
+// validation passes. Nil disables the stage.
+func (r *Resolver) SetPolicyEngine(p PolicyEngine) {
+	r.policy = p
+}
+
+// OPAPolicy evaluates Rego policies by shelling out to the opa binary, so
+// organizations bring their own policy bundle and engine version without the
+// control plane linking one.
+type OPAPolicy struct {
+	binary string
+	dir    string
+	query  string
+}
+
+// NewOPAPolicy returns a policy engine loading Rego policies from dir.
+// binary defaults to "opa" on PATH; query defaults to "data.opamp.deny",
+// expected to evaluate to a set or array of denial messages.
+func NewOPAPolicy(binary, dir, query string) *OPAPolicy {
+	if binary == "" {
+		binary = "opa"
+	}
+	if query == "" {
+		query = "data.opamp.deny"
+	}
+	return &OPAPolicy{binary: binary, dir: dir, query: query}
+}
+
+// Evaluate implements PolicyEngine.
+func (p *OPAPolicy) Evaluate(input PolicyInput) ([]string, error) {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("opa eval: marshal input: %w", err)
+	}
+	cmd := exec.Command(p.binary, "eval", "--format", "json", "--data", p.dir, "--stdin-input", p.query)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("opa eval: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	var result struct {
+		Result []struct {
+			Expressions []struct {
+				Value any `json:"value"`
+			} `json:"expressions"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return nil, fmt.Errorf("opa eval: parse output: %w", err)
+	}
+	var denials []string
+	for _, res := range result.Result {
+		for _, expr := range res.Expressions {
+			values, ok := expr.Value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("opa eval: query %s did not evaluate to a list", p.query)
+			}
+			for _, v := range values {
+				denials = append(denials, fmt.Sprint(v))
+			}
+		}
+	}
+	return denials, nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+type stubPolicy struct {
+	denials []string
+	err     error
+	input   PolicyInput
+}
+
+func (p *stubPolicy) Evaluate(input PolicyInput) ([]string, error) {
+	p.input = input
+	return p.denials, p.err
+}
+
+func TestResolvePolicyDenies(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": "receivers: {otlp: {}}\nexporters: {debug: {}}\nservice: {pipelines: {traces: {receivers: [otlp], exporters: [debug]}}}\n",
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	resolver := NewResolver(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	policy := &stubPolicy{denials: []string{"debug exporter is forbidden in prod"}}
+	resolver.SetPolicyEngine(policy)
+
+	agent := &registry.Agent{InstanceUID: "agent-1", Labels: map[string]string{"env": "prod"}}
+	_, err := resolver.Resolve(agent)
+	if err == nil || !strings.Contains(err.Error(), "forbidden in prod") {
+		t.Fatalf("expected policy denial, got %v", err)
+	}
+	if policy.input.Labels["env"] != "prod" {
+		t.Errorf("policy input labels = %v, want agent labels", policy.input.Labels)
+	}
+	if _, ok := policy.input.Config["receivers"]; !ok {
+		t.Errorf("policy input config missing receivers: %v", policy.input.Config)
+	}
+
+	policy.denials = nil
+	if _, err := resolver.Resolve(agent); err != nil {
+		t.Fatalf("Resolve with no denials: %v", err)
+	}
+}
+
+func TestOPAPolicyEvaluate(t *testing.T) {
+	script := `#!/bin/sh
+cat > /dev/null
+echo '{"result":[{"expressions":[{"value":["missing required exporter"]}]}]}'
+`
+	path := filepath.Join(t.TempDir(), "opa")
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	engine := NewOPAPolicy(path, t.TempDir(), "")
+	denials, err := engine.Evaluate(PolicyInput{Selector: "base"})
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if len(denials) != 1 || denials[0] != "missing required exporter" {
+		t.Errorf("denials = %v", denials)
+	}
+}
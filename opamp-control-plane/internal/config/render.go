@@ -0,0 +1,47 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Delivery formats a selector can request via its "format" field. Authoring
+// is always YAML; rendering happens after merge and validation.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+)
+
+// renderConfig converts the merged YAML body into the requested delivery
+// format and returns it with the matching content type. An empty format
+// means YAML.
+func renderConfig(format string, body []byte) ([]byte, string, error) {
+	switch format {
+	case "", FormatYAML:
+		return body, "text/yaml", nil
+	case FormatJSON, FormatTOML:
+	default:
+		return nil, "", fmt.Errorf("unsupported format %q", format)
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return nil, "", fmt.Errorf("render %s: %w", format, err)
+	}
+	if format == FormatJSON {
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, "", fmt.Errorf("render json: %w", err)
+		}
+		return append(out, '\n'), "application/json", nil
+	}
+	out, err := toml.Marshal(doc)
+	if err != nil {
+		return nil, "", fmt.Errorf("render toml: %w", err)
+	}
+	return out, "application/toml", nil
+}
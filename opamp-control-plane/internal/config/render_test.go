@@ -0,0 +1,57 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRenderConfigYAMLPassthrough(t *testing.T) {
+	body := []byte("receivers:\n  otlp: {}\n")
+	out, contentType, err := renderConfig("", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "text/yaml" {
+		t.Errorf("content type = %q, want text/yaml", contentType)
+	}
+	if string(out) != string(body) {
+		t.Errorf("yaml body changed: %q", out)
+	}
+}
+
+func TestRenderConfigJSON(t *testing.T) {
+	out, contentType, err := renderConfig(FormatJSON, []byte("receivers:\n  otlp:\n    protocols:\n      grpc: {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("content type = %q, want application/json", contentType)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if _, ok := doc["receivers"]; !ok {
+		t.Errorf("rendered JSON missing receivers key: %s", out)
+	}
+}
+
+func TestRenderConfigTOML(t *testing.T) {
+	out, contentType, err := renderConfig(FormatTOML, []byte("exporters:\n  otlp:\n    endpoint: example.com:4317\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/toml" {
+		t.Errorf("content type = %q, want application/toml", contentType)
+	}
+	if !strings.Contains(string(out), "endpoint = 'example.com:4317'") {
+		t.Errorf("unexpected TOML output: %s", out)
+	}
+}
+
+func TestRenderConfigUnknownFormat(t *testing.T) {
+	if _, _, err := renderConfig("xml", []byte("a: 1\n")); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,423 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// ResolvedConfig is the final config for one agent, ready to be offered over
+// OpAMP.
+type ResolvedConfig struct {
+	// Selector is the name of the selector that matched, or "base" when the
+	// agent fell back to the base config.
+	Selector string
+	// Hash identifies the config contents; agents echo it back in their
+	// RemoteConfigStatus.
+	Hash string
+	// Body is the merged config, rendered in the selector's delivery format.
+	Body []byte
+	// ContentType describes Body: "text/yaml", "application/json", or
+	// "application/toml".
+	ContentType string
+	// Commit is the config repository commit of the snapshot this config
+	// was resolved from, if known.
+	Commit string
+	// OwnTelemetry is the matched selector's own-telemetry destination, if
+	// any.
+	OwnTelemetry *OwnTelemetry
+	// HeartbeatInterval is the matched selector's heartbeat override; zero
+	// means the server default applies.
+	HeartbeatInterval time.Duration
+	// Unmanaged means the agent must not be offered any config; Body and
+	// Hash are empty.
+	Unmanaged bool
+	// Provenance records, per dotted key path in the merged config, which
+	// repository file last set the value there.
+	Provenance Provenance
+	// Secrets are the secret values substituted into Body at render time.
+	// The agent needs them verbatim; anything re-serving Body to operators
+	// must redact them first.
+	Secrets []string
+	// Warnings are semantic lint findings that do not block delivery.
+	Warnings []string
+}
+
+// Resolver produces the desired config for an agent by matching its labels
+// against the selectors, merging the selected config with its overlays, and
+// validating the result.
+type Resolver struct {
+	store     *Store
+	merger    *Merger
+	validator *Validator
+	hasher    *Hasher
+	policy    PolicyEngine
+	// rejectPlaintextSecrets upgrades plaintext credential findings from
+	// warnings to resolution errors.
+	rejectPlaintextSecrets bool
+	// maxFileSize and maxMergedSize cap the size of individual source
+	// files and of the merged result, in bytes; zero means unlimited.
+	maxFileSize   int
+	maxMergedSize int
+	secrets       SecretsProvider
+	logger        *slog.Logger
+
+	// envLabel and envRoots map a promotion stage label on the agent to
+	// the config tree root for that environment; see SetEnvironments.
+	envLabel string
+	envRoots map[string]string
+	// envMatchers route agents to environment roots by a full label match,
+	// checked before envRoots; see SetEnvironmentMatchers.
+	envMatchers []EnvironmentMatch
+
+	// cache holds successful resolutions for the current store generation,
+	// keyed by the agent inputs that feed resolution. Resolve runs on every
+	// OpAMP message; without the cache every heartbeat re-merges and
+	// re-validates the same documents.
+	cacheMu  sync.Mutex
+	cacheGen int64
+	cache    map[string]*ResolvedConfig
+}
+
+// NewResolver returns a Resolver reading from store.
+func NewResolver(store *Store, logger *slog.Logger) *Resolver {
+	return &Resolver{
+		store:     store,
+		merger:    NewMerger(),
+		validator: NewValidator(),
+		hasher:    &Hasher{algorithm: "sha256"},
+		logger:    logger,
+		cache:     map[string]*ResolvedConfig{},
+	}
+}
+
+// SetHasher replaces the default sha256 config hasher. Changing the hasher
+// changes every config hash, so the whole fleet is re-offered on the next
+// resolve.
+func (r *Resolver) SetHasher(h *Hasher) {
+	r.hasher = h
+}
+
+// SetRejectPlaintextSecrets makes plaintext credential findings block
+// delivery instead of only warning.
+func (r *Resolver) SetRejectPlaintextSecrets(reject bool) {
+	r.rejectPlaintextSecrets = reject
+}
+
+// SetSizeLimits caps, in bytes, how large an individual config file and the
+// merged result may be. Oversized configs fail resolution instead of being
+// shipped to agents that may choke on them. Zero means unlimited.
+func (r *Resolver) SetSizeLimits(maxFile, maxMerged int) {
+	r.maxFileSize = maxFile
+	r.maxMergedSize = maxMerged
+}
+
+// SetEnvironments maps values of the given agent label to config tree root
+// prefixes (e.g. "staging" -> "envs/staging/"), so one server can serve
+// several promotion stages out of one snapshot. Agents whose label value
+// has no mapping resolve against the default tree.
+func (r *Resolver) SetEnvironments(label string, roots map[string]string) {
+	r.envLabel = label
+	r.envRoots = roots
+}
+
+// EnvironmentMatch routes agents satisfying a label criteria to a config
+// tree root, for environments picked out by more than a single label value.
+type EnvironmentMatch struct {
+	// Labels maps label keys to expected values, with the same value syntax
+	// as selector matches (exact, glob, or "~"-prefixed regexp). Every
+	// listed label must match.
+	Labels map[string]string
+	// Root is the config tree root prefix for matching agents.
+	Root string
+}
+
+// SetEnvironmentMatchers routes agents to environment roots by a full label
+// match, evaluated in order before the SetEnvironments mapping. The first
+// matching entry wins.
+func (r *Resolver) SetEnvironmentMatchers(rules []EnvironmentMatch) {
+	r.envMatchers = rules
+}
+
+// treePrefix returns the config tree root for an agent: the environment
+// root when a matcher or its environment label picks one, with the tenant
+// tree below it.
+func (r *Resolver) treePrefix(agent *registry.Agent) string {
+	prefix := TenantPrefix(agent.Tenant)
+	for _, rule := range r.envMatchers {
+		if (SelectorMatch{Labels: rule.Labels}).Matches(agent.Labels, nil) {
+			return rule.Root + prefix
+		}
+	}
+	if r.envLabel != "" {
+		if root, ok := r.envRoots[agent.Labels[r.envLabel]]; ok {
+			prefix = root + prefix
+		}
+	}
+	return prefix
+}
+
+// cacheKey fingerprints everything agent-side that feeds resolution: the
+// tenant picks the config tree, labels pick the selector, and templates can
+// read labels, attributes, and the instance UID.
+func cacheKey(agent *registry.Agent) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%t\x00", agent.InstanceUID, agent.Tenant, agent.Unmanaged)
+	for _, k := range sortedStringMapKeys(agent.Labels) {
+		fmt.Fprintf(h, "l:%s=%s\x00", k, agent.Labels[k])
+	}
+	for _, k := range sortedStringMapKeys(agent.Description) {
+		fmt.Fprintf(h, "a:%s=%s\x00", k, agent.Description[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sortedStringMapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// cached returns the cache entry for key, resetting the cache first if the
+// store snapshot has changed since it was filled.
+func (r *Resolver) cached(gen int64, key string) (*ResolvedConfig, bool) {
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	if gen != r.cacheGen {
+		r.cache = map[string]*ResolvedConfig{}
+		r.cacheGen = gen
+		return nil, false
+	}
+	resolved, ok := r.cache[key]
+	return resolved, ok
+}
+
+func (r *Resolver) storeCache(gen int64, key string, resolved *ResolvedConfig) {
+	r.cacheMu.Lock()
+	defer r.cacheMu.Unlock()
+	if gen == r.cacheGen {
+		r.cache[key] = resolved
+	}
+}
+
+// Resolve returns the desired config for the agent. All file references are
+// scoped to the agent's tenant config tree.
+//
+// Successful resolutions are cached until the next LoadConfigs, so repeated
+// OpAMP messages from a steady fleet do not re-merge and re-validate. Callers
+// must treat the returned ResolvedConfig as immutable.
+func (r *Resolver) Resolve(agent *registry.Agent) (*ResolvedConfig, error) {
+	gen := r.store.Generation()
+	key := cacheKey(agent)
+	if resolved, ok := r.cached(gen, key); ok {
+		return resolved, nil
+	}
+	resolved, err := r.resolve(agent)
+	if err != nil {
+		return nil, err
+	}
+	r.storeCache(gen, key, resolved)
+	return resolved, nil
+}
+
+func (r *Resolver) resolve(agent *registry.Agent) (*ResolvedConfig, error) {
+	prefix := r.treePrefix(agent)
+	matcher := NewSelectorMatcher(r.store.SelectorsAt(prefix))
+	sel := matcher.Match(agent.Labels, agent.Description)
+
+	if agent.Unmanaged {
+		return &ResolvedConfig{Selector: "unmanaged", Unmanaged: true}, nil
+	}
+	if sel != nil && sel.Unmanaged {
+		return &ResolvedConfig{Selector: sel.Name, Unmanaged: true}, nil
+	}
+	return r.resolveSelector(prefix, sel, agent, "")
+}
+
+// ErrUnknownSelector is returned by ResolveSelector for names not present
+// in the tree.
+var ErrUnknownSelector = errors.New("unknown selector")
+
+// ResolveSelector returns the merged config a selector would deliver,
+// rendered without any agent context: label and attr lookups expand to
+// empty strings and no per-agent vars apply. "base" previews the fallback
+// config. A non-empty format overrides the selector's delivery format, so
+// reviewers can ask for JSON regardless of how agents receive it.
+func (r *Resolver) ResolveSelector(tenant, name, format string) (*ResolvedConfig, error) {
+	prefix := TenantPrefix(tenant)
+	var sel *Selector
+	if name != "base" {
+		selectors := r.store.SelectorsAt(prefix)
+		for i := range selectors {
+			if selectors[i].Name == name {
+				sel = &selectors[i]
+				break
+			}
+		}
+		if sel == nil {
+			return nil, fmt.Errorf("%w %s", ErrUnknownSelector, name)
+		}
+		if sel.Unmanaged {
+			return &ResolvedConfig{Selector: sel.Name, Unmanaged: true}, nil
+		}
+	}
+	preview := &registry.Agent{InstanceUID: "selector-preview", Tenant: tenant}
+	return r.resolveSelector(prefix, sel, preview, format)
+}
+
+// resolveSelector merges, renders, and validates the config for one
+// selector (nil means base only) against the given agent.
+func (r *Resolver) resolveSelector(prefix string, sel *Selector, agent *registry.Agent, formatOverride string) (*ResolvedConfig, error) {
+	var docs [][]byte
+	var paths []string
+	if base, ok := r.store.File(prefix + BaseConfigFile); ok {
+		docs = append(docs, base)
+		paths = append(paths, prefix+BaseConfigFile)
+	}
+
+	name := "base"
+	if sel != nil {
+		name = sel.Name
+		var files []string
+		switch {
+		case sel.Layers != "" && (sel.Config != "" || len(sel.Overlays) > 0):
+			return nil, fmt.Errorf("selector %s sets both layers and config/overlays", sel.Name)
+		case sel.Layers != "":
+			stack, ok := r.store.LayerStackAt(prefix, sel.Layers)
+			if !ok {
+				return nil, fmt.Errorf("selector %s references unknown layer stack %s", sel.Name, sel.Layers)
+			}
+			files = stack
+		default:
+			files = append([]string{sel.Config}, sel.Overlays...)
+		}
+		for _, file := range files {
+			data, ok := r.store.File(prefix + file)
+			if !ok {
+				return nil, fmt.Errorf("selector %s references missing file %s", sel.Name, file)
+			}
+			docs = append(docs, data)
+			paths = append(paths, prefix+file)
+		}
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no config for agent %s: no selector matched and no %s", agent.InstanceUID, BaseConfigFile)
+	}
+	if r.maxFileSize > 0 {
+		for i, doc := range docs {
+			if len(doc) > r.maxFileSize {
+				return nil, fmt.Errorf("resolve %s for agent %s: %s is %d bytes, over the %d byte file limit",
+					name, agent.InstanceUID, paths[i], len(doc), r.maxFileSize)
+			}
+		}
+	}
+
+	// Expand template actions against the agent before merging, so shared
+	// files can carry per-host values.
+	vars, err := r.loadVars(prefix, name, agent)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s for agent %s: %w", name, agent.InstanceUID, err)
+	}
+	data := &TemplateData{
+		InstanceUID: agent.InstanceUID,
+		Selector:    name,
+		Labels:      agent.Labels,
+		Attrs:       agent.Description,
+		Vars:        vars,
+	}
+	var secretsUsed []string
+	for i, doc := range docs {
+		rendered, err := renderTemplate(paths[i], doc, data, r.secrets, &secretsUsed)
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s for agent %s: %w", name, agent.InstanceUID, err)
+		}
+		docs[i] = rendered
+	}
+
+	body, provenance, err := r.merger.MergeTraced(paths, docs...)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s for agent %s: %w", name, agent.InstanceUID, err)
+	}
+	if r.maxMergedSize > 0 && len(body) > r.maxMergedSize {
+		return nil, fmt.Errorf("resolve %s for agent %s: merged config is %d bytes, over the %d byte limit",
+			name, agent.InstanceUID, len(body), r.maxMergedSize)
+	}
+	if err := r.validator.Validate(body); err != nil {
+		return nil, fmt.Errorf("resolve %s for agent %s: %w", name, agent.InstanceUID, err)
+	}
+	if sel != nil && sel.Distro != "" {
+		manifest, ok := r.store.DistroAt(prefix, sel.Distro)
+		if !ok {
+			return nil, fmt.Errorf("selector %s references unknown distro %s", sel.Name, sel.Distro)
+		}
+		if problems := manifest.Check(body); len(problems) > 0 {
+			return nil, fmt.Errorf("resolve %s for agent %s: distro %s: %w",
+				name, agent.InstanceUID, sel.Distro, &ValidationError{Problems: problems})
+		}
+	}
+	if r.policy != nil {
+		var parsed map[string]any
+		if err := yaml.Unmarshal(body, &parsed); err != nil {
+			return nil, fmt.Errorf("resolve %s for agent %s: policy input: %w", name, agent.InstanceUID, err)
+		}
+		denials, err := r.policy.Evaluate(PolicyInput{
+			Selector: name,
+			Tenant:   agent.Tenant,
+			Labels:   agent.Labels,
+			Config:   parsed,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s for agent %s: policy: %w", name, agent.InstanceUID, err)
+		}
+		if len(denials) > 0 {
+			return nil, fmt.Errorf("resolve %s for agent %s: policy denied: %w",
+				name, agent.InstanceUID, &ValidationError{Problems: denials})
+		}
+	}
+	secretFindings := scanPlaintextSecrets(body, secretsUsed)
+	if len(secretFindings) > 0 && r.rejectPlaintextSecrets {
+		return nil, fmt.Errorf("resolve %s for agent %s: %w", name, agent.InstanceUID,
+			&ValidationError{Problems: secretFindings})
+	}
+	warnings := append(r.validator.Warnings(body), secretFindings...)
+
+	var format string
+	if sel != nil {
+		format = sel.Format
+	}
+	if formatOverride != "" {
+		format = formatOverride
+	}
+	body, contentType, err := renderConfig(format, body)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s for agent %s: %w", name, agent.InstanceUID, err)
+	}
+
+	resolved := &ResolvedConfig{
+		Selector:    name,
+		Body:        body,
+		ContentType: contentType,
+		Commit:      r.store.Commit(),
+		Provenance:  provenance,
+		Secrets:     secretsUsed,
+		Warnings:    warnings,
+	}
+	if sel != nil {
+		resolved.OwnTelemetry = sel.OwnTelemetry
+		resolved.HeartbeatInterval = sel.HeartbeatInterval
+	}
+	resolved.Hash = r.hasher.Sum(name, paths, contentType, body)
+	return resolved, nil
+}
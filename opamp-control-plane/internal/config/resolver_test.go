@@ -0,0 +1,215 @@
+package config
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+func TestResolveEnvironmentRoots(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml":         "receivers: {otlp: {}}\nexporters: {debug: {}}\nservice: {pipelines: {traces: {receivers: [otlp], exporters: [debug]}}}\n",
+		"staging/base.yaml": "receivers: {jaeger: {}}\nexporters: {debug: {}}\nservice: {pipelines: {traces: {receivers: [jaeger], exporters: [debug]}}}\n",
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	resolver := NewResolver(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	resolver.SetEnvironments("deployment.environment", map[string]string{"staging": "staging/"})
+
+	prod := &registry.Agent{InstanceUID: "agent-prod"}
+	resolved, err := resolver.Resolve(prod)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if !strings.Contains(string(resolved.Body), "otlp") {
+		t.Errorf("unmapped agent did not get the default tree:\n%s", resolved.Body)
+	}
+
+	staging := &registry.Agent{
+		InstanceUID: "agent-staging",
+		Labels:      map[string]string{"deployment.environment": "staging"},
+	}
+	resolved, err = resolver.Resolve(staging)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if !strings.Contains(string(resolved.Body), "jaeger") {
+		t.Errorf("staging agent did not get the staging tree:\n%s", resolved.Body)
+	}
+}
+
+func TestResolveEnvironmentMatchers(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml":         "receivers: {otlp: {}}\nexporters: {debug: {}}\nservice: {pipelines: {traces: {receivers: [otlp], exporters: [debug]}}}\n",
+		"staging/base.yaml": "receivers: {jaeger: {}}\nexporters: {debug: {}}\nservice: {pipelines: {traces: {receivers: [jaeger], exporters: [debug]}}}\n",
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	resolver := NewResolver(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	resolver.SetEnvironmentMatchers([]EnvironmentMatch{
+		{Labels: map[string]string{"env": "staging", "region": "eu-*"}, Root: "staging/"},
+	})
+
+	matched := &registry.Agent{
+		InstanceUID: "agent-eu",
+		Labels:      map[string]string{"env": "staging", "region": "eu-west"},
+	}
+	resolved, err := resolver.Resolve(matched)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if !strings.Contains(string(resolved.Body), "jaeger") {
+		t.Errorf("matched agent did not get the staging tree:\n%s", resolved.Body)
+	}
+
+	partial := &registry.Agent{
+		InstanceUID: "agent-us",
+		Labels:      map[string]string{"env": "staging", "region": "us-east"},
+	}
+	resolved, err = resolver.Resolve(partial)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if !strings.Contains(string(resolved.Body), "otlp") {
+		t.Errorf("partially matching agent did not get the default tree:\n%s", resolved.Body)
+	}
+}
+
+func TestResolveSelectorWithoutAgent(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": "receivers: {otlp: {}}\nexporters: {debug: {}}\nservice: {pipelines: {traces: {receivers: [otlp], exporters: [debug]}}}\n",
+		"prod.yaml": "exporters: {otlphttp: {}}\nservice: {pipelines: {traces: {exporters: [otlphttp]}}}\n",
+		"_selectors.yaml": `selectors:
+  - name: prod
+    match:
+      labels:
+        env: prod
+    config: prod.yaml
+`,
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	resolver := NewResolver(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	resolved, err := resolver.ResolveSelector("", "prod", "")
+	if err != nil {
+		t.Fatalf("ResolveSelector: %v", err)
+	}
+	if resolved.Selector != "prod" || !strings.Contains(string(resolved.Body), "otlphttp") {
+		t.Errorf("unexpected resolution: selector=%s body=%s", resolved.Selector, resolved.Body)
+	}
+
+	asJSON, err := resolver.ResolveSelector("", "prod", "json")
+	if err != nil {
+		t.Fatalf("ResolveSelector json: %v", err)
+	}
+	if asJSON.ContentType != "application/json" {
+		t.Errorf("format override ignored: %s", asJSON.ContentType)
+	}
+
+	if _, err := resolver.ResolveSelector("", "missing", ""); !errors.Is(err, ErrUnknownSelector) {
+		t.Errorf("expected ErrUnknownSelector, got %v", err)
+	}
+}
+
+func TestResolveCachesUntilReload(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": "receivers: {otlp: {}}\nexporters: {debug: {}}\nservice: {pipelines: {traces: {receivers: [otlp], exporters: [debug]}}}\n",
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	resolver := NewResolver(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	agent := &registry.Agent{InstanceUID: "agent-1", Labels: map[string]string{"env": "prod"}}
+
+	first, err := resolver.Resolve(agent)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	second, err := resolver.Resolve(agent)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if first != second {
+		t.Error("second Resolve did not return the cached result")
+	}
+
+	other := &registry.Agent{InstanceUID: "agent-2", Labels: map[string]string{"env": "dev"}}
+	otherResolved, err := resolver.Resolve(other)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if otherResolved == first {
+		t.Error("different agent shared a cache entry")
+	}
+
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	third, err := resolver.Resolve(agent)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if third == first {
+		t.Error("LoadConfigs did not invalidate the cache")
+	}
+	if third.Hash != first.Hash {
+		t.Errorf("hash changed across identical snapshots: %s != %s", third.Hash, first.Hash)
+	}
+}
+
+func TestResolveEnforcesSizeLimits(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": "receivers: {otlp: {}}\nexporters: {debug: {}}\nservice: {pipelines: {traces: {receivers: [otlp], exporters: [debug]}}}\n",
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	resolver := NewResolver(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	agent := &registry.Agent{InstanceUID: "agent-1"}
+
+	resolver.SetSizeLimits(16, 0)
+	if _, err := resolver.Resolve(agent); err == nil || !strings.Contains(err.Error(), "file limit") {
+		t.Errorf("expected file size error, got %v", err)
+	}
+
+	resolver.SetSizeLimits(0, 16)
+	if _, err := resolver.Resolve(agent); err == nil || !strings.Contains(err.Error(), "byte limit") {
+		t.Errorf("expected merged size error, got %v", err)
+	}
+
+	resolver.SetSizeLimits(0, 0)
+	if _, err := resolver.Resolve(agent); err != nil {
+		t.Fatalf("Resolve without limits: %v", err)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	resolver := NewResolver(NewStore(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	problems, _ := resolver.ValidateConfig([]byte("receivers: {otlp: {}}\n"))
+	if len(problems) == 0 {
+		t.Error("config without a service section produced no problems")
+	}
+
+	problems, warnings := resolver.ValidateConfig([]byte(
+		"receivers: {otlp: {}}\nexporters: {debug: {}}\nservice: {pipelines: {traces: {receivers: [otlp], exporters: [debug]}}}\n"))
+	if len(problems) != 0 {
+		t.Errorf("valid config produced problems: %v", problems)
+	}
+	if len(warnings) == 0 {
+		t.Error("pipeline without batch processor produced no warnings")
+	}
+}
@@ -0,0 +1,16 @@
+package config
+
+// SecretsProvider resolves secret references appearing in config templates
+// via the secret function, so credentials are injected at render time and
+// never stored in git.
+type SecretsProvider interface {
+	// Secret returns the value for a secret reference, e.g.
+	// "kv/otel/api-key".
+	Secret(ref string) (string, error)
+}
+
+// SetSecretsProvider wires a secrets provider into template rendering. Nil
+// (the default) makes any secret reference a resolution error.
+func (r *Resolver) SetSecretsProvider(p SecretsProvider) {
+	r.secrets = p
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretValuePatterns spot credential material by shape, wherever it sits in
+// the config.
+var secretValuePatterns = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"AWS access key ID", regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},
+	{"URL with embedded credentials", regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:[^@\s]+@`)},
+	{"private key block", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+}
+
+// secretKeyPattern spots keys that by name hold credentials; any literal
+// value under them is suspect.
+var secretKeyPattern = regexp.MustCompile(`(?i)(password|passwd|secret|api[_-]?key|auth[_-]?token|authorization)$`)
+
+// scanPlaintextSecrets reports likely plaintext credentials in a merged
+// config, so teams are nudged toward the secrets provider instead of
+// committing keys to the config repo. Values substituted by the secrets
+// provider (injected) and values deferring to collector-side expansion
+// ("${...}") are not flagged.
+func scanPlaintextSecrets(cfg []byte, injected []string) []string {
+	var root map[string]any
+	if err := yaml.Unmarshal(cfg, &root); err != nil {
+		return nil // Validate already rejects unparseable configs.
+	}
+	var warnings []string
+	scanSecretValues(root, "", injected, &warnings)
+	return warnings
+}
+
+func scanSecretValues(v any, path string, injected []string, warnings *[]string) {
+	switch v := v.(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			scanSecretValues(v[k], childPath, injected, warnings)
+		}
+	case []any:
+		for i, item := range v {
+			scanSecretValues(item, fmt.Sprintf("%s[%d]", path, i), injected, warnings)
+		}
+	case string:
+		if strings.Contains(v, "${") || isInjectedSecret(v, injected) {
+			return
+		}
+		for _, pattern := range secretValuePatterns {
+			if pattern.re.MatchString(v) {
+				*warnings = append(*warnings,
+					fmt.Sprintf("%s looks like a plaintext %s; use the secrets provider instead", path, pattern.name))
+				return
+			}
+		}
+		key := path
+		if i := strings.LastIndexAny(path, "."); i >= 0 {
+			key = path[i+1:]
+		}
+		if secretKeyPattern.MatchString(key) && v != "" {
+			*warnings = append(*warnings,
+				fmt.Sprintf("%s holds a literal value under a credential-looking key; use the secrets provider instead", path))
+		}
+	}
+}
+
+// isInjectedSecret reports whether the value carries a secret the provider
+// substituted at render time; those are expected to be present verbatim.
+func isInjectedSecret(value string, injected []string) bool {
+	for _, secret := range injected {
+		if secret != "" && strings.Contains(value, secret) {
+			return true
+		}
+	}
+	return false
+}
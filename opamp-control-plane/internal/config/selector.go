@@ -0,0 +1,198 @@
+package config
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Selector routes a set of agents, identified by their labels, to a config
+// file and an ordered list of overlays.
+type Selector struct {
+	// Name uniquely identifies the selector within the repository.
+	Name string `yaml:"name"`
+	// Priority breaks ties when several selectors match; higher wins.
+	// Selectors with equal priority are ordered by name for determinism.
+	Priority int `yaml:"priority"`
+	// Match describes which agents this selector applies to.
+	Match SelectorMatch `yaml:"match"`
+	// Config is the repository path of the config file to serve.
+	Config string `yaml:"config"`
+	// Overlays are repository paths merged on top of Config, in order.
+	Overlays []string `yaml:"overlays"`
+	// Layers names a layer stack declared in the selectors file, used
+	// instead of Config and Overlays: the stack's files are merged in
+	// order on top of the base config.
+	Layers string `yaml:"layers"`
+	// Distro names a distribution manifest from the tree's _distros.yaml;
+	// merged configs are checked against it so components the target
+	// agents' distribution does not ship are rejected at resolve time.
+	Distro string `yaml:"distro"`
+	// Format is the delivery format for matched agents: "yaml" (default),
+	// "json", or "toml". Authoring stays in YAML; rendering happens after
+	// merge and validation.
+	Format string `yaml:"format"`
+	// Unmanaged marks matched agents as observed-only: they are tracked in
+	// the registry but never offered configs. Useful while migrating fleets
+	// onto the control plane gradually.
+	Unmanaged bool `yaml:"unmanaged"`
+	// OwnTelemetry optionally tells matched agents where to send their own
+	// telemetry, offered over OpAMP connection settings.
+	OwnTelemetry *OwnTelemetry `yaml:"own_telemetry"`
+	// HeartbeatInterval overrides the server-wide heartbeat interval for
+	// matched agents. Zero inherits the server default.
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+}
+
+// OwnTelemetry is the destination for an agent's own telemetry. Endpoints
+// left empty are not offered.
+type OwnTelemetry struct {
+	MetricsEndpoint string `yaml:"metrics_endpoint"`
+	TracesEndpoint  string `yaml:"traces_endpoint"`
+	LogsEndpoint    string `yaml:"logs_endpoint"`
+	// Headers are sent with each export, e.g. for backend auth.
+	Headers map[string]string `yaml:"headers"`
+}
+
+// SelectorMatch is the match criteria of a selector. An agent matches when
+// every listed label is present with a satisfying value and every expression
+// holds. An empty criteria matches every agent.
+type SelectorMatch struct {
+	// Labels maps label keys to expected values: exact by default, a glob
+	// pattern when the value contains * or ? (e.g. "prod-*"), or a regular
+	// expression when prefixed with "~" (e.g. "~^0\.9\.").
+	Labels map[string]string `yaml:"labels"`
+	// Attrs maps non-identifying description attribute keys (e.g.
+	// "os.type", "host.arch") to expected values, with the same value
+	// syntax as Labels. Platform overlays match here without the
+	// attributes having to be promoted to labels first.
+	Attrs map[string]string `yaml:"attrs"`
+	// Expressions are Kubernetes-style label requirements, evaluated in
+	// addition to Labels.
+	Expressions []MatchExpression `yaml:"expressions"`
+}
+
+// MatchExpression is one Kubernetes-style label requirement.
+type MatchExpression struct {
+	Key string `yaml:"key"`
+	// Operator is one of In, NotIn, Exists, DoesNotExist. NotIn and
+	// DoesNotExist also match agents missing the key, per the Kubernetes
+	// semantics.
+	Operator string `yaml:"operator"`
+	// Values is required for In and NotIn and ignored otherwise.
+	Values []string `yaml:"values"`
+}
+
+// matches reports whether the expression holds for the given labels. Unknown
+// operators never match, so a typo narrows a selector instead of widening it.
+func (e MatchExpression) matches(labels map[string]string) bool {
+	value, ok := labels[e.Key]
+	switch e.Operator {
+	case "In":
+		return ok && slices.Contains(e.Values, value)
+	case "NotIn":
+		return !ok || !slices.Contains(e.Values, value)
+	case "Exists":
+		return ok
+	case "DoesNotExist":
+		return !ok
+	}
+	return false
+}
+
+// Matches reports whether the given agent labels and description attributes
+// satisfy the match criteria. An empty criteria matches every agent.
+func (m SelectorMatch) Matches(labels, attrs map[string]string) bool {
+	for k, want := range m.Labels {
+		have, ok := labels[k]
+		if !ok || !labelValueMatches(want, have) {
+			return false
+		}
+	}
+	for k, want := range m.Attrs {
+		have, ok := attrs[k]
+		if !ok || !labelValueMatches(want, have) {
+			return false
+		}
+	}
+	for _, e := range m.Expressions {
+		if !e.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// labelValueMatches reports whether an agent's label value satisfies a
+// selector's expected value: "~"-prefixed values are regular expressions,
+// values containing * or ? are glob patterns, anything else compares exactly.
+// Invalid patterns never match, so a broken selector narrows rather than
+// catching the whole fleet.
+func labelValueMatches(want, have string) bool {
+	if pattern, ok := strings.CutPrefix(want, "~"); ok {
+		re := compileLabelPattern(pattern)
+		return re != nil && re.MatchString(have)
+	}
+	if strings.ContainsAny(want, "*?") {
+		ok, err := path.Match(want, have)
+		return err == nil && ok
+	}
+	return want == have
+}
+
+// labelValueIsPattern reports whether a selector label value is a glob or
+// regexp rather than an exact string.
+func labelValueIsPattern(want string) bool {
+	return strings.HasPrefix(want, "~") || strings.ContainsAny(want, "*?")
+}
+
+// labelPatterns caches compiled label regexps; selector matching runs on
+// every agent message, patterns change only on config sync.
+var labelPatterns sync.Map // pattern -> *regexp.Regexp, nil for invalid
+
+func compileLabelPattern(pattern string) *regexp.Regexp {
+	if v, ok := labelPatterns.Load(pattern); ok {
+		re, _ := v.(*regexp.Regexp)
+		return re
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		re = nil
+	}
+	labelPatterns.Store(pattern, re)
+	return re
+}
+
+// SelectorMatcher evaluates an ordered selector list against agent labels.
+type SelectorMatcher struct {
+	selectors []Selector
+}
+
+// NewSelectorMatcher builds a matcher over the given selectors, ordered by
+// descending priority and then by name.
+func NewSelectorMatcher(selectors []Selector) *SelectorMatcher {
+	ordered := make([]Selector, len(selectors))
+	copy(ordered, selectors)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Priority != ordered[j].Priority {
+			return ordered[i].Priority > ordered[j].Priority
+		}
+		return ordered[i].Name < ordered[j].Name
+	})
+	return &SelectorMatcher{selectors: ordered}
+}
+
+// Match returns the first selector matching the labels and description
+// attributes, or nil if none does.
+func (sm *SelectorMatcher) Match(labels, attrs map[string]string) *Selector {
+	for i := range sm.selectors {
+		if sm.selectors[i].Match.Matches(labels, attrs) {
+			return &sm.selectors[i]
+		}
+	}
+	return nil
+}
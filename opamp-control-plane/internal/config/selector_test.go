@@ -0,0 +1,138 @@
+package config
+
+import "testing"
+
+func TestSelectorMatcherPriorityAndOrder(t *testing.T) {
+	matcher := NewSelectorMatcher([]Selector{
+		{Name: "all-prod", Priority: 1, Match: SelectorMatch{Labels: map[string]string{"env": "prod"}}},
+		{Name: "prod-eu", Priority: 10, Match: SelectorMatch{Labels: map[string]string{"env": "prod", "region": "eu"}}},
+		{Name: "catch-all", Priority: 0},
+	})
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{"most specific wins by priority", map[string]string{"env": "prod", "region": "eu"}, "prod-eu"},
+		{"falls through to lower priority", map[string]string{"env": "prod", "region": "us"}, "all-prod"},
+		{"empty match is a catch-all", map[string]string{"env": "dev"}, "catch-all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel := matcher.Match(tt.labels, nil)
+			if sel == nil {
+				t.Fatalf("Match(%v) = nil, want %s", tt.labels, tt.want)
+			}
+			if sel.Name != tt.want {
+				t.Errorf("Match(%v) = %s, want %s", tt.labels, sel.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   MatchExpression
+		labels map[string]string
+		want   bool
+	}{
+		{"In matches listed value", MatchExpression{Key: "env", Operator: "In", Values: []string{"prod", "staging"}}, map[string]string{"env": "prod"}, true},
+		{"In rejects other value", MatchExpression{Key: "env", Operator: "In", Values: []string{"prod", "staging"}}, map[string]string{"env": "dev"}, false},
+		{"In rejects missing key", MatchExpression{Key: "env", Operator: "In", Values: []string{"prod"}}, map[string]string{}, false},
+		{"NotIn rejects listed value", MatchExpression{Key: "env", Operator: "NotIn", Values: []string{"prod"}}, map[string]string{"env": "prod"}, false},
+		{"NotIn matches other value", MatchExpression{Key: "env", Operator: "NotIn", Values: []string{"prod"}}, map[string]string{"env": "dev"}, true},
+		{"NotIn matches missing key", MatchExpression{Key: "env", Operator: "NotIn", Values: []string{"prod"}}, map[string]string{}, true},
+		{"Exists matches present key", MatchExpression{Key: "region", Operator: "Exists"}, map[string]string{"region": "eu"}, true},
+		{"Exists rejects missing key", MatchExpression{Key: "region", Operator: "Exists"}, map[string]string{}, false},
+		{"DoesNotExist matches missing key", MatchExpression{Key: "canary", Operator: "DoesNotExist"}, map[string]string{}, true},
+		{"DoesNotExist rejects present key", MatchExpression{Key: "canary", Operator: "DoesNotExist"}, map[string]string{"canary": "1"}, false},
+		{"unknown operator never matches", MatchExpression{Key: "env", Operator: "Includes", Values: []string{"prod"}}, map[string]string{"env": "prod"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.expr.matches(tt.labels); got != tt.want {
+				t.Errorf("matches(%v) = %v, want %v", tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectorMatchCombinesLabelsAndExpressions(t *testing.T) {
+	m := SelectorMatch{
+		Labels: map[string]string{"env": "prod"},
+		Expressions: []MatchExpression{
+			{Key: "region", Operator: "In", Values: []string{"eu", "us"}},
+			{Key: "canary", Operator: "DoesNotExist"},
+		},
+	}
+	if !m.Matches(map[string]string{"env": "prod", "region": "eu"}, nil) {
+		t.Error("expected match")
+	}
+	if m.Matches(map[string]string{"env": "prod", "region": "ap"}, nil) {
+		t.Error("region outside the In set must not match")
+	}
+	if m.Matches(map[string]string{"env": "prod", "region": "eu", "canary": "1"}, nil) {
+		t.Error("canary agents must not match")
+	}
+}
+
+func TestLabelValueMatches(t *testing.T) {
+	tests := []struct {
+		name, want, have string
+		match            bool
+	}{
+		{"exact match", "prod", "prod", true},
+		{"exact mismatch", "prod", "prod-eu", false},
+		{"glob matches", "prod-*", "prod-eu", true},
+		{"glob rejects", "prod-*", "staging-eu", false},
+		{"single char glob", "v?", "v2", true},
+		{"regex matches", `~^0\.9\.`, "0.9.12", true},
+		{"regex rejects", `~^0\.9\.`, "0.10.0", false},
+		{"invalid regex never matches", "~([", "([", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := labelValueMatches(tt.want, tt.have); got != tt.match {
+				t.Errorf("labelValueMatches(%q, %q) = %v, want %v", tt.want, tt.have, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestSelectorMatchPatternValues(t *testing.T) {
+	m := SelectorMatch{Labels: map[string]string{"env": "prod-*"}}
+	if !m.Matches(map[string]string{"env": "prod-eu"}, nil) {
+		t.Error("glob label value did not match")
+	}
+	if m.Matches(map[string]string{}, nil) {
+		t.Error("pattern must not match a missing label")
+	}
+}
+
+func TestSelectorMatcherNoMatch(t *testing.T) {
+	matcher := NewSelectorMatcher([]Selector{
+		{Name: "prod", Match: SelectorMatch{Labels: map[string]string{"env": "prod"}}},
+	})
+	if sel := matcher.Match(map[string]string{"env": "dev"}, nil); sel != nil {
+		t.Errorf("Match = %s, want nil", sel.Name)
+	}
+}
+
+func TestSelectorMatchAttrs(t *testing.T) {
+	m := SelectorMatch{
+		Labels: map[string]string{"env": "prod"},
+		Attrs:  map[string]string{"os.type": "linux", "host.arch": "arm*"},
+	}
+	labels := map[string]string{"env": "prod"}
+	if !m.Matches(labels, map[string]string{"os.type": "linux", "host.arch": "arm64"}) {
+		t.Error("expected match on attrs")
+	}
+	if m.Matches(labels, map[string]string{"os.type": "windows", "host.arch": "arm64"}) {
+		t.Error("mismatched attr value must not match")
+	}
+	if m.Matches(labels, nil) {
+		t.Error("missing attrs must not match")
+	}
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// selectorFields are the keys a selector entry may carry, mirroring the
+// Selector struct's yaml tags.
+var selectorFields = map[string]bool{
+	"name":               true,
+	"priority":           true,
+	"match":              true,
+	"config":             true,
+	"overlays":           true,
+	"layers":             true,
+	"distro":             true,
+	"format":             true,
+	"unmanaged":          true,
+	"own_telemetry":      true,
+	"heartbeat_interval": true,
+}
+
+// selectorsTopLevelFields are the keys allowed at the top of a selectors
+// file.
+var selectorsTopLevelFields = map[string]bool{
+	"selectors": true,
+	"layers":    true,
+}
+
+// SelectorProblem is one issue found validating a selectors file: where it
+// is, which selector it concerns, and what is wrong.
+type SelectorProblem struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Selector string `json:"selector,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (p SelectorProblem) String() string {
+	loc := p.File
+	if p.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+	if p.Selector != "" {
+		return fmt.Sprintf("%s: selector %s: %s", loc, p.Selector, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", loc, p.Message)
+}
+
+// lintSelectorsFile validates one selectors file against its tree: unknown
+// fields, selectors without a name or a config source, and references to
+// files missing from the snapshot. files holds the full snapshot; prefix is
+// the tree root the selectors file sits in.
+func lintSelectorsFile(name, prefix string, raw []byte, files map[string][]byte) []SelectorProblem {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return []SelectorProblem{{File: name, Message: "not valid YAML: " + err.Error()}}
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return []SelectorProblem{{File: name, Line: root.Line, Message: "top level must be a mapping"}}
+	}
+
+	var problems []SelectorProblem
+	var sf selectorsFile
+	if err := root.Decode(&sf); err != nil {
+		return []SelectorProblem{{File: name, Message: "not a selectors file: " + err.Error()}}
+	}
+	exists := func(rel string) bool {
+		_, ok := files[prefix+rel]
+		return ok
+	}
+
+	var selectorsNode *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if !selectorsTopLevelFields[key.Value] {
+			problems = append(problems, SelectorProblem{
+				File: name, Line: key.Line,
+				Message: unknownKeyMessage(key.Value, selectorsTopLevelFields, "selectors file key"),
+			})
+		}
+		if key.Value == "selectors" {
+			selectorsNode = value
+		}
+	}
+
+	for stack, paths := range sf.Layers {
+		for _, rel := range paths {
+			if !exists(rel) {
+				problems = append(problems, SelectorProblem{
+					File:    name,
+					Message: fmt.Sprintf("layer stack %s references missing file %s", stack, rel),
+				})
+			}
+		}
+	}
+
+	if selectorsNode == nil || selectorsNode.Kind != yaml.SequenceNode {
+		return problems
+	}
+	seen := map[string]bool{}
+	for i, node := range selectorsNode.Content {
+		sel := Selector{}
+		if err := node.Decode(&sel); err != nil {
+			problems = append(problems, SelectorProblem{
+				File: name, Line: node.Line,
+				Message: fmt.Sprintf("selector %d does not decode: %v", i, err),
+			})
+			continue
+		}
+		label := sel.Name
+		if label == "" {
+			label = fmt.Sprintf("#%d", i)
+		}
+		add := func(line int, format string, args ...any) {
+			problems = append(problems, SelectorProblem{
+				File: name, Line: line, Selector: label,
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		if node.Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				key := node.Content[j]
+				if !selectorFields[key.Value] {
+					add(key.Line, "%s", unknownKeyMessage(key.Value, selectorFields, "selector field"))
+				}
+			}
+		}
+		if sel.Name == "" {
+			add(node.Line, "missing name")
+		} else if seen[sel.Name] {
+			add(node.Line, "duplicate selector name")
+		} else {
+			seen[sel.Name] = true
+		}
+
+		switch {
+		case sel.Unmanaged:
+			// Unmanaged selectors serve no config; a config source is
+			// optional noise but not an error.
+		case sel.Layers != "" && (sel.Config != "" || len(sel.Overlays) > 0):
+			add(node.Line, "sets both layers and config/overlays")
+		case sel.Layers != "":
+			if _, ok := sf.Layers[sel.Layers]; !ok {
+				add(node.Line, "references unknown layer stack %s", sel.Layers)
+			}
+		case sel.Config == "" && len(sel.Overlays) == 0:
+			if !exists(BaseConfigFile) {
+				add(node.Line, "has no config, overlays, or layers and the tree has no %s", BaseConfigFile)
+			}
+		default:
+			if sel.Config != "" && !exists(sel.Config) {
+				add(node.Line, "references missing config %s", sel.Config)
+			}
+			for _, overlay := range sel.Overlays {
+				if !exists(overlay) {
+					add(node.Line, "references missing overlay %s", overlay)
+				}
+			}
+		}
+		if sel.Format != "" && sel.Format != "yaml" && sel.Format != "json" && sel.Format != "toml" {
+			add(node.Line, "unknown format %q", sel.Format)
+		}
+	}
+	return problems
+}
+
+// lintSelectors validates every selectors file in the snapshot.
+func lintSelectors(files map[string][]byte) []SelectorProblem {
+	var problems []SelectorProblem
+	for name, raw := range files {
+		prefix, ok := strings.CutSuffix(name, SelectorsFile)
+		if !ok || (prefix != "" && !strings.HasSuffix(prefix, "/")) {
+			continue
+		}
+		problems = append(problems, lintSelectorsFile(name, prefix, raw, files)...)
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintSelectorsFile(t *testing.T) {
+	files := map[string][]byte{
+		"configs/prod.yaml":  []byte("receivers: {}\n"),
+		"overlays/debug.yml": []byte("service: {}\n"),
+	}
+	raw := []byte(`selectors:
+  - name: prod
+    match: {labels: {env: prod}}
+    config: configs/prod.yaml
+    overlay: overlays/debug.yml
+  - match: {labels: {env: dev}}
+    config: configs/missing.yaml
+  - name: prod
+    config: configs/prod.yaml
+    format: xml
+`)
+	problems := lintSelectorsFile(SelectorsFile, "", raw, files)
+
+	wants := []string{
+		`did you mean "overlays"?`,
+		"missing name",
+		"references missing config configs/missing.yaml",
+		"duplicate selector name",
+		`unknown format "xml"`,
+	}
+	for _, want := range wants {
+		found := false
+		for _, p := range problems {
+			if strings.Contains(p.Message, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing problem %q in %v", want, problems)
+		}
+	}
+	for _, p := range problems {
+		if p.File != SelectorsFile {
+			t.Errorf("problem does not name the file: %+v", p)
+		}
+	}
+	// Line numbers point into the document.
+	if problems[0].Line == 0 {
+		t.Errorf("expected a line number, got %+v", problems[0])
+	}
+}
+
+func TestLintSelectorsFileClean(t *testing.T) {
+	files := map[string][]byte{
+		"base.yaml":         []byte("receivers: {}\n"),
+		"configs/prod.yaml": []byte("receivers: {}\n"),
+	}
+	raw := []byte(`layers:
+  prod-stack:
+    - configs/prod.yaml
+selectors:
+  - name: prod
+    match: {labels: {env: prod}}
+    layers: prod-stack
+  - name: fallback
+`)
+	if problems := lintSelectorsFile(SelectorsFile, "", raw, files); len(problems) != 0 {
+		t.Errorf("clean selectors file produced problems: %v", problems)
+	}
+}
+
+func TestLoadConfigsRecordsSelectorProblems(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"_selectors.yaml": "selectors:\n  - name: s\n    config: configs/nope.yaml\n",
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	problems := store.SelectorProblems()
+	if len(problems) != 1 || problems[0].Selector != "s" {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SelectorConflict is one routing problem between two selectors in the same
+// config tree: a selector that can never match because an earlier one takes
+// everything it would, or two selectors that may ambiguously overlap.
+type SelectorConflict struct {
+	// Tree is the config tree prefix ("" for the repository root).
+	Tree     string `json:"tree,omitempty"`
+	Selector string `json:"selector"`
+	Other    string `json:"other"`
+	// Kind is "shadowed" or "overlap".
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+func (c SelectorConflict) String() string {
+	return fmt.Sprintf("selector %s: %s", c.Selector, c.Message)
+}
+
+// AnalyzeSelectors detects selectors that can never match (an earlier,
+// higher-precedence selector subsumes their match criteria) and
+// equal-priority selectors whose criteria may overlap, where routing is
+// decided only by the name tiebreak. The analysis is conservative: pattern
+// values are never treated as subsuming, so it reports no false shadows.
+func AnalyzeSelectors(selectors []Selector) []SelectorConflict {
+	ordered := NewSelectorMatcher(selectors).selectors
+	var conflicts []SelectorConflict
+	for j := 1; j < len(ordered); j++ {
+		shadowed := false
+		for i := 0; i < j; i++ {
+			if matchSubsumes(ordered[i].Match, ordered[j].Match) {
+				conflicts = append(conflicts, SelectorConflict{
+					Selector: ordered[j].Name,
+					Other:    ordered[i].Name,
+					Kind:     "shadowed",
+					Message: fmt.Sprintf("can never match: %s matches a superset of its agents and has higher precedence",
+						ordered[i].Name),
+				})
+				shadowed = true
+				break
+			}
+		}
+		if shadowed {
+			continue
+		}
+		for i := 0; i < j; i++ {
+			if ordered[i].Priority != ordered[j].Priority {
+				continue
+			}
+			if matchesMayOverlap(ordered[i].Match, ordered[j].Match) && !matchSubsumes(ordered[i].Match, ordered[j].Match) {
+				conflicts = append(conflicts, SelectorConflict{
+					Selector: ordered[j].Name,
+					Other:    ordered[i].Name,
+					Kind:     "overlap",
+					Message: fmt.Sprintf("may overlap with %s at equal priority; the tie is broken only by name order",
+						ordered[i].Name),
+				})
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// matchSubsumes reports whether a matches every agent b matches: each of
+// a's constraints is also one of b's. Pattern values (globs, regexps) only
+// count when textually identical.
+func matchSubsumes(a, b SelectorMatch) bool {
+	for key, want := range a.Labels {
+		if b.Labels[key] != want {
+			return false
+		}
+	}
+	for key, want := range a.Attrs {
+		if b.Attrs[key] != want {
+			return false
+		}
+	}
+	for _, expr := range a.Expressions {
+		if !containsExpression(b.Expressions, expr) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesMayOverlap reports whether some agent could satisfy both criteria.
+// Only exact values on a shared key can prove the criteria disjoint;
+// patterns are assumed to overlap.
+func matchesMayOverlap(a, b SelectorMatch) bool {
+	for key, want := range a.Labels {
+		other, ok := b.Labels[key]
+		if !ok {
+			continue
+		}
+		if want != other && isExactLabelValue(want) && isExactLabelValue(other) {
+			return false
+		}
+	}
+	for key, want := range a.Attrs {
+		other, ok := b.Attrs[key]
+		if !ok {
+			continue
+		}
+		if want != other && isExactLabelValue(want) && isExactLabelValue(other) {
+			return false
+		}
+	}
+	return true
+}
+
+func isExactLabelValue(want string) bool {
+	return !labelValueIsPattern(want)
+}
+
+func containsExpression(exprs []MatchExpression, want MatchExpression) bool {
+	for _, e := range exprs {
+		if e.Key != want.Key || e.Operator != want.Operator || len(e.Values) != len(want.Values) {
+			continue
+		}
+		same := true
+		for i := range e.Values {
+			if e.Values[i] != want.Values[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
+
+// SelectorConflicts runs the shadowing analysis over every config tree in
+// the current snapshot.
+func (s *Store) SelectorConflicts() []SelectorConflict {
+	s.mu.RLock()
+	prefixes := make([]string, 0, len(s.selectors))
+	for prefix := range s.selectors {
+		prefixes = append(prefixes, prefix)
+	}
+	s.mu.RUnlock()
+	sort.Strings(prefixes)
+
+	var conflicts []SelectorConflict
+	for _, prefix := range prefixes {
+		for _, c := range AnalyzeSelectors(s.SelectorsAt(prefix)) {
+			c.Tree = prefix
+			conflicts = append(conflicts, c)
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,58 @@
+package config
+
+import "testing"
+
+func TestAnalyzeSelectorsShadowing(t *testing.T) {
+	selectors := []Selector{
+		{Name: "catch-all", Priority: 10},
+		{Name: "prod", Match: SelectorMatch{Labels: map[string]string{"env": "prod"}}},
+	}
+	conflicts := AnalyzeSelectors(selectors)
+	if len(conflicts) != 1 {
+		t.Fatalf("expected 1 conflict, got %v", conflicts)
+	}
+	c := conflicts[0]
+	if c.Kind != "shadowed" || c.Selector != "prod" || c.Other != "catch-all" {
+		t.Fatalf("unexpected conflict: %+v", c)
+	}
+}
+
+func TestAnalyzeSelectorsOverlap(t *testing.T) {
+	selectors := []Selector{
+		{Name: "linux", Match: SelectorMatch{Labels: map[string]string{"os": "linux"}}},
+		{Name: "prod", Match: SelectorMatch{Labels: map[string]string{"env": "prod"}}},
+	}
+	conflicts := AnalyzeSelectors(selectors)
+	if len(conflicts) != 1 || conflicts[0].Kind != "overlap" {
+		t.Fatalf("expected 1 overlap, got %v", conflicts)
+	}
+}
+
+func TestAnalyzeSelectorsDisjoint(t *testing.T) {
+	selectors := []Selector{
+		{Name: "prod", Match: SelectorMatch{Labels: map[string]string{"env": "prod"}}},
+		{Name: "dev", Match: SelectorMatch{Labels: map[string]string{"env": "dev"}}},
+		// Higher priority looks ahead of prod/dev but matches a disjoint
+		// set, and patterns never subsume.
+		{Name: "canary", Priority: 5, Match: SelectorMatch{Labels: map[string]string{"env": "canary-*"}}},
+	}
+	if conflicts := AnalyzeSelectors(selectors); len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %v", conflicts)
+	}
+}
+
+func TestAnalyzeSelectorsExpressionSubsumption(t *testing.T) {
+	selectors := []Selector{
+		{Name: "broad", Match: SelectorMatch{Expressions: []MatchExpression{
+			{Key: "region", Operator: "Exists"},
+		}}},
+		{Name: "narrow", Match: SelectorMatch{Expressions: []MatchExpression{
+			{Key: "region", Operator: "Exists"},
+			{Key: "env", Operator: "In", Values: []string{"prod"}},
+		}}},
+	}
+	conflicts := AnalyzeSelectors(selectors)
+	if len(conflicts) != 1 || conflicts[0].Kind != "shadowed" || conflicts[0].Selector != "narrow" {
+		t.Fatalf("expected narrow shadowed by broad, got %v", conflicts)
+	}
+}
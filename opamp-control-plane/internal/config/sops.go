@@ -0,0 +1,73 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Decryptor decrypts encrypted config files encountered during LoadConfigs,
+// so secrets can live in git safely and still be delivered to agents in the
+// clear.
+type Decryptor interface {
+	Decrypt(path string, data []byte) ([]byte, error)
+}
+
+// SetDecryptor wires a decryptor into snapshot loading. Without one, an
+// encrypted file fails the load rather than being served as ciphertext.
+func (s *Store) SetDecryptor(d Decryptor) {
+	s.decryptor = d
+}
+
+// isSOPSEncrypted reports whether a YAML document carries SOPS encryption
+// metadata: a top-level "sops" mapping with a version. Unparseable files are
+// not encrypted; the validator deals with them later.
+func isSOPSEncrypted(data []byte) bool {
+	var doc struct {
+		Sops struct {
+			Version string `yaml:"version"`
+		} `yaml:"sops"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	return doc.Sops.Version != ""
+}
+
+// SopsDecryptor decrypts SOPS files by shelling out to the sops binary, which
+// brings its full key management (age, KMS, PGP) without linking it in. Key
+// material is configured the way sops expects: ambient cloud credentials, or
+// an age key file passed through SOPS_AGE_KEY_FILE.
+type SopsDecryptor struct {
+	binary     string
+	ageKeyFile string
+}
+
+// NewSopsDecryptor returns a decryptor invoking binary ("sops" when empty),
+// optionally pointing it at an age key file.
+func NewSopsDecryptor(binary, ageKeyFile string) *SopsDecryptor {
+	if binary == "" {
+		binary = "sops"
+	}
+	return &SopsDecryptor{binary: binary, ageKeyFile: ageKeyFile}
+}
+
+// Decrypt implements Decryptor.
+func (d *SopsDecryptor) Decrypt(path string, data []byte) ([]byte, error) {
+	cmd := exec.Command(d.binary, "--decrypt", "--input-type", "yaml", "--output-type", "yaml", "/dev/stdin")
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = os.Environ()
+	if d.ageKeyFile != "" {
+		cmd.Env = append(cmd.Env, "SOPS_AGE_KEY_FILE="+d.ageKeyFile)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("decrypt %s: %w: %s", path, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
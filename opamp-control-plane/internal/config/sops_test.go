@@ -0,0 +1,74 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const sopsEncrypted = `receivers:
+  otlp:
+    api_key: ENC[AES256_GCM,data:abc,iv:def,tag:ghi,type:str]
+sops:
+  version: 3.8.1
+  age:
+    - recipient: age1example
+`
+
+type stubDecryptor struct {
+	decrypted []string
+}
+
+func (d *stubDecryptor) Decrypt(path string, data []byte) ([]byte, error) {
+	d.decrypted = append(d.decrypted, path)
+	return []byte("receivers:\n  otlp:\n    api_key: plaintext\n"), nil
+}
+
+func TestIsSOPSEncrypted(t *testing.T) {
+	if !isSOPSEncrypted([]byte(sopsEncrypted)) {
+		t.Error("encrypted file not detected")
+	}
+	if isSOPSEncrypted([]byte("receivers: {otlp: {}}\n")) {
+		t.Error("plain file detected as encrypted")
+	}
+	if isSOPSEncrypted([]byte(":\tnot yaml")) {
+		t.Error("unparseable file detected as encrypted")
+	}
+}
+
+func TestLoadConfigsDecryptsSOPSFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"configs/secret.yaml": sopsEncrypted,
+		"configs/plain.yaml":  "receivers: {otlp: {}}\n",
+	})
+	store := NewStore()
+	dec := &stubDecryptor{}
+	store.SetDecryptor(dec)
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+
+	data, ok := store.File("configs/secret.yaml")
+	if !ok {
+		t.Fatal("decrypted file missing from snapshot")
+	}
+	if !strings.Contains(string(data), "plaintext") {
+		t.Errorf("snapshot holds ciphertext:\n%s", data)
+	}
+	if len(dec.decrypted) != 1 || dec.decrypted[0] != "configs/secret.yaml" {
+		t.Errorf("decrypted files = %v, want [configs/secret.yaml]", dec.decrypted)
+	}
+}
+
+func TestLoadConfigsRejectsEncryptedWithoutDecryptor(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"configs/secret.yaml": sopsEncrypted,
+	})
+	store := NewStore()
+	err := store.LoadConfigs(dir)
+	if err == nil {
+		t.Fatal("expected error loading encrypted file without a decryptor")
+	}
+	if !strings.Contains(err.Error(), "configs/secret.yaml") {
+		t.Errorf("error does not name the file: %v", err)
+	}
+}
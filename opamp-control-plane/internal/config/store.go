@@ -0,0 +1,325 @@
+// Package config loads collector configurations from the synced config
+// repository, matches agents to selectors, merges base configs with
+// overlays, and validates the result before it is offered to an agent.
+//
+// Repository layout:
+//
+//	_selectors.yaml   routing rules mapping agent labels to configs
+//	_distros.yaml     collector distribution manifests for validation
+//	base.yaml         fallback config for agents no selector matches
+//	configs/*.yaml    per-fleet configs referenced by selectors
+//	overlays/*.yaml   partial configs deep-merged on top of a config
+//	vars/**/*.yaml    template variables; see VarsDir
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SelectorsFile is the well-known name of the routing rules file.
+const SelectorsFile = "_selectors.yaml"
+
+// BaseConfigFile is the well-known name of the fallback config.
+const BaseConfigFile = "base.yaml"
+
+// TenantsDir is the directory holding per-tenant config trees. Each
+// subdirectory is a self-contained config root (selectors, base, configs,
+// overlays) for one tenant; the repository root serves the default tenant.
+const TenantsDir = "tenants"
+
+// TenantPrefix returns the repository path prefix for a tenant's config
+// tree. The default (empty) tenant lives at the repository root.
+func TenantPrefix(tenant string) string {
+	if tenant == "" {
+		return ""
+	}
+	return TenantsDir + "/" + tenant + "/"
+}
+
+// Store holds an in-memory snapshot of the config repository. It is replaced
+// atomically by LoadConfigs on every sync, so readers always see a coherent
+// tree.
+type Store struct {
+	mu sync.RWMutex
+	// files maps repository-relative paths to contents.
+	files map[string][]byte
+	// selectors maps config tree prefix ("" for the repository root,
+	// "tenants/<t>/" for tenant trees, an environment root otherwise) to
+	// that tree's parsed selectors.
+	selectors map[string][]Selector
+	// layers maps config tree prefix to that tree's named layer stacks.
+	layers map[string]map[string][]string
+	// distros maps config tree prefix to that tree's distribution
+	// manifests.
+	distros map[string]map[string]DistroManifest
+	commit  string
+	// generation increments on every LoadConfigs, so caches keyed on it
+	// invalidate when the snapshot changes.
+	generation int64
+	// extraSelectors are API-managed selectors (see SetExtraSelectors),
+	// merged into the root tree's selectors and kept across LoadConfigs
+	// reloads.
+	extraSelectors []Selector
+	// selectorProblems are the selectors-file validation findings of the
+	// current snapshot; resolution proceeds regardless, these are for
+	// operators.
+	selectorProblems []SelectorProblem
+	// decryptor, when set, decrypts SOPS-encrypted files during LoadConfigs.
+	decryptor Decryptor
+}
+
+// NewStore returns an empty store. Call LoadConfigs before use.
+func NewStore() *Store {
+	return &Store{
+		files:     map[string][]byte{},
+		selectors: map[string][]Selector{},
+		layers:    map[string]map[string][]string{},
+		distros:   map[string]map[string]DistroManifest{},
+	}
+}
+
+// LoadConfigs reads every YAML file under dir into the store, replacing the
+// previous snapshot, and parses the selectors file if present.
+func (s *Store) LoadConfigs(dir string) error {
+	files, err := s.readTree(dir)
+	if err != nil {
+		return fmt.Errorf("load configs from %s: %w", dir, err)
+	}
+
+	selectors := map[string][]Selector{}
+	layers := map[string]map[string][]string{}
+	distros := map[string]map[string]DistroManifest{}
+	for name, raw := range files {
+		if prefix, ok := treeFilePrefix(name, SelectorsFile); ok {
+			var sf selectorsFile
+			if err := yaml.Unmarshal(raw, &sf); err != nil {
+				return fmt.Errorf("parse %s: %w", name, err)
+			}
+			selectors[prefix] = sf.Selectors
+			layers[prefix] = sf.Layers
+		}
+		if prefix, ok := treeFilePrefix(name, DistrosFile); ok {
+			var df distrosFile
+			if err := yaml.Unmarshal(raw, &df); err != nil {
+				return fmt.Errorf("parse %s: %w", name, err)
+			}
+			distros[prefix] = df.Distros
+		}
+	}
+
+	problems := lintSelectors(files)
+
+	s.mu.Lock()
+	s.files = files
+	s.selectors = selectors
+	s.layers = layers
+	s.distros = distros
+	s.selectorProblems = problems
+	s.generation++
+	s.mu.Unlock()
+	return nil
+}
+
+// SelectorProblems returns the selectors-file validation findings of the
+// current snapshot.
+func (s *Store) SelectorProblems() []SelectorProblem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]SelectorProblem, len(s.selectorProblems))
+	copy(out, s.selectorProblems)
+	return out
+}
+
+// readTree reads every YAML file under dir into a path→contents map,
+// decrypting SOPS files along the way, without touching the snapshot.
+func (s *Store) readTree(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".yaml") && !strings.HasSuffix(path, ".yml") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if isSOPSEncrypted(data) {
+			if s.decryptor == nil {
+				return fmt.Errorf("%s is SOPS-encrypted but no decryptor is configured", rel)
+			}
+			if data, err = s.decryptor.Decrypt(rel, data); err != nil {
+				return err
+			}
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Generation returns the snapshot generation, incremented on every
+// LoadConfigs.
+func (s *Store) Generation() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.generation
+}
+
+// SetCommit records the repository commit this snapshot was loaded from.
+func (s *Store) SetCommit(commit string) {
+	s.mu.Lock()
+	s.commit = commit
+	s.mu.Unlock()
+}
+
+// Commit returns the repository commit of the current snapshot, if known.
+func (s *Store) Commit() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.commit
+}
+
+// File returns the contents of a file in the current snapshot.
+func (s *Store) File(name string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.files[name]
+	return data, ok
+}
+
+// Files returns the sorted names of all files in the current snapshot.
+func (s *Store) Files() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.files))
+	for name := range s.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Selectors returns the selectors for a tenant from the current snapshot.
+func (s *Store) Selectors(tenant string) []Selector {
+	return s.SelectorsAt(TenantPrefix(tenant))
+}
+
+// SelectorsAt returns the selectors of the config tree rooted at prefix
+// ("" for the repository root). API-managed selectors are merged into the
+// root tree, each shadowing a git-defined selector of the same name.
+func (s *Store) SelectorsAt(prefix string) []Selector {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if prefix != "" || len(s.extraSelectors) == 0 {
+		out := make([]Selector, len(s.selectors[prefix]))
+		copy(out, s.selectors[prefix])
+		return out
+	}
+	shadowed := map[string]bool{}
+	for _, sel := range s.extraSelectors {
+		shadowed[sel.Name] = true
+	}
+	var out []Selector
+	for _, sel := range s.selectors[prefix] {
+		if !shadowed[sel.Name] {
+			out = append(out, sel)
+		}
+	}
+	return append(out, s.extraSelectors...)
+}
+
+// LayerStack returns the named layer stack for a tenant from the current
+// snapshot: the ordered repository paths merged for selectors referencing it.
+func (s *Store) LayerStack(tenant, name string) ([]string, bool) {
+	return s.LayerStackAt(TenantPrefix(tenant), name)
+}
+
+// LayerStackAt returns the named layer stack of the config tree rooted at
+// prefix.
+func (s *Store) LayerStackAt(prefix, name string) ([]string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stack, ok := s.layers[prefix][name]
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, len(stack))
+	copy(out, stack)
+	return out, true
+}
+
+// Tenants returns the sorted tenant names that have a selectors file in the
+// current snapshot. The default tenant is returned as "".
+func (s *Store) Tenants() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tenants := make([]string, 0, len(s.selectors))
+	for prefix := range s.selectors {
+		tenant, ok := tenantOfSelectorsFile(prefix + SelectorsFile)
+		if !ok {
+			// Environment roots and other non-tenant trees.
+			continue
+		}
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// treeFilePrefix reports whether path is the given well-known file at a
+// config tree root and, if so, which tree prefix it belongs to.
+func treeFilePrefix(path, file string) (string, bool) {
+	prefix, ok := strings.CutSuffix(path, file)
+	if !ok || (prefix != "" && !strings.HasSuffix(prefix, "/")) {
+		return "", false
+	}
+	return prefix, true
+}
+
+// tenantOfSelectorsFile reports whether path is a selectors file and, if so,
+// which tenant it belongs to.
+func tenantOfSelectorsFile(path string) (string, bool) {
+	if path == SelectorsFile {
+		return "", true
+	}
+	rest, ok := strings.CutPrefix(path, TenantsDir+"/")
+	if !ok {
+		return "", false
+	}
+	tenant, file, ok := strings.Cut(rest, "/")
+	if !ok || file != SelectorsFile {
+		return "", false
+	}
+	return tenant, true
+}
+
+type selectorsFile struct {
+	Selectors []Selector `yaml:"selectors"`
+	// Layers are named stacks of repository paths merged in order, so an
+	// inheritance chain (base → environment → region → workload) is declared
+	// once and referenced by name from selectors.
+	Layers map[string][]string `yaml:"layers"`
+}
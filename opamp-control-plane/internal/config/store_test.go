@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadConfigsLayerStacks(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"_selectors.yaml": "layers:\n  prod-eu:\n    - configs/workload.yaml\n    - overlays/prod.yaml\n    - overlays/eu.yaml\nselectors:\n  - name: s\n    layers: prod-eu\n",
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+
+	stack, ok := store.LayerStack("", "prod-eu")
+	if !ok {
+		t.Fatal("layer stack not found")
+	}
+	want := []string{"configs/workload.yaml", "overlays/prod.yaml", "overlays/eu.yaml"}
+	if len(stack) != len(want) {
+		t.Fatalf("stack = %v, want %v", stack, want)
+	}
+	for i := range want {
+		if stack[i] != want[i] {
+			t.Errorf("stack[%d] = %q, want %q", i, stack[i], want[i])
+		}
+	}
+	if _, ok := store.LayerStack("", "absent"); ok {
+		t.Error("unknown stack reported as present")
+	}
+}
+
+func TestLoadConfigsPerTenantSelectors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"_selectors.yaml":                "selectors:\n  - name: default-prod\n    config: configs/prod.yaml\n",
+		"tenants/acme/_selectors.yaml":   "selectors:\n  - name: acme-prod\n    config: configs/prod.yaml\n",
+		"tenants/acme/configs/prod.yaml": "receivers: {otlp: {}}\n",
+		"tenants/acme/deep/extra.yaml":   "ignored: true\n",
+		"tenants/globex/_selectors.yaml": "selectors: []\n",
+		"tenants/globex/base.yaml":       "receivers: {otlp: {}}\n",
+	})
+
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+
+	if sels := store.Selectors(""); len(sels) != 1 || sels[0].Name != "default-prod" {
+		t.Errorf("default tenant selectors = %+v, want [default-prod]", sels)
+	}
+	if sels := store.Selectors("acme"); len(sels) != 1 || sels[0].Name != "acme-prod" {
+		t.Errorf("acme selectors = %+v, want [acme-prod]", sels)
+	}
+	if sels := store.Selectors("unknown"); len(sels) != 0 {
+		t.Errorf("unknown tenant selectors = %+v, want none", sels)
+	}
+
+	tenants := store.Tenants()
+	want := []string{"", "acme", "globex"}
+	if len(tenants) != len(want) {
+		t.Fatalf("Tenants() = %v, want %v", tenants, want)
+	}
+	for i := range want {
+		if tenants[i] != want[i] {
+			t.Errorf("Tenants() = %v, want %v", tenants, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateData is the data visible to Go template actions in config files:
+// the agent's identity and the selector that routed it. Label and description
+// keys are dotted resource attribute names, so templates read them through
+// the label/attr functions rather than field access.
+type TemplateData struct {
+	InstanceUID string
+	Selector    string
+	Labels      map[string]string
+	Attrs       map[string]string
+	// Vars are the merged variables from the repo's vars/ tree; see VarsDir.
+	Vars map[string]any
+}
+
+// renderTemplate expands Go template actions in one config file against the
+// agent's attributes, so a shared config can carry per-host values (e.g. a
+// hostname in resource attributes) without one file per agent. Files without
+// template actions pass through untouched. Secret references resolve through
+// secrets; each substituted value is appended to used so callers serving the
+// rendered config to operators can redact them.
+func renderTemplate(path string, doc []byte, data *TemplateData, secrets SecretsProvider, used *[]string) ([]byte, error) {
+	if !bytes.Contains(doc, []byte("{{")) {
+		return doc, nil
+	}
+	// Sprig supplies the general-purpose functions (default, ternary,
+	// b64enc, ...); the control plane's own helpers are layered on top and
+	// win on name clashes.
+	funcs := sprig.TxtFuncMap()
+	funcs["toYaml"] = func(v any) (string, error) {
+		out, err := yaml.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes.TrimRight(out, "\n")), nil
+	}
+	funcs["label"] = func(key string) string { return data.Labels[key] }
+	funcs["attr"] = func(key string) string { return data.Attrs[key] }
+	funcs["var"] = func(key string) any { return data.Vars[key] }
+	funcs["secret"] = func(ref string) (string, error) {
+		if secrets == nil {
+			return "", fmt.Errorf("secret %q referenced but no secrets provider is configured", ref)
+		}
+		value, err := secrets.Secret(ref)
+		if err != nil {
+			return "", err
+		}
+		*used = append(*used, value)
+		return value, nil
+	}
+	tmpl, err := template.New(path).Funcs(funcs).Parse(string(doc))
+	if err != nil {
+		return nil, fmt.Errorf("parse template %s: %w", path, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("render template %s: %w", path, err)
+	}
+	return buf.Bytes(), nil
+}
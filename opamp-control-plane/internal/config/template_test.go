@@ -0,0 +1,86 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	data := &TemplateData{
+		InstanceUID: "agent-1",
+		Selector:    "linux-hosts",
+		Labels:      map[string]string{"host.name": "web-01"},
+		Attrs:       map[string]string{"os.version": "6.1"},
+	}
+	doc := []byte(`
+processors:
+  resource:
+    attributes:
+      - key: host.name
+        value: {{ label "host.name" }}
+      - key: os.version
+        value: {{ attr "os.version" }}
+      - key: fleet
+        value: {{ .Selector }}
+`)
+	out, err := renderTemplate("configs/test.yaml", doc, data, nil, nil)
+	if err != nil {
+		t.Fatalf("renderTemplate: %v", err)
+	}
+	for _, want := range []string{"value: web-01", "value: 6.1", "value: linux-hosts"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("rendered config missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderTemplateSprigFunctions(t *testing.T) {
+	data := &TemplateData{
+		Labels: map[string]string{"env": "prod"},
+		Vars:   map[string]any{"endpoints": []any{"a:4317", "b:4317"}},
+	}
+	doc := []byte(`
+exporters:
+  otlp:
+    endpoint: {{ label "collector" | default "collector:4317" }}
+    compression: {{ ternary "zstd" "gzip" (eq (label "env") "prod") }}
+    headers:
+      authorization: Basic {{ b64enc "user:pass" }}
+    endpoints: {{ var "endpoints" | toYaml | nindent 6 }}
+`)
+	out, err := renderTemplate("configs/sprig.yaml", doc, data, nil, nil)
+	if err != nil {
+		t.Fatalf("renderTemplate: %v", err)
+	}
+	for _, want := range []string{
+		"endpoint: collector:4317",
+		"compression: zstd",
+		"authorization: Basic dXNlcjpwYXNz",
+		"- a:4317",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("rendered config missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderTemplatePassthrough(t *testing.T) {
+	doc := []byte("receivers:\n  otlp: {}\n")
+	out, err := renderTemplate("configs/plain.yaml", doc, &TemplateData{}, nil, nil)
+	if err != nil {
+		t.Fatalf("renderTemplate: %v", err)
+	}
+	if string(out) != string(doc) {
+		t.Errorf("plain config was altered:\n%s", out)
+	}
+}
+
+func TestRenderTemplateParseError(t *testing.T) {
+	_, err := renderTemplate("configs/bad.yaml", []byte(`value: {{ label `), &TemplateData{}, nil, nil)
+	if err == nil {
+		t.Fatal("expected parse error")
+	}
+	if !strings.Contains(err.Error(), "configs/bad.yaml") {
+		t.Errorf("error does not name the file: %v", err)
+	}
+}
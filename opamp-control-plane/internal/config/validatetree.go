@@ -0,0 +1,53 @@
+package config
+
+import "fmt"
+
+// ValidateTree loads the config tree rooted at dir into a throwaway snapshot
+// and resolves every selector of every tenant through the resolver's full
+// validation pipeline, without touching the served snapshot. It returns one
+// human-readable problem per finding; an empty slice means the tree is good
+// to merge.
+func (r *Resolver) ValidateTree(dir string) ([]string, error) {
+	store := NewStore()
+	store.decryptor = r.store.decryptor
+	if err := store.LoadConfigs(dir); err != nil {
+		return []string{fmt.Sprintf("load configs: %v", err)}, nil
+	}
+	var problems []string
+	for _, p := range store.SelectorProblems() {
+		problems = append(problems, p.String())
+	}
+	for _, c := range store.SelectorConflicts() {
+		problems = append(problems, c.String())
+	}
+	for _, p := range store.LintOverlays() {
+		problems = append(problems, fmt.Sprintf("%s: %s: %s", p.File, p.Key, p.Message))
+	}
+	candidate := r.forStore(store)
+	for _, tenant := range store.Tenants() {
+		for _, sel := range store.Selectors(tenant) {
+			if _, err := candidate.ResolveSelector(tenant, sel.Name, ""); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+	return problems, nil
+}
+
+// forStore returns a copy of the resolver bound to a different snapshot,
+// carrying over every validation knob but starting with a fresh cache.
+func (r *Resolver) forStore(store *Store) *Resolver {
+	nr := NewResolver(store, r.logger)
+	nr.merger = r.merger
+	nr.validator = r.validator
+	nr.hasher = r.hasher
+	nr.policy = r.policy
+	nr.rejectPlaintextSecrets = r.rejectPlaintextSecrets
+	nr.maxFileSize = r.maxFileSize
+	nr.maxMergedSize = r.maxMergedSize
+	nr.secrets = r.secrets
+	nr.envLabel = r.envLabel
+	nr.envRoots = r.envRoots
+	nr.envMatchers = r.envMatchers
+	return nr
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Validator performs structural checks on a merged collector config before
+// it is offered to agents. It understands the generic collector config shape
+// (receivers/processors/exporters/extensions plus service.pipelines) without
+// knowing anything about individual component settings; an optional backend
+// (see SetBackend) covers those.
+type Validator struct {
+	backend ComponentValidator
+}
+
+// NewValidator returns a Validator.
+func NewValidator() *Validator {
+	return &Validator{}
+}
+
+// ValidationError describes why a config was rejected.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the config and returns a *ValidationError listing every
+// problem found, or nil if the config is acceptable.
+func (v *Validator) Validate(cfg []byte) error {
+	var root map[string]any
+	if err := yaml.Unmarshal(cfg, &root); err != nil {
+		return &ValidationError{Problems: []string{fmt.Sprintf("not valid YAML: %v", err)}}
+	}
+	if len(root) == 0 {
+		return &ValidationError{Problems: []string{"config is empty"}}
+	}
+
+	var problems []string
+	service, ok := root["service"].(map[string]any)
+	if !ok {
+		problems = append(problems, "missing service section")
+	}
+
+	defined := map[string]map[string]bool{}
+	for _, section := range []string{"receivers", "processors", "exporters", "extensions"} {
+		defined[section] = componentIDs(root[section])
+	}
+
+	if ok {
+		pipelines, _ := service["pipelines"].(map[string]any)
+		if len(pipelines) == 0 {
+			problems = append(problems, "service has no pipelines")
+		}
+		for _, name := range sortedKeys(pipelines) {
+			pipeline, _ := pipelines[name].(map[string]any)
+			problems = append(problems, checkPipelineRefs(name, pipeline, "receivers", defined["receivers"])...)
+			problems = append(problems, checkPipelineRefs(name, pipeline, "processors", defined["processors"])...)
+			problems = append(problems, checkPipelineRefs(name, pipeline, "exporters", defined["exporters"])...)
+		}
+		for _, ext := range stringList(service["extensions"]) {
+			if !defined["extensions"][ext] {
+				problems = append(problems, fmt.Sprintf("service references undefined extension %q", ext))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	// Only a structurally sound config is worth the backend's time; it
+	// would report the same missing sections with worse messages.
+	if v.backend != nil {
+		return v.backend.Validate(cfg)
+	}
+	return nil
+}
+
+// ValidateConfig runs body through the resolver's validator — the same
+// structural checks, component backend, and lint pass applied when a config
+// is offered — and returns the blocking problems and non-blocking warnings.
+func (r *Resolver) ValidateConfig(body []byte) (problems, warnings []string) {
+	if err := r.validator.Validate(body); err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			problems = verr.Problems
+		} else {
+			problems = []string{err.Error()}
+		}
+	}
+	return problems, r.validator.Warnings(body)
+}
+
+// checkPipelineRefs verifies that every component a pipeline references is
+// defined in the corresponding top-level section.
+func checkPipelineRefs(pipeline string, p map[string]any, section string, defined map[string]bool) []string {
+	var problems []string
+	refs := stringList(p[section])
+	if section != "processors" && len(refs) == 0 {
+		problems = append(problems, fmt.Sprintf("pipeline %q has no %s", pipeline, section))
+	}
+	for _, ref := range refs {
+		if !defined[ref] {
+			problems = append(problems, fmt.Sprintf("pipeline %q references undefined %s %q",
+				pipeline, strings.TrimSuffix(section, "s"), ref))
+		}
+	}
+	return problems
+}
+
+func componentIDs(section any) map[string]bool {
+	ids := map[string]bool{}
+	if m, ok := section.(map[string]any); ok {
+		for id := range m {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+func stringList(v any) []string {
+	list, _ := v.([]any)
+	var out []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// VarsDir is the directory in the config repo holding template variable
+// files:
+//
+//	vars/common.yaml           variables for every agent
+//	vars/selectors/<name>.yaml variables for agents routed by that selector
+//	vars/agents/<uid>.yaml     variables for one agent
+//
+// Files are merged in that order, so the most specific value wins. Variables
+// are read in templates through the var function.
+const VarsDir = "vars"
+
+// loadVars collects the template variables applying to one agent from the
+// tenant's vars tree. Missing files are fine; a file that fails to parse is
+// an error so a typo does not silently drop a whole variable layer.
+func (r *Resolver) loadVars(prefix, selector string, agent *registry.Agent) (map[string]any, error) {
+	vars := map[string]any{}
+	for _, path := range []string{
+		VarsDir + "/common.yaml",
+		VarsDir + "/selectors/" + selector + ".yaml",
+		VarsDir + "/agents/" + agent.InstanceUID + ".yaml",
+	} {
+		data, ok := r.store.File(prefix + path)
+		if !ok {
+			continue
+		}
+		var m map[string]any
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("parse vars %s: %w", path, err)
+		}
+		for k, v := range m {
+			vars[k] = v
+		}
+	}
+	return vars, nil
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+func TestLoadVarsPrecedence(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"vars/common.yaml":          "region: eu\nsample_rate: 1\n",
+		"vars/selectors/linux.yaml": "sample_rate: 5\nlog_level: info\n",
+		"vars/agents/agent-1.yaml":  "sample_rate: 9\n",
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	r := NewResolver(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	vars, err := r.loadVars("", "linux", &registry.Agent{InstanceUID: "agent-1"})
+	if err != nil {
+		t.Fatalf("loadVars: %v", err)
+	}
+	if vars["region"] != "eu" {
+		t.Errorf("region = %v, want eu", vars["region"])
+	}
+	if vars["log_level"] != "info" {
+		t.Errorf("log_level = %v, want info", vars["log_level"])
+	}
+	if vars["sample_rate"] != 9 {
+		t.Errorf("sample_rate = %v, want the per-agent value 9", vars["sample_rate"])
+	}
+}
+
+func TestLoadVarsParseError(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"vars/common.yaml": ":\tnot yaml",
+	})
+	store := NewStore()
+	if err := store.LoadConfigs(dir); err != nil {
+		t.Fatalf("LoadConfigs: %v", err)
+	}
+	r := NewResolver(store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := r.loadVars("", "base", &registry.Agent{InstanceUID: "agent-1"}); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultProvider reads secrets from HashiCorp Vault over its HTTP API. A
+// reference's last path element names the field within the secret; the rest
+// is the Vault read path, so "kv/otel/api-key" reads field "api-key" from
+// "kv/otel" (KV v2 paths include the "data" element: "kv/data/otel/api-key").
+type VaultProvider struct {
+	addr   string
+	token  string
+	client *http.Client
+}
+
+// NewVaultProvider returns a provider reading from the Vault server at addr
+// using token.
+func NewVaultProvider(addr, token string) *VaultProvider {
+	return &VaultProvider{
+		addr:   strings.TrimSuffix(addr, "/"),
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Secret implements SecretsProvider.
+func (v *VaultProvider) Secret(ref string) (string, error) {
+	path, field, ok := splitSecretRef(ref)
+	if !ok {
+		return "", fmt.Errorf("invalid secret reference %q: want <path>/<field>", ref)
+	}
+	req, err := http.NewRequest(http.MethodGet, v.addr+"/v1/"+path, nil)
+	if err != nil {
+		return "", fmt.Errorf("read secret %s: %w", path, err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("read secret %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("read secret %s: vault returned %s", path, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode secret %s: %w", path, err)
+	}
+	fields := body.Data
+	// KV v2 nests the fields one level deeper than KV v1.
+	if inner, ok := body.Data["data"].(map[string]any); ok {
+		fields = inner
+	}
+	value, ok := fields[field].(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s has no string field %q", path, field)
+	}
+	return value, nil
+}
+
+// splitSecretRef splits a reference into the Vault read path and the field
+// name carried as its last element.
+func splitSecretRef(ref string) (path, field string, ok bool) {
+	i := strings.LastIndex(ref, "/")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", false
+	}
+	return ref[:i], ref[i+1:], true
+}
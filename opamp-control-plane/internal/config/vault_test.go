@@ -0,0 +1,82 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVaultProviderSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Vault-Token"); got != "test-token" {
+			t.Errorf("X-Vault-Token = %q", got)
+		}
+		switch r.URL.Path {
+		case "/v1/kv/otel":
+			// KV v1 shape.
+			w.Write([]byte(`{"data": {"api-key": "s3cret"}}`))
+		case "/v1/kv/data/otel":
+			// KV v2 nests fields one level deeper.
+			w.Write([]byte(`{"data": {"data": {"api-key": "v2secret"}, "metadata": {}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	v := NewVaultProvider(srv.URL, "test-token")
+
+	got, err := v.Secret("kv/otel/api-key")
+	if err != nil {
+		t.Fatalf("Secret: %v", err)
+	}
+	if got != "s3cret" {
+		t.Errorf("Secret = %q, want s3cret", got)
+	}
+
+	got, err = v.Secret("kv/data/otel/api-key")
+	if err != nil {
+		t.Fatalf("Secret (kv v2): %v", err)
+	}
+	if got != "v2secret" {
+		t.Errorf("Secret = %q, want v2secret", got)
+	}
+
+	if _, err := v.Secret("kv/otel/missing"); err == nil {
+		t.Error("expected error for missing field")
+	}
+	if _, err := v.Secret("kv/absent/key"); err == nil {
+		t.Error("expected error for missing secret")
+	}
+	if _, err := v.Secret("nofield"); err == nil {
+		t.Error("expected error for reference without a field")
+	}
+}
+
+func TestRenderTemplateRedactsSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"token": "hunter2"}}`))
+	}))
+	defer srv.Close()
+
+	var used []string
+	out, err := renderTemplate("configs/s.yaml",
+		[]byte(`headers: {auth: {{ secret "kv/otel/token" }}}`),
+		&TemplateData{}, NewVaultProvider(srv.URL, "t"), &used)
+	if err != nil {
+		t.Fatalf("renderTemplate: %v", err)
+	}
+	if string(out) != "headers: {auth: hunter2}" {
+		t.Errorf("rendered = %q", out)
+	}
+	if len(used) != 1 || used[0] != "hunter2" {
+		t.Errorf("used secrets = %v, want [hunter2]", used)
+	}
+}
+
+func TestRenderTemplateSecretWithoutProvider(t *testing.T) {
+	if _, err := renderTemplate("configs/s.yaml",
+		[]byte(`{{ secret "kv/otel/token" }}`), &TemplateData{}, nil, nil); err == nil {
+		t.Fatal("expected error without a secrets provider")
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Warnings reports semantic anti-patterns in a merged config: things that
+// deploy fine but hurt in production. Unlike Validate, none of these block
+// delivery; they are surfaced through the validation API and logged when the
+// config is offered.
+func (v *Validator) Warnings(cfg []byte) []string {
+	var root map[string]any
+	if err := yaml.Unmarshal(cfg, &root); err != nil {
+		return nil // Validate already rejects unparseable configs.
+	}
+
+	var warnings []string
+	service, _ := root["service"].(map[string]any)
+	pipelines, _ := service["pipelines"].(map[string]any)
+	for _, name := range sortedKeys(pipelines) {
+		pipeline, _ := pipelines[name].(map[string]any)
+		warnings = append(warnings, lintPipelineProcessors(name, stringList(pipeline["processors"]))...)
+	}
+
+	exporters, _ := root["exporters"].(map[string]any)
+	for _, id := range sortedKeys(exporters) {
+		if !exporterRetries(id, exporters[id]) {
+			warnings = append(warnings,
+				fmt.Sprintf("exporter %q has no retry_on_failure settings; transient backend errors will drop data", id))
+		}
+	}
+
+	receivers, _ := root["receivers"].(map[string]any)
+	for _, id := range sortedKeys(receivers) {
+		if componentType(id) != "filelog" {
+			continue
+		}
+		settings, _ := receivers[id].(map[string]any)
+		for _, include := range stringList(settings["include"]) {
+			if strings.Contains(include, "*") {
+				warnings = append(warnings,
+					fmt.Sprintf("receiver %q include %q uses a wildcard; it can match an unbounded set of files", id, include))
+			}
+		}
+	}
+	return warnings
+}
+
+// lintPipelineProcessors checks one pipeline's processor chain: every
+// pipeline should batch, and memory_limiter only protects the collector when
+// it runs first.
+func lintPipelineProcessors(pipeline string, processors []string) []string {
+	var warnings []string
+	hasBatch := false
+	for i, id := range processors {
+		switch componentType(id) {
+		case "batch":
+			hasBatch = true
+		case "memory_limiter":
+			if i != 0 {
+				warnings = append(warnings,
+					fmt.Sprintf("pipeline %q has memory_limiter at position %d; it must run first to protect the collector", pipeline, i))
+			}
+		}
+	}
+	if !hasBatch {
+		warnings = append(warnings, fmt.Sprintf("pipeline %q has no batch processor", pipeline))
+	}
+	return warnings
+}
+
+// exporterRetries reports whether an exporter is configured to retry, or is
+// one of the sinks where retrying is meaningless.
+func exporterRetries(id string, settings any) bool {
+	switch componentType(id) {
+	case "debug", "logging", "nop":
+		return true
+	}
+	m, ok := settings.(map[string]any)
+	if !ok {
+		return false
+	}
+	_, ok = m["retry_on_failure"]
+	return ok
+}
+
+// componentType is the component id without the optional /name suffix.
+func componentType(id string) string {
+	t, _, _ := strings.Cut(id, "/")
+	return t
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWarningsAntiPatterns(t *testing.T) {
+	cfg := []byte(`
+receivers:
+  otlp: {}
+  filelog:
+    include: ["/var/log/app/*.log"]
+processors:
+  memory_limiter: {limit_mib: 512}
+  batch: {}
+exporters:
+  otlp:
+    endpoint: collector:4317
+service:
+  pipelines:
+    traces:
+      receivers: [otlp]
+      processors: [batch, memory_limiter]
+      exporters: [otlp]
+    logs:
+      receivers: [filelog]
+      exporters: [otlp]
+`)
+	warnings := NewValidator().Warnings(cfg)
+
+	wants := []string{
+		`pipeline "logs" has no batch processor`,
+		`pipeline "traces" has memory_limiter at position 1`,
+		`exporter "otlp" has no retry_on_failure settings`,
+		`receiver "filelog" include "/var/log/app/*.log" uses a wildcard`,
+	}
+	for _, want := range wants {
+		found := false
+		for _, w := range warnings {
+			if strings.Contains(w, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("missing warning %q in %v", want, warnings)
+		}
+	}
+}
+
+func TestWarningsCleanConfig(t *testing.T) {
+	cfg := []byte(`
+receivers:
+  otlp: {}
+processors:
+  memory_limiter: {limit_mib: 512}
+  batch: {}
+exporters:
+  otlp:
+    endpoint: collector:4317
+    retry_on_failure: {enabled: true}
+  debug: {}
+service:
+  pipelines:
+    traces:
+      receivers: [otlp]
+      processors: [memory_limiter, batch]
+      exporters: [otlp, debug]
+`)
+	if warnings := NewValidator().Warnings(cfg); len(warnings) != 0 {
+		t.Errorf("clean config produced warnings: %v", warnings)
+	}
+}
@@ -0,0 +1,122 @@
+// Package federation aggregates read-only fleet views from downstream
+// control planes. One instance per region or site keeps its own OpAMP
+// connections and registry; a federating instance queries their REST APIs
+// and presents a unified inventory.
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// Peer is one downstream control plane.
+type Peer struct {
+	// Name identifies the peer in aggregated results, e.g. "eu-west".
+	Name string
+	// URL is the base URL of the peer's REST API.
+	URL string
+	// Token, when set, is sent as a bearer token.
+	Token string
+}
+
+// Agent is a downstream agent annotated with the peer it was reported by.
+type Agent struct {
+	registry.Agent
+	Peer string `json:"peer"`
+}
+
+// Aggregator queries downstream peers and merges their fleet views.
+type Aggregator struct {
+	peers  []Peer
+	client *http.Client
+	logger *slog.Logger
+}
+
+// New returns an Aggregator over the given peers.
+func New(peers []Peer, logger *slog.Logger) *Aggregator {
+	return &Aggregator{
+		peers:  peers,
+		client: &http.Client{Timeout: 15 * time.Second},
+		logger: logger,
+	}
+}
+
+// Agents fetches the agent listing from every peer concurrently, passing the
+// given query parameters through. Unreachable peers do not fail the whole
+// view; their errors are returned alongside the agents that were collected.
+func (a *Aggregator) Agents(ctx context.Context, query url.Values) ([]*Agent, map[string]string) {
+	var mu sync.Mutex
+	var agents []*Agent
+	errs := map[string]string{}
+
+	var wg sync.WaitGroup
+	for _, peer := range a.peers {
+		wg.Add(1)
+		go func(peer Peer) {
+			defer wg.Done()
+			peerAgents, err := a.fetchAgents(ctx, peer, query)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				a.logger.Error("federation peer query failed", "peer", peer.Name, "error", err)
+				errs[peer.Name] = err.Error()
+				return
+			}
+			agents = append(agents, peerAgents...)
+		}(peer)
+	}
+	wg.Wait()
+
+	sort.Slice(agents, func(i, j int) bool {
+		if agents[i].Peer != agents[j].Peer {
+			return agents[i].Peer < agents[j].Peer
+		}
+		return agents[i].InstanceUID < agents[j].InstanceUID
+	})
+	return agents, errs
+}
+
+func (a *Aggregator) fetchAgents(ctx context.Context, peer Peer, query url.Values) ([]*Agent, error) {
+	u := peer.URL + "/api/v1/agents"
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if peer.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+peer.Token)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("peer returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Agents []*registry.Agent `json:"agents"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode peer response: %w", err)
+	}
+	agents := make([]*Agent, 0, len(body.Agents))
+	for _, ag := range body.Agents {
+		agents = append(agents, &Agent{Agent: *ag, Peer: peer.Name})
+	}
+	return agents, nil
+}
@@ -0,0 +1,137 @@
+package gitsync
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// AppAuth authenticates to GitHub as a GitHub App installation. It mints
+// short-lived installation tokens on demand — signing an app JWT with the
+// private key and exchanging it at the installations API — so the server
+// config never holds a long-lived personal access token.
+type AppAuth struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	apiBase        string
+	client         *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewAppAuth parses the app's PEM private key and derives the API endpoint
+// from the remote URL (github.com or GitHub Enterprise).
+func NewAppAuth(remoteURL string, appID, installationID int64, keyPEM []byte) (*AppAuth, error) {
+	key, err := parseRSAKey(keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	apiBase := "https://api.github.com"
+	if u, err := url.Parse(remoteURL); err == nil && u.Host != "" && u.Host != "github.com" {
+		apiBase = "https://" + u.Host + "/api/v3"
+	}
+	return &AppAuth{
+		appID:          appID,
+		installationID: installationID,
+		key:            key,
+		apiBase:        apiBase,
+		client:         &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Token returns a valid installation token, refreshing it from the API when
+// the cached one is within a minute of expiry.
+func (a *AppAuth) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Until(a.expires) > time.Minute {
+		return a.token, nil
+	}
+	jwt, err := a.signJWT()
+	if err != nil {
+		return "", fmt.Errorf("sign app jwt: %w", err)
+	}
+	endpoint := fmt.Sprintf("%s/app/installations/%d/access_tokens", a.apiBase, a.installationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(nil))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("installation token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("installation token: github responded %s", resp.Status)
+	}
+	var out struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("installation token: %w", err)
+	}
+	a.token = out.Token
+	a.expires = out.ExpiresAt
+	return a.token, nil
+}
+
+// signJWT builds the RS256 app JWT GitHub expects: issued by the app ID,
+// valid for a few minutes, clock skew tolerated.
+func (a *AppAuth) signJWT() (string, error) {
+	now := time.Now()
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-30 * time.Second).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	signing := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signing))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signing + "." + enc.EncodeToString(sig), nil
+}
+
+// parseRSAKey accepts the PKCS#1 keys GitHub issues for apps as well as
+// PKCS#8-wrapped RSA keys.
+func parseRSAKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA key")
+	}
+	return key, nil
+}
@@ -0,0 +1,232 @@
+package gitsync
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BundleSource periodically downloads a config bundle — a .tar.gz or .zip
+// published by CI — and unpacks it into the local config directory, as an
+// alternative source for teams that do not expose git. The syncer runs in
+// local mode over the same directory, so its file watcher picks up every
+// unpacked change. Downloads use ETag-based change detection: an unchanged
+// bundle costs one conditional request and touches nothing on disk.
+type BundleSource struct {
+	url      string
+	interval time.Duration
+	dir      string
+	client   *http.Client
+	logger   *slog.Logger
+
+	etag string
+}
+
+// NewBundleSource returns a source that keeps dir in sync with the bundle
+// at url.
+func NewBundleSource(url string, interval time.Duration, dir string, logger *slog.Logger) *BundleSource {
+	return &BundleSource{
+		url:      url,
+		interval: interval,
+		dir:      dir,
+		client:   &http.Client{Timeout: time.Minute},
+		logger:   logger,
+	}
+}
+
+// Start performs the initial download, then polls until ctx is cancelled.
+func (b *BundleSource) Start(ctx context.Context) error {
+	if err := b.fetchOnce(ctx); err != nil {
+		return fmt.Errorf("initial bundle fetch: %w", err)
+	}
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if err := b.fetchOnce(ctx); err != nil {
+			b.logger.Error("bundle fetch failed", "error", err)
+		}
+	}
+}
+
+// fetchOnce downloads the bundle if it changed and syncs its contents into
+// the config directory, removing files the new bundle no longer contains.
+func (b *BundleSource) fetchOnce(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url, nil)
+	if err != nil {
+		return err
+	}
+	if b.etag != "" {
+		req.Header.Set("If-None-Match", b.etag)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server responded %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	files, err := unpackBundle(b.url, resp.Header.Get("Content-Type"), data)
+	if err != nil {
+		return err
+	}
+	if err := b.syncDir(files); err != nil {
+		return err
+	}
+	b.etag = resp.Header.Get("ETag")
+	b.logger.Info("bundle unpacked", "files", len(files))
+	return nil
+}
+
+// syncDir writes the bundle files under the config directory and removes
+// regular files the bundle no longer contains, leaving the directory itself
+// in place so the local-mode watcher keeps working.
+func (b *BundleSource) syncDir(files map[string][]byte) error {
+	for name, content := range files {
+		full := filepath.Join(b.dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			return fmt.Errorf("mkdir for %s: %w", name, err)
+		}
+		if err := os.WriteFile(full, content, 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return filepath.WalkDir(b.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(b.dir, path)
+		if err != nil {
+			return err
+		}
+		if _, ok := files[filepath.ToSlash(rel)]; !ok {
+			return os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// unpackBundle decodes a .zip or gzipped tarball into relative path →
+// contents, picking the format from the content type or URL. A single
+// top-level directory wrapping every entry (as CI archive tools produce) is
+// stripped.
+func unpackBundle(url, contentType string, data []byte) (map[string][]byte, error) {
+	var files map[string][]byte
+	var err error
+	if strings.Contains(contentType, "zip") || strings.HasSuffix(url, ".zip") {
+		files, err = unpackZip(data)
+	} else {
+		files, err = unpackTarGz(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stripCommonRoot(files), nil
+}
+
+func unpackTarGz(data []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("gzip: %w", err)
+	}
+	defer gz.Close()
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tar: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := filepath.ToSlash(hdr.Name)
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("tar: unsafe path %q", hdr.Name)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("tar %s: %w", hdr.Name, err)
+		}
+		files[name] = content
+	}
+}
+
+func unpackZip(data []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("zip: %w", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name := filepath.ToSlash(f.Name)
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("zip: unsafe path %q", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("zip %s: %w", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("zip %s: %w", f.Name, err)
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+// stripCommonRoot removes a single directory prefix shared by every entry,
+// so "repo-main/base.yaml" style archives unpack to "base.yaml".
+func stripCommonRoot(files map[string][]byte) map[string][]byte {
+	var root string
+	for name := range files {
+		first, _, found := strings.Cut(name, "/")
+		if !found {
+			return files
+		}
+		if root == "" {
+			root = first
+		} else if first != root {
+			return files
+		}
+	}
+	if root == "" {
+		return files
+	}
+	stripped := make(map[string][]byte, len(files))
+	for name, content := range files {
+		stripped[strings.TrimPrefix(name, root+"/")] = content
+	}
+	return stripped
+}
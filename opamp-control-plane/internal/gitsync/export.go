@@ -0,0 +1,179 @@
+package gitsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// ExportConfig configures an Exporter.
+type ExportConfig struct {
+	// URL of the repository to push exports to.
+	URL string
+	// Branch receiving the exports. It must already exist on the remote.
+	Branch string
+	// LocalPath is the export checkout directory.
+	LocalPath string
+	// Interval between exports.
+	Interval time.Duration
+	// Token is an optional bearer token for HTTPS remotes.
+	Token string
+	// AppAuth, when set, authenticates as a GitHub App installation and
+	// takes precedence over Token.
+	AppAuth *AppAuth
+	// Proxy and CABundle configure the HTTPS transport; see Config.
+	Proxy    string
+	CABundle []byte
+}
+
+// Exporter periodically commits a snapshot of deployed state — rendered
+// per-selector configs and a fleet summary — to a git branch, giving
+// auditors a git-native record of exactly what was served. Files are
+// collected by a callback so the exporter stays decoupled from the resolver
+// and registry; a tick where nothing changed commits nothing.
+type Exporter struct {
+	cfg     ExportConfig
+	collect func() (map[string][]byte, error)
+	logger  *slog.Logger
+
+	repo *git.Repository
+}
+
+// NewExporter returns an Exporter that writes the files returned by collect
+// (paths relative to the repository root) on every interval.
+func NewExporter(cfg ExportConfig, collect func() (map[string][]byte, error), logger *slog.Logger) *Exporter {
+	return &Exporter{cfg: cfg, collect: collect, logger: logger}
+}
+
+// Start performs the initial clone, then exports on every interval until ctx
+// is cancelled.
+func (e *Exporter) Start(ctx context.Context) error {
+	if err := e.ensureRepo(ctx); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if err := e.exportOnce(ctx); err != nil {
+			e.logger.Error("export failed", "error", err)
+		}
+	}
+}
+
+// ensureRepo clones the export branch if LocalPath is not already a checkout.
+func (e *Exporter) ensureRepo(ctx context.Context) error {
+	repo, err := git.PlainOpen(e.cfg.LocalPath)
+	if err == nil {
+		e.repo = repo
+		return nil
+	}
+	if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return fmt.Errorf("open %s: %w", e.cfg.LocalPath, err)
+	}
+	e.logger.Info("cloning export repository", "url", e.cfg.URL, "branch", e.cfg.Branch)
+	repo, err = git.PlainCloneContext(ctx, e.cfg.LocalPath, false, &git.CloneOptions{
+		URL:           e.cfg.URL,
+		ReferenceName: plumbing.NewBranchReferenceName(e.cfg.Branch),
+		SingleBranch:  true,
+		Auth:          e.auth(),
+		ProxyOptions:  transport.ProxyOptions{URL: e.cfg.Proxy},
+		CABundle:      e.cfg.CABundle,
+	})
+	if err != nil {
+		return fmt.Errorf("clone %s: %w", e.cfg.URL, err)
+	}
+	e.repo = repo
+	return nil
+}
+
+// exportOnce pulls the export branch, writes the collected files, and
+// commits and pushes if anything changed.
+func (e *Exporter) exportOnce(ctx context.Context) error {
+	wt, err := e.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("worktree: %w", err)
+	}
+	err = wt.PullContext(ctx, &git.PullOptions{
+		ReferenceName: plumbing.NewBranchReferenceName(e.cfg.Branch),
+		SingleBranch:  true,
+		Auth:          e.auth(),
+		ProxyOptions:  transport.ProxyOptions{URL: e.cfg.Proxy},
+		CABundle:      e.cfg.CABundle,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("pull: %w", err)
+	}
+	files, err := e.collect()
+	if err != nil {
+		return fmt.Errorf("collect: %w", err)
+	}
+	for name, content := range files {
+		full := filepath.Join(e.cfg.LocalPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			return fmt.Errorf("mkdir for %s: %w", name, err)
+		}
+		if err := os.WriteFile(full, content, 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			return fmt.Errorf("add %s: %w", name, err)
+		}
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+	if status.IsClean() {
+		return nil
+	}
+	_, err = wt.Commit("Export deployed configs and fleet state", &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  "opamp-control-plane",
+			Email: "opamp-control-plane@localhost",
+			When:  time.Now(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	err = e.repo.PushContext(ctx, &git.PushOptions{
+		Auth:         e.auth(),
+		ProxyOptions: transport.ProxyOptions{URL: e.cfg.Proxy},
+		CABundle:     e.cfg.CABundle,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("push: %w", err)
+	}
+	e.logger.Info("exported deployed state", "files", len(files))
+	return nil
+}
+
+func (e *Exporter) auth() transport.AuthMethod {
+	if e.cfg.AppAuth != nil {
+		token, err := e.cfg.AppAuth.Token(context.Background())
+		if err != nil {
+			e.logger.Error("github app token refresh failed", "error", err)
+			return nil
+		}
+		return &githttp.BasicAuth{Username: "x-access-token", Password: token}
+	}
+	if e.cfg.Token == "" {
+		return nil
+	}
+	return &githttp.BasicAuth{Username: "x-token-auth", Password: e.cfg.Token}
+}
@@ -0,0 +1,88 @@
+package gitsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// StatusPoster posts commit statuses to the GitHub statuses API so pull
+// request validation results show up as checks on the PR.
+type StatusPoster struct {
+	statusURL string // the commit SHA is appended
+	token     string
+	tokenFn   func(context.Context) (string, error)
+	client    *http.Client
+}
+
+// NewStatusPoster derives the statuses endpoint from an HTTPS GitHub remote
+// URL (github.com or GitHub Enterprise). It returns nil for remotes it does
+// not recognize, which disables status posting.
+func NewStatusPoster(remoteURL, token string) *StatusPoster {
+	u, err := url.Parse(remoteURL)
+	if err != nil || u.Scheme != "https" {
+		return nil
+	}
+	repoPath := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if strings.Count(repoPath, "/") != 1 {
+		return nil
+	}
+	base := "https://api.github.com"
+	if u.Host != "github.com" {
+		base = "https://" + u.Host + "/api/v3"
+	}
+	return &StatusPoster{
+		statusURL: base + "/repos/" + repoPath + "/statuses/",
+		token:     token,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SetTokenSource switches authentication to dynamically minted tokens (a
+// GitHub App installation) instead of the static token.
+func (p *StatusPoster) SetTokenSource(fn func(context.Context) (string, error)) {
+	p.tokenFn = fn
+}
+
+// PostStatus reports state ("pending", "success", "failure", "error") for a
+// commit under the opamp-control-plane/config-validation context.
+func (p *StatusPoster) PostStatus(ctx context.Context, sha, state, description string) error {
+	payload, err := json.Marshal(map[string]string{
+		"state":       state,
+		"context":     "opamp-control-plane/config-validation",
+		"description": description,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.statusURL+sha, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/vnd.github+json")
+	token := p.token
+	if p.tokenFn != nil {
+		token, err = p.tokenFn(ctx)
+		if err != nil {
+			return fmt.Errorf("token: %w", err)
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("github responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package gitsync
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// CommitInfo describes one commit in the checkout's history.
+type CommitInfo struct {
+	SHA     string    `json:"sha"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+	// Files are the repository paths the commit changed under the config
+	// root.
+	Files []string `json:"files"`
+}
+
+// History returns up to limit commits from the checkout head, newest first.
+// Files are filtered to pathPrefix ("/"-separated, trailing slash included);
+// an empty prefix lists every changed file. Only valid in git mode.
+func (s *Syncer) History(limit int, pathPrefix string) ([]CommitInfo, error) {
+	if s.repo == nil {
+		return nil, fmt.Errorf("commit history requires a git remote")
+	}
+	head, err := s.repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("head: %w", err)
+	}
+	iter, err := s.repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return nil, fmt.Errorf("log: %w", err)
+	}
+	defer iter.Close()
+
+	commits := make([]CommitInfo, 0, limit)
+	for len(commits) < limit {
+		commit, err := iter.Next()
+		if err != nil {
+			// io.EOF ends the walk; anything else is a broken repository.
+			break
+		}
+		stats, err := commit.Stats()
+		if err != nil {
+			return nil, fmt.Errorf("stats %s: %w", commit.Hash, err)
+		}
+		files := []string{}
+		for _, stat := range stats {
+			if pathPrefix == "" || strings.HasPrefix(stat.Name, pathPrefix) {
+				files = append(files, stat.Name)
+			}
+		}
+		commits = append(commits, CommitInfo{
+			SHA:     commit.Hash.String(),
+			Author:  commit.Author.Name,
+			Email:   commit.Author.Email,
+			Message: strings.TrimSpace(commit.Message),
+			Time:    commit.Author.When.UTC(),
+			Files:   files,
+		})
+	}
+	return commits, nil
+}
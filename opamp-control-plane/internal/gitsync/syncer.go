@@ -0,0 +1,636 @@
+// Package gitsync keeps a local checkout of the config repository up to
+// date, either by polling or when triggered by a webhook, and notifies the
+// rest of the control plane when new commits arrive.
+package gitsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// Config configures a Syncer.
+type Config struct {
+	// URL is the remote repository. If empty, the syncer runs in local mode
+	// and treats LocalPath as authoritative without any git operations.
+	URL string
+	// Branch to track.
+	Branch string
+	// Ref, when set, pins the checkout to a tag or exact commit SHA and
+	// takes precedence over Branch. The worktree only moves when the ref
+	// resolves to a new commit (a retargeted tag, or a settings change).
+	Ref string
+	// LocalPath is the checkout directory (or the config directory itself in
+	// local mode).
+	LocalPath string
+	// PollInterval between automatic syncs.
+	PollInterval time.Duration
+	// Token is an optional bearer token for HTTPS remotes.
+	Token string
+	// AppAuth, when set, authenticates as a GitHub App installation and
+	// takes precedence over Token.
+	AppAuth *AppAuth
+	// Proxy routes the HTTPS transport through the given proxy URL, for
+	// deployments behind corporate egress proxies.
+	Proxy string
+	// CABundle holds additional PEM-encoded CAs trusted for the remote, for
+	// git servers with internally-signed certificates.
+	CABundle []byte
+	// ForceReset hard-resets the checkout to the remote branch head when a
+	// pull fails because the remote history diverged (a force-push),
+	// instead of erroring on every poll until an operator intervenes.
+	ForceReset bool
+	// SigningKeyring is an armored PGP public keyring. When set, every
+	// synced commit must carry a valid signature from one of its keys;
+	// a commit that fails verification is rejected and the checkout is
+	// reverted to the last verified commit.
+	SigningKeyring string
+	// SparseDirs limits the checkout to these directory prefixes (relative
+	// to the repository root, "/"-separated, trailing slash included), so a
+	// large monorepo is not materialized in full. Empty checks out
+	// everything. The full history is still fetched; only the worktree is
+	// sparse.
+	SparseDirs []string
+}
+
+// Syncer maintains the local checkout and invokes the OnSync callback after
+// every successful sync that produced a new commit (and once at startup).
+type Syncer struct {
+	cfg    Config
+	logger *slog.Logger
+
+	repo    *git.Repository
+	trigger chan struct{}
+
+	mu          sync.Mutex
+	onSync      func(commit string)
+	lastCommit  string
+	lastSync    time.Time
+	lastAttempt time.Time
+	lastError   string
+	failures    int
+	// pinned is the commit a rollback detached the checkout at; while set,
+	// polling does not pull so the pin survives until a roll-forward.
+	pinned string
+}
+
+// New returns a Syncer for the given config.
+func New(cfg Config, logger *slog.Logger) *Syncer {
+	return &Syncer{
+		cfg:     cfg,
+		logger:  logger,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// OnSync registers the callback invoked with the new head commit after each
+// successful sync. Must be called before Start.
+func (s *Syncer) OnSync(fn func(commit string)) {
+	s.mu.Lock()
+	s.onSync = fn
+	s.mu.Unlock()
+}
+
+// Trigger requests an immediate sync. It never blocks; triggers arriving
+// while a sync is already pending are coalesced.
+func (s *Syncer) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// LastCommit returns the head commit of the most recent successful sync.
+func (s *Syncer) LastCommit() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastCommit
+}
+
+// Start performs the initial clone/open and sync, then blocks servicing the
+// poll ticker and manual triggers until ctx is cancelled.
+func (s *Syncer) Start(ctx context.Context) error {
+	if s.cfg.URL != "" {
+		if err := s.ensureRepo(ctx); err != nil {
+			return err
+		}
+	} else {
+		// In local mode nothing pulls changes in, so watch the directory and
+		// trigger a sync when files change.
+		go func() {
+			if err := s.watchLocal(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("config watch stopped", "error", err)
+			}
+		}()
+	}
+	if err := s.syncOnce(ctx, true); err != nil {
+		return fmt.Errorf("initial sync: %w", err)
+	}
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-s.trigger:
+		}
+		if err := s.syncOnce(ctx, false); err != nil {
+			s.logger.Error("sync failed", "error", err)
+		}
+	}
+}
+
+// ensureRepo clones the remote if LocalPath is not already a checkout.
+func (s *Syncer) ensureRepo(ctx context.Context) error {
+	repo, err := git.PlainOpen(s.cfg.LocalPath)
+	if err == nil {
+		s.repo = repo
+		return nil
+	}
+	if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return fmt.Errorf("open %s: %w", s.cfg.LocalPath, err)
+	}
+	if s.cfg.Ref != "" {
+		// A pinned ref may live on any branch, so clone everything and
+		// check out the resolved commit detached.
+		s.logger.Info("cloning config repository", "url", s.cfg.URL, "ref", s.cfg.Ref)
+		repo, err = git.PlainCloneContext(ctx, s.cfg.LocalPath, false, &git.CloneOptions{
+			URL:          s.cfg.URL,
+			NoCheckout:   true,
+			Auth:         s.auth(),
+			ProxyOptions: s.proxy(),
+			CABundle:     s.cfg.CABundle,
+		})
+		if err != nil {
+			return fmt.Errorf("clone %s: %w", s.cfg.URL, err)
+		}
+		s.repo = repo
+		hash, err := repo.ResolveRevision(plumbing.Revision(s.cfg.Ref))
+		if err != nil {
+			return fmt.Errorf("resolve %s: %w", s.cfg.Ref, err)
+		}
+		if err := s.checkout(&git.CheckoutOptions{Hash: *hash, Force: true}); err != nil {
+			return fmt.Errorf("checkout %s: %w", s.cfg.Ref, err)
+		}
+		return nil
+	}
+	s.logger.Info("cloning config repository", "url", s.cfg.URL, "branch", s.cfg.Branch)
+	repo, err = git.PlainCloneContext(ctx, s.cfg.LocalPath, false, &git.CloneOptions{
+		URL:           s.cfg.URL,
+		ReferenceName: plumbing.NewBranchReferenceName(s.cfg.Branch),
+		SingleBranch:  true,
+		NoCheckout:    len(s.cfg.SparseDirs) > 0,
+		Auth:          s.auth(),
+		ProxyOptions:  s.proxy(),
+		CABundle:      s.cfg.CABundle,
+	})
+	if err != nil {
+		return fmt.Errorf("clone %s: %w", s.cfg.URL, err)
+	}
+	s.repo = repo
+	if len(s.cfg.SparseDirs) > 0 {
+		err := s.checkout(&git.CheckoutOptions{
+			Branch: plumbing.NewBranchReferenceName(s.cfg.Branch),
+			Force:  true,
+		})
+		if err != nil {
+			return fmt.Errorf("sparse checkout: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkout runs a worktree checkout restricted to the configured sparse
+// directories, or a plain checkout when none are configured.
+func (s *Syncer) checkout(opts *git.CheckoutOptions) error {
+	wt, err := s.repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("worktree: %w", err)
+	}
+	opts.SparseCheckoutDirectories = s.cfg.SparseDirs
+	return wt.Checkout(opts)
+}
+
+// syncOnce pulls the remote (in git mode) and fires OnSync if the head moved
+// or this is the initial sync.
+func (s *Syncer) syncOnce(ctx context.Context, initial bool) error {
+	var commit string
+	if s.cfg.URL != "" {
+		if pinned := s.Pinned(); pinned != "" {
+			s.recordResult(pinned, nil)
+			return nil
+		}
+		changed, head, err := s.PullContext(ctx)
+		if err != nil {
+			s.recordResult("", err)
+			return err
+		}
+		if (changed || initial) && s.cfg.SigningKeyring != "" {
+			if err := s.verifyCommit(head); err != nil {
+				err = fmt.Errorf("commit %s: %w", head, err)
+				if last := s.LastCommit(); last != "" && last != head {
+					if rerr := s.revertTo(last); rerr != nil {
+						s.logger.Error("revert to last verified commit failed", "commit", last, "error", rerr)
+					}
+				}
+				s.recordResult("", err)
+				return err
+			}
+		}
+		if !changed && !initial {
+			s.recordResult(head, nil)
+			return nil
+		}
+		commit = head
+	} else {
+		// Local mode has no commits, so a hash of the directory contents
+		// stands in for one: the pipeline gets the same change detection and
+		// a stable identifier for what was loaded.
+		hash, err := s.hashLocalTree()
+		if err != nil {
+			s.recordResult("", err)
+			return err
+		}
+		if hash == s.LastCommit() && !initial {
+			s.recordResult(hash, nil)
+			return nil
+		}
+		commit = hash
+	}
+	s.recordResult(commit, nil)
+
+	s.mu.Lock()
+	fn := s.onSync
+	s.mu.Unlock()
+	if fn != nil {
+		fn(commit)
+	}
+	return nil
+}
+
+// Pinned returns the commit the checkout is pinned at after a rollback, or
+// "" when tracking the branch normally.
+func (s *Syncer) Pinned() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pinned
+}
+
+// Checkout detaches the local checkout at the given commit and pins it
+// there: polling stops pulling until Checkout is called with an empty
+// commit, which re-attaches to the tracked branch and resumes. It returns
+// the head commit before the checkout so callers can roll forward, and
+// fires OnSync with the new head. Only valid in git mode.
+func (s *Syncer) Checkout(ctx context.Context, commit string) (string, error) {
+	if s.repo == nil {
+		return "", fmt.Errorf("checkout requires a git remote")
+	}
+	head, err := s.repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("head: %w", err)
+	}
+	previous := head.Hash().String()
+
+	if commit == "" {
+		err = s.checkout(&git.CheckoutOptions{
+			Branch: plumbing.NewBranchReferenceName(s.cfg.Branch),
+			Force:  true,
+		})
+		if err != nil {
+			return "", fmt.Errorf("checkout %s: %w", s.cfg.Branch, err)
+		}
+	} else {
+		// The commit may be ahead of the last pull (roll-forward), so fetch
+		// first.
+		err = s.repo.FetchContext(ctx, &git.FetchOptions{
+			Auth:         s.auth(),
+			ProxyOptions: s.proxy(),
+			CABundle:     s.cfg.CABundle,
+		})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return "", fmt.Errorf("fetch: %w", err)
+		}
+		err = s.checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(commit), Force: true})
+		if err != nil {
+			return "", fmt.Errorf("checkout %s: %w", commit, err)
+		}
+	}
+
+	head, err = s.repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("head: %w", err)
+	}
+	current := head.Hash().String()
+
+	s.mu.Lock()
+	s.pinned = commit
+	fn := s.onSync
+	s.mu.Unlock()
+	s.recordResult(current, nil)
+	if fn != nil {
+		fn(current)
+	}
+	return previous, nil
+}
+
+// PullContext fast-forwards the local checkout to the remote branch head.
+// It returns whether the head moved and the new head commit.
+func (s *Syncer) PullContext(ctx context.Context) (bool, string, error) {
+	if s.cfg.Ref != "" {
+		return s.pullPinned(ctx)
+	}
+	if len(s.cfg.SparseDirs) > 0 {
+		return s.pullSparse(ctx)
+	}
+	wt, err := s.repo.Worktree()
+	if err != nil {
+		return false, "", fmt.Errorf("worktree: %w", err)
+	}
+	err = wt.PullContext(ctx, &git.PullOptions{
+		ReferenceName: plumbing.NewBranchReferenceName(s.cfg.Branch),
+		SingleBranch:  true,
+		Auth:          s.auth(),
+		ProxyOptions:  s.proxy(),
+		CABundle:      s.cfg.CABundle,
+	})
+	changed := true
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		changed = false
+	} else if err != nil {
+		if s.cfg.ForceReset && isNonFastForward(err) {
+			s.logger.Warn("remote history diverged, hard-resetting to remote head", "error", err)
+			return s.resetToRemote(ctx)
+		}
+		return false, "", fmt.Errorf("pull: %w", err)
+	}
+	head, err := s.repo.Head()
+	if err != nil {
+		return false, "", fmt.Errorf("head: %w", err)
+	}
+	return changed, head.Hash().String(), nil
+}
+
+// pullSparse fast-forwards a sparse checkout. Worktree.Pull rebuilds the full
+// worktree regardless of the sparse index, so sparse mode fetches and then
+// re-runs the sparse checkout at the new remote head instead.
+func (s *Syncer) pullSparse(ctx context.Context) (bool, string, error) {
+	err := s.repo.FetchContext(ctx, &git.FetchOptions{
+		Auth:         s.auth(),
+		ProxyOptions: s.proxy(),
+		CABundle:     s.cfg.CABundle,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		if s.cfg.ForceReset && isNonFastForward(err) {
+			s.logger.Warn("remote history diverged, hard-resetting to remote head", "error", err)
+			return s.resetToRemote(ctx)
+		}
+		return false, "", fmt.Errorf("fetch: %w", err)
+	}
+	remote, err := s.repo.Reference(plumbing.NewRemoteReferenceName("origin", s.cfg.Branch), true)
+	if err != nil {
+		return false, "", fmt.Errorf("remote head: %w", err)
+	}
+	head, err := s.repo.Head()
+	if err != nil {
+		return false, "", fmt.Errorf("head: %w", err)
+	}
+	if head.Hash() == remote.Hash() {
+		return false, head.Hash().String(), nil
+	}
+	branch := plumbing.NewBranchReferenceName(s.cfg.Branch)
+	if err := s.repo.Storer.SetReference(plumbing.NewHashReference(branch, remote.Hash())); err != nil {
+		return false, "", fmt.Errorf("update %s: %w", branch.Short(), err)
+	}
+	if err := s.checkout(&git.CheckoutOptions{Branch: branch, Force: true}); err != nil {
+		return false, "", fmt.Errorf("checkout: %w", err)
+	}
+	return true, remote.Hash().String(), nil
+}
+
+// isNonFastForward reports whether err is go-git's non-fast-forward error,
+// surfaced either as the worktree sentinel or a wrapped ref update failure.
+func isNonFastForward(err error) bool {
+	return errors.Is(err, git.ErrNonFastForwardUpdate) ||
+		strings.Contains(err.Error(), "non-fast-forward update")
+}
+
+// resetToRemote force-fetches and hard-resets the checkout to the remote
+// branch head, discarding the divergent local history a force-push left
+// behind.
+func (s *Syncer) resetToRemote(ctx context.Context) (bool, string, error) {
+	err := s.repo.FetchContext(ctx, &git.FetchOptions{
+		Auth:         s.auth(),
+		Force:        true,
+		ProxyOptions: s.proxy(),
+		CABundle:     s.cfg.CABundle,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return false, "", fmt.Errorf("fetch: %w", err)
+	}
+	remote, err := s.repo.Reference(plumbing.NewRemoteReferenceName("origin", s.cfg.Branch), true)
+	if err != nil {
+		return false, "", fmt.Errorf("remote head: %w", err)
+	}
+	branch := plumbing.NewBranchReferenceName(s.cfg.Branch)
+	if err := s.repo.Storer.SetReference(plumbing.NewHashReference(branch, remote.Hash())); err != nil {
+		return false, "", fmt.Errorf("update %s: %w", branch.Short(), err)
+	}
+	if err := s.checkout(&git.CheckoutOptions{Branch: branch, Force: true}); err != nil {
+		return false, "", fmt.Errorf("checkout: %w", err)
+	}
+	return true, remote.Hash().String(), nil
+}
+
+// verifyCommit checks that the commit carries a valid PGP signature from one
+// of the configured signing keys.
+func (s *Syncer) verifyCommit(hash string) error {
+	commit, err := s.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return fmt.Errorf("load commit: %w", err)
+	}
+	if commit.PGPSignature == "" {
+		return fmt.Errorf("commit is not signed")
+	}
+	if _, err := commit.Verify(s.cfg.SigningKeyring); err != nil {
+		return fmt.Errorf("signature verification: %w", err)
+	}
+	return nil
+}
+
+// revertTo moves the checkout back to the given commit after a rejected sync.
+// In branch mode the branch ref is rewound so the next pull fast-forwards
+// normally; in ref-pinned mode the checkout is already detached.
+func (s *Syncer) revertTo(commit string) error {
+	if s.cfg.Ref != "" {
+		return s.checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(commit), Force: true})
+	}
+	branch := plumbing.NewBranchReferenceName(s.cfg.Branch)
+	if err := s.repo.Storer.SetReference(plumbing.NewHashReference(branch, plumbing.NewHash(commit))); err != nil {
+		return fmt.Errorf("update %s: %w", branch.Short(), err)
+	}
+	return s.checkout(&git.CheckoutOptions{Branch: branch, Force: true})
+}
+
+// pullPinned keeps the checkout at the configured ref. It fetches so a
+// retargeted tag is seen, resolves the ref, and only touches the worktree
+// when the resolved commit differs from HEAD.
+func (s *Syncer) pullPinned(ctx context.Context) (bool, string, error) {
+	err := s.repo.FetchContext(ctx, &git.FetchOptions{
+		Auth:         s.auth(),
+		Tags:         git.AllTags,
+		Force:        true,
+		ProxyOptions: s.proxy(),
+		CABundle:     s.cfg.CABundle,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return false, "", fmt.Errorf("fetch: %w", err)
+	}
+	hash, err := s.repo.ResolveRevision(plumbing.Revision(s.cfg.Ref))
+	if err != nil {
+		return false, "", fmt.Errorf("resolve %s: %w", s.cfg.Ref, err)
+	}
+	head, err := s.repo.Head()
+	if err != nil {
+		return false, "", fmt.Errorf("head: %w", err)
+	}
+	if head.Hash() == *hash {
+		return false, head.Hash().String(), nil
+	}
+	if err := s.checkout(&git.CheckoutOptions{Hash: *hash, Force: true}); err != nil {
+		return false, "", fmt.Errorf("checkout %s: %w", s.cfg.Ref, err)
+	}
+	return true, hash.String(), nil
+}
+
+// MaterializeRef fetches the given commit — including GitHub pull request
+// head refs — and writes its tree to a temporary directory, returning the
+// directory and a cleanup function. The checkout itself is not touched.
+// Only valid in git mode.
+func (s *Syncer) MaterializeRef(ctx context.Context, sha string) (string, func(), error) {
+	if s.repo == nil {
+		return "", nil, fmt.Errorf("materializing a ref requires a git remote")
+	}
+	err := s.repo.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []gitconfig.RefSpec{
+			"+refs/heads/*:refs/remotes/origin/*",
+			"+refs/pull/*/head:refs/remotes/origin/pull/*",
+		},
+		Auth:         s.auth(),
+		Force:        true,
+		ProxyOptions: s.proxy(),
+		CABundle:     s.cfg.CABundle,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return "", nil, fmt.Errorf("fetch: %w", err)
+	}
+	commit, err := s.repo.CommitObject(plumbing.NewHash(sha))
+	if err != nil {
+		return "", nil, fmt.Errorf("commit %s: %w", sha, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", nil, fmt.Errorf("tree %s: %w", sha, err)
+	}
+	dir, err := os.MkdirTemp("", "opamp-validate-")
+	if err != nil {
+		return "", nil, fmt.Errorf("temp dir: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	err = tree.Files().ForEach(func(f *object.File) error {
+		contents, err := f.Contents()
+		if err != nil {
+			return fmt.Errorf("read %s: %w", f.Name, err)
+		}
+		full := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(full, []byte(contents), 0o644)
+	})
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("materialize %s: %w", sha, err)
+	}
+	return dir, cleanup, nil
+}
+
+// proxy returns the transport proxy configuration; zero when no proxy is
+// set.
+func (s *Syncer) proxy() transport.ProxyOptions {
+	return transport.ProxyOptions{URL: s.cfg.Proxy}
+}
+
+func (s *Syncer) auth() transport.AuthMethod {
+	if s.cfg.AppAuth != nil {
+		token, err := s.cfg.AppAuth.Token(context.Background())
+		if err != nil {
+			s.logger.Error("github app token refresh failed", "error", err)
+			return nil
+		}
+		return &githttp.BasicAuth{Username: "x-access-token", Password: token}
+	}
+	if s.cfg.Token == "" {
+		return nil
+	}
+	return &githttp.BasicAuth{Username: "x-token-auth", Password: s.cfg.Token}
+}
+
+func (s *Syncer) recordResult(commit string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastAttempt = time.Now().UTC()
+	if err != nil {
+		s.lastError = err.Error()
+		s.failures++
+		return
+	}
+	s.lastError = ""
+	s.failures = 0
+	s.lastSync = time.Now().UTC()
+	if commit != "" {
+		s.lastCommit = commit
+	}
+}
+
+// Status is a snapshot of the syncer's health for dashboards.
+type Status struct {
+	LastSync time.Time `json:"last_sync"`
+	// LastCommit is the head of the most recent successful sync.
+	LastCommit string `json:"last_commit,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+	// ConsecutiveFailures counts sync attempts since the last success.
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// NextPoll is when the next automatic sync is due.
+	NextPoll time.Time `json:"next_poll"`
+	// PinnedCommit is set while a rollback pin keeps polling paused.
+	PinnedCommit string `json:"pinned_commit,omitempty"`
+}
+
+// Status reports the syncer's current health.
+func (s *Syncer) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Status{
+		LastSync:            s.lastSync,
+		LastCommit:          s.lastCommit,
+		LastError:           s.lastError,
+		ConsecutiveFailures: s.failures,
+		NextPoll:            s.lastAttempt.Add(s.cfg.PollInterval),
+		PinnedCommit:        s.pinned,
+	}
+}
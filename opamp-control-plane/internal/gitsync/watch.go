@@ -0,0 +1,134 @@
+package gitsync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watcher waits after the last filesystem
+// event before triggering a sync, so an editor save or rsync burst causes
+// one reload instead of one per file.
+const watchDebounce = 500 * time.Millisecond
+
+// watchLocal watches the config directory and triggers a sync whenever a
+// file changes. Only used in local mode, where there is no remote to poll
+// for changes.
+func (s *Syncer) watchLocal(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := addWatchTree(watcher, s.cfg.LocalPath); err != nil {
+		return err
+	}
+	s.logger.Info("watching config directory", "path", s.cfg.LocalPath)
+
+	var debounce *time.Timer
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// New directories must be watched explicitly; fsnotify does not
+			// recurse.
+			if event.Op.Has(fsnotify.Create) {
+				if err := addWatchTree(watcher, event.Name); err != nil {
+					s.logger.Warn("watch new path failed", "path", event.Name, "error", err)
+				}
+			}
+			if debounce == nil {
+				debounce = time.NewTimer(watchDebounce)
+				fire = debounce.C
+			} else {
+				debounce.Reset(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			s.logger.Warn("watch error", "error", err)
+		case <-fire:
+			debounce = nil
+			fire = nil
+			s.Trigger()
+		}
+	}
+}
+
+// hashLocalTree hashes the names and contents of every file under the
+// config directory (except .git), giving local mode a stable stand-in for
+// the head commit: the same tree always produces the same hash, so the rest
+// of the pipeline gets real change detection.
+func (s *Syncer) hashLocalTree() (string, error) {
+	var names []string
+	err := filepath.WalkDir(s.cfg.LocalPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		names = append(names, path)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("walk %s: %w", s.cfg.LocalPath, err)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		rel, err := filepath.Rel(s.cfg.LocalPath, name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		f, err := os.Open(name)
+		if err != nil {
+			return "", fmt.Errorf("open %s: %w", name, err)
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("hash %s: %w", name, err)
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// addWatchTree watches path and, if it is a directory, every directory
+// below it (except .git). Non-directory paths are ignored: fsnotify
+// reports file events through their parent directory's watch.
+func addWatchTree(watcher *fsnotify.Watcher, path string) error {
+	return filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
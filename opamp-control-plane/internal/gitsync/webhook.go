@@ -0,0 +1,182 @@
+package gitsync
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// WebhookHandler accepts git hosting push webhooks — GitHub, Bitbucket Cloud,
+// and Bitbucket Server — and triggers an immediate sync. HMAC signatures are
+// verified when a secret is configured.
+type WebhookHandler struct {
+	syncer     *Syncer
+	secret     string
+	pathPrefix string
+	logger     *slog.Logger
+}
+
+// NewWebhookHandler returns a handler that triggers syncer on push events.
+func NewWebhookHandler(syncer *Syncer, secret string, logger *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{syncer: syncer, secret: secret, logger: logger}
+}
+
+// SetPathFilter restricts sync triggers to pushes whose changed files touch
+// the given repository path prefix ("/"-separated, trailing slash included).
+// Pushes whose payload does not list changed files always trigger.
+func (h *WebhookHandler) SetPathFilter(prefix string) {
+	h.pathPrefix = prefix
+}
+
+// ServeHTTP implements http.Handler.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "read body", http.StatusBadRequest)
+		return
+	}
+	if h.secret != "" && !h.verifySignature(r, body) {
+		h.logger.Warn("webhook signature verification failed", "remote", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if bbEvent := r.Header.Get("X-Event-Key"); bbEvent != "" {
+		// Bitbucket Cloud sends repo:push, Bitbucket Server repo:refs_changed.
+		if bbEvent != "repo:push" && bbEvent != "repo:refs_changed" {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if !h.bitbucketTouchesBranch(body) {
+			h.logger.Info("webhook push is for another branch, ignoring", "event", bbEvent)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		event = bbEvent
+	} else {
+		if event != "" && event != "push" {
+			// Ping and other events are acknowledged but do not trigger a sync.
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if !h.githubRelevant(body) {
+			h.logger.Info("webhook push does not affect the config tree, ignoring")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+
+	h.logger.Info("webhook received, triggering sync", "event", event)
+	h.syncer.Trigger()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// githubPush is the subset of the GitHub push payload used for filtering.
+type githubPush struct {
+	Ref     string `json:"ref"`
+	Commits []struct {
+		Added    []string `json:"added"`
+		Removed  []string `json:"removed"`
+		Modified []string `json:"modified"`
+	} `json:"commits"`
+}
+
+// githubRelevant reports whether a GitHub push is for the tracked branch and
+// touches the configured path filter. Payloads missing the ref or the
+// changed-file lists err on the side of syncing.
+func (h *WebhookHandler) githubRelevant(body []byte) bool {
+	var push githubPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		return true
+	}
+	if push.Ref != "" && h.syncer.cfg.Branch != "" &&
+		push.Ref != "refs/heads/"+h.syncer.cfg.Branch {
+		return false
+	}
+	if h.pathPrefix == "" || len(push.Commits) == 0 {
+		return true
+	}
+	for _, commit := range push.Commits {
+		for _, files := range [][]string{commit.Added, commit.Removed, commit.Modified} {
+			for _, file := range files {
+				if strings.HasPrefix(file, h.pathPrefix) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// bitbucketPush covers the push payload shapes of Bitbucket Cloud
+// (push.changes[].new.name) and Bitbucket Server (changes[].ref.displayId).
+type bitbucketPush struct {
+	Push struct {
+		Changes []struct {
+			New struct {
+				Name string `json:"name"`
+			} `json:"new"`
+		} `json:"changes"`
+	} `json:"push"`
+	Changes []struct {
+		Ref struct {
+			DisplayID string `json:"displayId"`
+		} `json:"ref"`
+	} `json:"changes"`
+}
+
+// bitbucketTouchesBranch reports whether a Bitbucket push payload includes
+// the branch the syncer tracks. Unparseable payloads and pushes without any
+// branch names err on the side of syncing.
+func (h *WebhookHandler) bitbucketTouchesBranch(body []byte) bool {
+	var push bitbucketPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		return true
+	}
+	var branches []string
+	for _, change := range push.Push.Changes {
+		if change.New.Name != "" {
+			branches = append(branches, change.New.Name)
+		}
+	}
+	for _, change := range push.Changes {
+		if change.Ref.DisplayID != "" {
+			branches = append(branches, change.Ref.DisplayID)
+		}
+	}
+	if len(branches) == 0 || h.syncer.cfg.Branch == "" {
+		return true
+	}
+	for _, branch := range branches {
+		if branch == h.syncer.cfg.Branch {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignature checks the HMAC signature header — X-Hub-Signature-256
+// (GitHub) or X-Hub-Signature (Bitbucket) — against the configured secret.
+func (h *WebhookHandler) verifySignature(r *http.Request, body []byte) bool {
+	sig := r.Header.Get("X-Hub-Signature-256")
+	if sig == "" {
+		sig = r.Header.Get("X-Hub-Signature")
+	}
+	if !strings.HasPrefix(sig, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(h.secret))
+	mac.Write(body)
+	want := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(strings.TrimPrefix(sig, "sha256=")), []byte(want))
+}
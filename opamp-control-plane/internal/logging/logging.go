@@ -0,0 +1,95 @@
+// Package logging hands out per-subsystem loggers whose levels can be
+// adjusted at runtime, so debug logging can be turned on for a single
+// component during an incident without restarting the server.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Manager creates subsystem loggers and tracks their level vars.
+type Manager struct {
+	out          io.Writer
+	defaultLevel slog.Level
+
+	mu     sync.Mutex
+	levels map[string]*slog.LevelVar
+}
+
+// NewManager returns a Manager writing to out. Subsystem loggers start at
+// defaultLevel.
+func NewManager(out io.Writer, defaultLevel slog.Level) *Manager {
+	return &Manager{
+		out:          out,
+		defaultLevel: defaultLevel,
+		levels:       map[string]*slog.LevelVar{},
+	}
+}
+
+// Logger returns the logger for a subsystem, creating it on first use. The
+// returned logger carries a "component" attribute and an independently
+// adjustable level.
+func (m *Manager) Logger(subsystem string) *slog.Logger {
+	m.mu.Lock()
+	lv, ok := m.levels[subsystem]
+	if !ok {
+		lv = &slog.LevelVar{}
+		lv.Set(m.defaultLevel)
+		m.levels[subsystem] = lv
+	}
+	m.mu.Unlock()
+	handler := slog.NewTextHandler(m.out, &slog.HandlerOptions{Level: lv})
+	return slog.New(handler).With("component", subsystem)
+}
+
+// SetLevel changes the level of one subsystem, or of every subsystem when
+// subsystem is empty.
+func (m *Manager) SetLevel(subsystem, level string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid level %q", level)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if subsystem == "" {
+		m.defaultLevel = l
+		for _, lv := range m.levels {
+			lv.Set(l)
+		}
+		return nil
+	}
+	lv, ok := m.levels[subsystem]
+	if !ok {
+		return fmt.Errorf("unknown subsystem %q", subsystem)
+	}
+	lv.Set(l)
+	return nil
+}
+
+// Levels returns the current level of every subsystem.
+func (m *Manager) Levels() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]string, len(m.levels))
+	for name, lv := range m.levels {
+		out[name] = strings.ToLower(lv.Level().String())
+	}
+	return out
+}
+
+// Subsystems returns the sorted names of known subsystems.
+func (m *Manager) Subsystems() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.levels))
+	for name := range m.levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
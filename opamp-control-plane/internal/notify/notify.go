@@ -0,0 +1,120 @@
+// Package notify delivers fleet events (connects, disconnects, config
+// transitions) to configured webhook receivers.
+//
+// Deliveries are authenticated: each request carries an X-Fleet-Timestamp
+// header (unix seconds) and an X-Fleet-Signature header of the form
+// "sha256=<hex>", where the signature is HMAC-SHA256 over
+// "<timestamp>.<body>" with the webhook's shared secret. Receivers MUST
+// recompute the HMAC and SHOULD reject deliveries whose timestamp is
+// outside a small replay window (5 minutes is recommended); together these
+// prevent both forgery and replay of captured deliveries.
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// Delivery headers.
+const (
+	HeaderEvent     = "X-Fleet-Event"
+	HeaderTimestamp = "X-Fleet-Timestamp"
+	HeaderSignature = "X-Fleet-Signature"
+)
+
+// Webhook is one configured receiver.
+type Webhook struct {
+	// URL receives POSTed JSON events.
+	URL string
+	// Secret signs deliveries; receivers verify the HMAC with it.
+	Secret string
+	// Events limits delivery to the listed event types. Empty means all.
+	Events []string
+}
+
+// Notifier fans fleet events out to webhooks. Deliveries are asynchronous
+// and best-effort: failures are logged, not retried.
+type Notifier struct {
+	webhooks []Webhook
+	client   *http.Client
+	logger   *slog.Logger
+}
+
+// New returns a Notifier for the given webhooks.
+func New(webhooks []Webhook, logger *slog.Logger) *Notifier {
+	return &Notifier{
+		webhooks: webhooks,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		logger:   logger,
+	}
+}
+
+// Notify delivers the event to every webhook subscribed to its type.
+func (n *Notifier) Notify(event *registry.Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		n.logger.Error("failed to encode event", "error", err)
+		return
+	}
+	for _, wh := range n.webhooks {
+		if !wh.wants(event.Type) {
+			continue
+		}
+		go n.deliver(wh, event.Type, body)
+	}
+}
+
+func (wh Webhook) wants(eventType string) bool {
+	if len(wh.Events) == 0 {
+		return true
+	}
+	for _, t := range wh.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Notifier) deliver(wh Webhook, eventType string, body []byte) {
+	req, err := http.NewRequest(http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		n.logger.Error("webhook delivery failed", "url", wh.URL, "error", err)
+		return
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(wh.Secret, timestamp, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		n.logger.Error("webhook delivery failed", "url", wh.URL, "error", err)
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		n.logger.Error("webhook delivery rejected", "url", wh.URL, "status", resp.StatusCode)
+	}
+}
+
+// Sign computes the delivery signature over "<timestamp>.<body>".
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,46 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"connected"}`)
+	got := Sign("topsecret", "1700000000", body)
+
+	if !strings.HasPrefix(got, "sha256=") {
+		t.Fatalf("signature %q missing sha256= prefix", got)
+	}
+	mac := hmac.New(sha256.New, []byte("topsecret"))
+	mac.Write([]byte("1700000000."))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestSignDependsOnTimestamp(t *testing.T) {
+	body := []byte(`{}`)
+	if Sign("s", "1", body) == Sign("s", "2", body) {
+		t.Error("signatures for different timestamps should differ")
+	}
+}
+
+func TestWebhookWants(t *testing.T) {
+	all := Webhook{}
+	if !all.wants("connected") {
+		t.Error("webhook with no event filter should accept all events")
+	}
+	filtered := Webhook{Events: []string{"config_failed"}}
+	if filtered.wants("connected") {
+		t.Error("filtered webhook should reject unlisted events")
+	}
+	if !filtered.wants("config_failed") {
+		t.Error("filtered webhook should accept listed events")
+	}
+}
@@ -0,0 +1,55 @@
+package opamp
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// gateUnknownAgent enforces the allowlist: only agents with a pre-registered,
+// approved registry record are served. Unknown agents are parked as pending —
+// with whatever description they sent, so an operator can identify them before
+// approving — and answered with an error instead of a config. Returns nil when
+// the agent may proceed.
+//
+// A registry error fails closed; an allowlist that fails open under load is
+// not an allowlist.
+func (s *Server) gateUnknownAgent(uid string, msg *protobufs.AgentToServer, logger *slog.Logger) *protobufs.ServerToAgent {
+	agent, err := s.registry.Get(uid)
+	switch {
+	case err == nil && !agent.Pending:
+		return nil
+	case errors.Is(err, registry.ErrNotFound):
+		parked := &registry.Agent{InstanceUID: uid, Pending: true}
+		if msg.AgentDescription != nil {
+			parked.Labels = normalizeLabels(s.labelNormalize, attrsToMap(msg.AgentDescription.IdentifyingAttributes))
+			parked.Description = normalizeLabels(s.labelNormalize, attrsToMap(msg.AgentDescription.NonIdentifyingAttributes))
+			applyLabelMapping(s.labelMapping, parked.Labels, parked.Description)
+			applyLabelRules(s.labelRules, parked.Labels, parked.Description)
+		}
+		if err := s.registry.Upsert(parked); err != nil {
+			logger.Error("failed to park unknown agent", "error", err)
+		} else if err := s.registry.RecordEvent(uid, registry.EventApprovalChanged, "pending approval"); err != nil {
+			logger.Error("failed to record pending approval", "error", err)
+		}
+		logger.Warn("agent not on allowlist, parked pending approval")
+	case err != nil:
+		logger.Error("allowlist lookup failed", "error", err)
+	default:
+		// Still pending: refresh last_seen so the operator can tell the
+		// agent is waiting, but offer nothing.
+		if err := s.registry.Touch(uid); err != nil {
+			logger.Error("failed to touch pending agent", "error", err)
+		}
+	}
+	return &protobufs.ServerToAgent{
+		InstanceUid: msg.InstanceUid,
+		ErrorResponse: &protobufs.ServerErrorResponse{
+			Type:         protobufs.ServerErrorResponseType_ServerErrorResponseType_Unknown,
+			ErrorMessage: "agent is not on the allowlist; awaiting operator approval",
+		},
+	}
+}
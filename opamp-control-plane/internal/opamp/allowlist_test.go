@@ -0,0 +1,94 @@
+package opamp
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+func newAllowlistTestServer(t *testing.T) (*Server, *registry.Registry) {
+	t.Helper()
+	reg, err := registry.Open(t.TempDir() + "/reg.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reg.Close() })
+	return &Server{
+		registry:     reg,
+		labelMapping: DefaultLabelMapping(),
+		allowlist:    true,
+		logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}, reg
+}
+
+func TestGateUnknownAgentParksPending(t *testing.T) {
+	s, reg := newAllowlistTestServer(t)
+	logger := s.logger
+
+	msg := &protobufs.AgentToServer{
+		InstanceUid: []byte("agent-0000000001"),
+		AgentDescription: &protobufs.AgentDescription{
+			IdentifyingAttributes: []*protobufs.KeyValue{{
+				Key:   "service.name",
+				Value: &protobufs.AnyValue{Value: &protobufs.AnyValue_StringValue{StringValue: "otelcol"}},
+			}},
+		},
+	}
+	rejection := s.gateUnknownAgent("agent-0000000001", msg, logger)
+	if rejection == nil {
+		t.Fatal("unknown agent was not rejected")
+	}
+	if rejection.ErrorResponse == nil {
+		t.Fatal("rejection carries no error response")
+	}
+	if rejection.RemoteConfig != nil {
+		t.Error("rejection must not offer a config")
+	}
+
+	agent, err := reg.Get("agent-0000000001")
+	if err != nil {
+		t.Fatalf("parked agent not registered: %v", err)
+	}
+	if !agent.Pending {
+		t.Error("parked agent is not pending")
+	}
+	if agent.Labels["service.name"] != "otelcol" {
+		t.Errorf("parked agent lost its labels: %v", agent.Labels)
+	}
+
+	// Second message while still pending: rejected again, not re-parked.
+	if s.gateUnknownAgent("agent-0000000001", msg, logger) == nil {
+		t.Error("pending agent was served")
+	}
+}
+
+func TestGateApprovedAgentPasses(t *testing.T) {
+	s, reg := newAllowlistTestServer(t)
+
+	if err := reg.Upsert(&registry.Agent{InstanceUID: "agent-0000000002"}); err != nil {
+		t.Fatal(err)
+	}
+	msg := &protobufs.AgentToServer{InstanceUid: []byte("agent-0000000002")}
+	if rejection := s.gateUnknownAgent("agent-0000000002", msg, s.logger); rejection != nil {
+		t.Fatalf("pre-registered agent was rejected: %v", rejection.ErrorResponse)
+	}
+}
+
+func TestGateApprovalReleasesAgent(t *testing.T) {
+	s, reg := newAllowlistTestServer(t)
+
+	msg := &protobufs.AgentToServer{InstanceUid: []byte("agent-0000000003")}
+	if s.gateUnknownAgent("agent-0000000003", msg, s.logger) == nil {
+		t.Fatal("unknown agent was not parked")
+	}
+	if err := reg.SetPending("agent-0000000003", false); err != nil {
+		t.Fatal(err)
+	}
+	if rejection := s.gateUnknownAgent("agent-0000000003", msg, s.logger); rejection != nil {
+		t.Fatalf("approved agent was rejected: %v", rejection.ErrorResponse)
+	}
+}
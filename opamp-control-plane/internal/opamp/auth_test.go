@@ -0,0 +1,65 @@
+package opamp
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func authRequest(t *testing.T, authorization string) *http.Request {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodGet, "/v1/opamp", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	return r
+}
+
+func TestStaticTokenAuthenticator(t *testing.T) {
+	auth := NewStaticTokenAuthenticator(map[string]string{"s3cret": "edge-fleet"})
+
+	identity, err := auth.Authenticate(authRequest(t, "Bearer s3cret"))
+	if err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if identity != "edge-fleet" {
+		t.Errorf("identity = %q, want edge-fleet", identity)
+	}
+
+	// Tenant-scoped bearer tokens match on the secret part.
+	if _, err := auth.Authenticate(authRequest(t, "Bearer acme:s3cret")); err != nil {
+		t.Errorf("tenant-scoped token rejected: %v", err)
+	}
+
+	if _, err := auth.Authenticate(authRequest(t, "")); err == nil {
+		t.Error("missing token accepted")
+	}
+	if _, err := auth.Authenticate(authRequest(t, "Bearer wrong")); err == nil {
+		t.Error("unknown token accepted")
+	}
+}
+
+func TestLoadTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	contents := "# comment\n\ntok1 fleet-a\ntok2\n"
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := LoadTokenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens["tok1"] != "fleet-a" {
+		t.Errorf("tok1 identity = %q, want fleet-a", tokens["tok1"])
+	}
+	if identity, ok := tokens["tok2"]; !ok || identity != "" {
+		t.Errorf("tok2 = %q, %v; want empty identity present", identity, ok)
+	}
+	if len(tokens) != 2 {
+		t.Errorf("len(tokens) = %d, want 2", len(tokens))
+	}
+}
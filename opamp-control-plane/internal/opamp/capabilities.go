@@ -0,0 +1,46 @@
+package opamp
+
+import "github.com/open-telemetry/opamp-go/protobufs"
+
+// agentCapabilityNames maps each agent capability bit to the name exposed in
+// the registry and API.
+var agentCapabilityNames = []struct {
+	bit  protobufs.AgentCapabilities
+	name string
+}{
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsStatus, "reports_status"},
+	{protobufs.AgentCapabilities_AgentCapabilities_AcceptsRemoteConfig, "accepts_remote_config"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsEffectiveConfig, "reports_effective_config"},
+	{protobufs.AgentCapabilities_AgentCapabilities_AcceptsPackages, "accepts_packages"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsPackageStatuses, "reports_package_statuses"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsOwnTraces, "reports_own_traces"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsOwnMetrics, "reports_own_metrics"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsOwnLogs, "reports_own_logs"},
+	{protobufs.AgentCapabilities_AgentCapabilities_AcceptsOpAMPConnectionSettings, "accepts_opamp_connection_settings"},
+	{protobufs.AgentCapabilities_AgentCapabilities_AcceptsOtherConnectionSettings, "accepts_other_connection_settings"},
+	{protobufs.AgentCapabilities_AgentCapabilities_AcceptsRestartCommand, "accepts_restart_command"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsHealth, "reports_health"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsRemoteConfig, "reports_remote_config"},
+	{protobufs.AgentCapabilities_AgentCapabilities_ReportsHeartbeat, "reports_heartbeat"},
+}
+
+// decodeAgentCapabilities expands a capability bitmask into sorted names.
+func decodeAgentCapabilities(caps uint64) []string {
+	var names []string
+	for _, c := range agentCapabilityNames {
+		if caps&uint64(c.bit) != 0 {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// agentCaps returns the last capability bitmask the agent reported on this
+// connection. Agents send capabilities on their first message and when they
+// change, so the tracked value stays current for the connection's lifetime.
+func (s *Server) agentCaps(uid string) uint64 {
+	if v, ok := s.caps.Load(uid); ok {
+		return v.(uint64)
+	}
+	return 0
+}
@@ -0,0 +1,22 @@
+package opamp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+)
+
+func TestDecodeAgentCapabilities(t *testing.T) {
+	caps := uint64(protobufs.AgentCapabilities_AgentCapabilities_ReportsStatus |
+		protobufs.AgentCapabilities_AgentCapabilities_AcceptsRemoteConfig |
+		protobufs.AgentCapabilities_AgentCapabilities_ReportsHealth)
+	got := decodeAgentCapabilities(caps)
+	want := []string{"reports_status", "accepts_remote_config", "reports_health"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeAgentCapabilities = %v, want %v", got, want)
+	}
+	if decodeAgentCapabilities(0) != nil {
+		t.Error("expected nil for empty bitmask")
+	}
+}
@@ -0,0 +1,41 @@
+package opamp
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// handleCertificateRequest signs the CSR carried in a ConnectionSettingsRequest
+// and folds the issued certificate into the reply's connection settings offer,
+// creating the offer if the reply does not already carry one.
+func (s *Server) handleCertificateRequest(response *protobufs.ServerToAgent, uid string, req *protobufs.ConnectionSettingsRequest, logger *slog.Logger) {
+	if req.Opamp == nil || req.Opamp.CertificateRequest == nil || len(req.Opamp.CertificateRequest.Csr) == 0 {
+		return
+	}
+	certPEM, notAfter, err := s.certSigner.Sign(req.Opamp.CertificateRequest.Csr)
+	if err != nil {
+		logger.Error("csr signing failed", "error", err)
+		return
+	}
+
+	if response.ConnectionSettings == nil {
+		response.ConnectionSettings = &protobufs.ConnectionSettingsOffers{}
+	}
+	if response.ConnectionSettings.Opamp == nil {
+		response.ConnectionSettings.Opamp = &protobufs.OpAMPConnectionSettings{}
+	}
+	response.ConnectionSettings.Opamp.Certificate = &protobufs.TLSCertificate{
+		Cert:   certPEM,
+		CaCert: s.certSigner.CertPEM(),
+	}
+	response.Capabilities |= uint64(protobufs.ServerCapabilities_ServerCapabilities_OffersConnectionSettings)
+
+	logger.Info("issued client certificate", "not_after", notAfter)
+	if err := s.registry.SetCertificate(uid, notAfter); err != nil && !errors.Is(err, registry.ErrNotFound) {
+		logger.Error("failed to record issued certificate", "error", err)
+	}
+}
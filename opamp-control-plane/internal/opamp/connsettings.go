@@ -0,0 +1,71 @@
+package opamp
+
+import (
+	"context"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+	"github.com/open-telemetry/opamp-go/server/types"
+)
+
+// ConnectionSettings describes an OpAMP endpoint offer used to migrate
+// agents to a different control plane address without touching their local
+// configuration.
+type ConnectionSettings struct {
+	// Endpoint is the new OpAMP server URL, e.g. "wss://new.example.com/v1/opamp".
+	Endpoint string `json:"endpoint"`
+	// Headers are sent by the agent on every connection to the new endpoint.
+	Headers map[string]string `json:"headers,omitempty"`
+	// CertificatePEM/PrivateKeyPEM/CAPEM optionally carry client TLS
+	// material for the new endpoint.
+	CertificatePEM string `json:"certificate_pem,omitempty"`
+	PrivateKeyPEM  string `json:"private_key_pem,omitempty"`
+	CAPEM          string `json:"ca_pem,omitempty"`
+}
+
+// Event type recorded when new connection settings are offered to an agent.
+const eventConnectionSettingsOffered = "connection_settings_offered"
+
+func (cs ConnectionSettings) toProto() *protobufs.OpAMPConnectionSettings {
+	offer := &protobufs.OpAMPConnectionSettings{
+		DestinationEndpoint: cs.Endpoint,
+		Headers:             telemetryHeaders(cs.Headers),
+	}
+	if cs.CertificatePEM != "" || cs.PrivateKeyPEM != "" || cs.CAPEM != "" {
+		offer.Certificate = &protobufs.TLSCertificate{
+			Cert:       []byte(cs.CertificatePEM),
+			PrivateKey: []byte(cs.PrivateKeyPEM),
+			CaCert:     []byte(cs.CAPEM),
+		}
+	}
+	return offer
+}
+
+// OfferConnectionSettings sends new OpAMP connection settings to the given
+// agents. The result maps each instance UID to an error message, empty on
+// success; disconnected agents fail without affecting the rest.
+func (s *Server) OfferConnectionSettings(ctx context.Context, uids []string, cs ConnectionSettings) map[string]string {
+	offer := cs.toProto()
+	results := make(map[string]string, len(uids))
+	for _, uid := range uids {
+		v, ok := s.conns.Load(uid)
+		if !ok {
+			results[uid] = "agent is not connected"
+			continue
+		}
+		err := v.(types.Connection).Send(ctx, &protobufs.ServerToAgent{
+			InstanceUid:        []byte(s.wireUID(uid)),
+			Capabilities:       uint64(protobufs.ServerCapabilities_ServerCapabilities_OffersConnectionSettings),
+			ConnectionSettings: &protobufs.ConnectionSettingsOffers{Opamp: offer},
+		})
+		if err != nil {
+			s.sendFailures.Add(1)
+			results[uid] = err.Error()
+			continue
+		}
+		results[uid] = ""
+		if err := s.registry.RecordEvent(uid, eventConnectionSettingsOffered, cs.Endpoint); err != nil {
+			s.logger.Error("failed to record connection settings offer", "agent", uid, "error", err)
+		}
+	}
+	return results
+}
@@ -0,0 +1,57 @@
+package opamp
+
+import (
+	"context"
+	"sort"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+)
+
+// CustomMessageHandler processes a CustomMessage received from an agent. It
+// may return a CustomMessage to deliver back to the agent in the same
+// response, or nil for no reply.
+type CustomMessageHandler func(ctx context.Context, instanceUID string, msg *protobufs.CustomMessage) *protobufs.CustomMessage
+
+// RegisterCustomMessageHandler registers a handler for one custom
+// capability. Incoming CustomMessage payloads are dispatched to the handler
+// registered for their capability, and the set of registered capabilities is
+// announced to agents, so extensions can exchange custom payloads without
+// touching the core message handling.
+func (s *Server) RegisterCustomMessageHandler(capability string, handler CustomMessageHandler) {
+	s.customMu.Lock()
+	defer s.customMu.Unlock()
+	if s.customHandlers == nil {
+		s.customHandlers = map[string]CustomMessageHandler{}
+	}
+	s.customHandlers[capability] = handler
+}
+
+// handleCustomMessage dispatches an incoming CustomMessage to its registered
+// handler, returning the handler's optional reply.
+func (s *Server) handleCustomMessage(ctx context.Context, uid string, msg *protobufs.CustomMessage) *protobufs.CustomMessage {
+	s.customMu.RLock()
+	handler := s.customHandlers[msg.Capability]
+	s.customMu.RUnlock()
+	if handler == nil {
+		s.logger.Debug("custom message for unhandled capability",
+			"agent", uid, "capability", msg.Capability, "type", msg.Type)
+		return nil
+	}
+	return handler(ctx, uid, msg)
+}
+
+// customCapabilities lists the registered capabilities for announcement to
+// agents, or nil when no handlers are registered.
+func (s *Server) customCapabilities() *protobufs.CustomCapabilities {
+	s.customMu.RLock()
+	defer s.customMu.RUnlock()
+	if len(s.customHandlers) == 0 {
+		return nil
+	}
+	capabilities := make([]string, 0, len(s.customHandlers))
+	for capability := range s.customHandlers {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	return &protobufs.CustomCapabilities{Capabilities: capabilities}
+}
@@ -0,0 +1,61 @@
+package opamp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+	"github.com/open-telemetry/opamp-go/server/types"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// drainConnections tells every connected agent the server is going away
+// before sockets are closed: agents are either redirected to a peer instance
+// (when one is configured) or told to back off and retry, and each is marked
+// disconnected in the registry. Sends are sequential, so a returned call
+// means no send is in flight; a cancelled ctx cuts the drain short.
+func (s *Server) drainConnections(ctx context.Context) {
+	drained := 0
+	s.conns.Range(func(key, value any) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		uid := key.(string)
+		conn := value.(types.Connection)
+		if err := conn.Send(ctx, s.drainMessage(uid)); err != nil {
+			s.logger.Debug("drain notification failed", "agent", uid, "error", err)
+		}
+		if err := s.registry.SetStatus(uid, registry.StatusDisconnected); err != nil && !errors.Is(err, registry.ErrNotFound) {
+			s.logger.Error("failed to mark agent disconnected", "agent", uid, "error", err)
+		}
+		drained++
+		return true
+	})
+	if drained > 0 {
+		s.logger.Info("drained agent connections", "count", drained)
+	}
+}
+
+func (s *Server) drainMessage(uid string) *protobufs.ServerToAgent {
+	msg := &protobufs.ServerToAgent{InstanceUid: []byte(s.wireUID(uid))}
+	if s.drainPeerURL != "" {
+		msg.Capabilities = uint64(protobufs.ServerCapabilities_ServerCapabilities_OffersConnectionSettings)
+		msg.ConnectionSettings = &protobufs.ConnectionSettingsOffers{
+			Opamp: &protobufs.OpAMPConnectionSettings{DestinationEndpoint: s.drainPeerURL},
+		}
+		return msg
+	}
+	msg.ErrorResponse = &protobufs.ServerErrorResponse{
+		Type: protobufs.ServerErrorResponseType_ServerErrorResponseType_Unavailable,
+		Details: &protobufs.ServerErrorResponse_RetryInfo{
+			RetryInfo: &protobufs.RetryInfo{RetryAfterNanoseconds: uint64(s.drainRetryAfter)},
+		},
+	}
+	return msg
+}
+
+// drainRetryAfterDefault is how long agents are told to back off when no
+// explicit retry-after is configured.
+const drainRetryAfterDefault = 30 * time.Second
@@ -0,0 +1,40 @@
+package opamp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+)
+
+func TestDrainMessageRetryAfter(t *testing.T) {
+	s := &Server{drainRetryAfter: 30 * time.Second}
+	msg := s.drainMessage("agent-1")
+	if msg.ErrorResponse == nil {
+		t.Fatal("no error response in drain message")
+	}
+	if msg.ErrorResponse.Type != protobufs.ServerErrorResponseType_ServerErrorResponseType_Unavailable {
+		t.Errorf("Type = %v, want Unavailable", msg.ErrorResponse.Type)
+	}
+	retry, ok := msg.ErrorResponse.Details.(*protobufs.ServerErrorResponse_RetryInfo)
+	if !ok {
+		t.Fatalf("Details = %T, want RetryInfo", msg.ErrorResponse.Details)
+	}
+	if got := retry.RetryInfo.RetryAfterNanoseconds; got != uint64(30*time.Second) {
+		t.Errorf("RetryAfterNanoseconds = %d, want 30s", got)
+	}
+}
+
+func TestDrainMessagePeerRedirect(t *testing.T) {
+	s := &Server{drainPeerURL: "wss://peer.example.com/v1/opamp"}
+	msg := s.drainMessage("agent-1")
+	if msg.ErrorResponse != nil {
+		t.Error("peer redirect should not carry an error response")
+	}
+	if msg.ConnectionSettings == nil || msg.ConnectionSettings.Opamp == nil {
+		t.Fatal("no connection settings offer in drain message")
+	}
+	if got := msg.ConnectionSettings.Opamp.DestinationEndpoint; got != "wss://peer.example.com/v1/opamp" {
+		t.Errorf("DestinationEndpoint = %q", got)
+	}
+}
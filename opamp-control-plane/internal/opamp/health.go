@@ -0,0 +1,35 @@
+package opamp
+
+import (
+	"time"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// healthFromProto converts the OpAMP ComponentHealth tree into the
+// registry's representation.
+func healthFromProto(h *protobufs.ComponentHealth) *registry.AgentHealth {
+	if h == nil {
+		return nil
+	}
+	out := &registry.AgentHealth{
+		Healthy:   h.Healthy,
+		LastError: h.LastError,
+		Status:    h.Status,
+	}
+	if h.StartTimeUnixNano > 0 {
+		out.StartTime = time.Unix(0, int64(h.StartTimeUnixNano)).UTC()
+	}
+	if h.StatusTimeUnixNano > 0 {
+		out.StatusTime = time.Unix(0, int64(h.StatusTimeUnixNano)).UTC()
+	}
+	if len(h.ComponentHealthMap) > 0 {
+		out.Components = make(map[string]*registry.AgentHealth, len(h.ComponentHealthMap))
+		for name, ch := range h.ComponentHealthMap {
+			out.Components[name] = healthFromProto(ch)
+		}
+	}
+	return out
+}
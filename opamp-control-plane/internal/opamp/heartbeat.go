@@ -0,0 +1,36 @@
+package opamp
+
+import (
+	"time"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/config"
+)
+
+// offerHeartbeat folds the desired heartbeat interval into the reply's OpAMP
+// connection settings offer, creating the offer if the reply does not already
+// carry one. No-op when the agent does not report heartbeats or cannot accept
+// OpAMP connection settings.
+func (s *Server) offerHeartbeat(response *protobufs.ServerToAgent, resolved *config.ResolvedConfig, agentCaps uint64) {
+	interval := resolved.HeartbeatInterval
+	if interval == 0 {
+		interval = s.heartbeatInterval
+	}
+	if interval <= 0 {
+		return
+	}
+	const needed = uint64(protobufs.AgentCapabilities_AgentCapabilities_ReportsHeartbeat) |
+		uint64(protobufs.AgentCapabilities_AgentCapabilities_AcceptsOpAMPConnectionSettings)
+	if agentCaps&needed != needed {
+		return
+	}
+	if response.ConnectionSettings == nil {
+		response.ConnectionSettings = &protobufs.ConnectionSettingsOffers{}
+	}
+	if response.ConnectionSettings.Opamp == nil {
+		response.ConnectionSettings.Opamp = &protobufs.OpAMPConnectionSettings{}
+	}
+	response.ConnectionSettings.Opamp.HeartbeatIntervalSeconds = uint64(interval / time.Second)
+	response.Capabilities |= uint64(protobufs.ServerCapabilities_ServerCapabilities_OffersConnectionSettings)
+}
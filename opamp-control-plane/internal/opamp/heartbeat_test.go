@@ -0,0 +1,56 @@
+package opamp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/config"
+)
+
+const heartbeatCaps = uint64(protobufs.AgentCapabilities_AgentCapabilities_ReportsHeartbeat) |
+	uint64(protobufs.AgentCapabilities_AgentCapabilities_AcceptsOpAMPConnectionSettings)
+
+func TestOfferHeartbeatUsesServerDefault(t *testing.T) {
+	s := &Server{heartbeatInterval: 30 * time.Second}
+	response := &protobufs.ServerToAgent{}
+	s.offerHeartbeat(response, &config.ResolvedConfig{}, heartbeatCaps)
+	if response.ConnectionSettings == nil || response.ConnectionSettings.Opamp == nil {
+		t.Fatal("no connection settings offer")
+	}
+	if got := response.ConnectionSettings.Opamp.HeartbeatIntervalSeconds; got != 30 {
+		t.Errorf("HeartbeatIntervalSeconds = %d, want 30", got)
+	}
+	if response.Capabilities&uint64(protobufs.ServerCapabilities_ServerCapabilities_OffersConnectionSettings) == 0 {
+		t.Error("OffersConnectionSettings capability not set")
+	}
+}
+
+func TestOfferHeartbeatSelectorOverride(t *testing.T) {
+	s := &Server{heartbeatInterval: 30 * time.Second}
+	response := &protobufs.ServerToAgent{}
+	s.offerHeartbeat(response, &config.ResolvedConfig{HeartbeatInterval: 2 * time.Minute}, heartbeatCaps)
+	if got := response.ConnectionSettings.Opamp.HeartbeatIntervalSeconds; got != 120 {
+		t.Errorf("HeartbeatIntervalSeconds = %d, want 120", got)
+	}
+}
+
+func TestOfferHeartbeatRequiresCapabilities(t *testing.T) {
+	s := &Server{heartbeatInterval: 30 * time.Second}
+	response := &protobufs.ServerToAgent{}
+	s.offerHeartbeat(response, &config.ResolvedConfig{},
+		uint64(protobufs.AgentCapabilities_AgentCapabilities_ReportsHeartbeat))
+	if response.ConnectionSettings != nil {
+		t.Error("offered heartbeat to agent without AcceptsOpAMPConnectionSettings")
+	}
+}
+
+func TestOfferHeartbeatDisabled(t *testing.T) {
+	s := &Server{}
+	response := &protobufs.ServerToAgent{}
+	s.offerHeartbeat(response, &config.ResolvedConfig{}, heartbeatCaps)
+	if response.ConnectionSettings != nil {
+		t.Error("offered heartbeat with no interval configured")
+	}
+}
@@ -0,0 +1,20 @@
+package opamp
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// newInstanceUID generates a fresh instance UID in UUID text form, used when
+// reassigning a colliding agent.
+func newInstanceUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand does not fail on supported platforms.
+		panic(err)
+	}
+	// RFC 4122 version 4, variant 10.
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
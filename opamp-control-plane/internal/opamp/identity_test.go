@@ -0,0 +1,21 @@
+package opamp
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewInstanceUID(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		uid := newInstanceUID()
+		if !uuidPattern.MatchString(uid) {
+			t.Fatalf("uid %q is not a v4 UUID", uid)
+		}
+		if seen[uid] {
+			t.Fatalf("uid %q generated twice", uid)
+		}
+		seen[uid] = true
+	}
+}
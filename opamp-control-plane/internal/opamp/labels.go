@@ -0,0 +1,138 @@
+package opamp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LabelNormalization cleans up reported attribute and label keys before the
+// mapping and rules run, so selectors see consistent names across collector
+// versions and distros.
+type LabelNormalization struct {
+	// Rename maps reported keys to replacements; an empty replacement
+	// drops the key.
+	Rename map[string]string
+	// StripPrefixes are key prefixes removed from keys not covered by
+	// Rename, e.g. "service." turns "service.version" into "version".
+	StripPrefixes []string
+	// LowercaseValues lowercases every value.
+	LowercaseValues bool
+}
+
+// normalizeLabels returns m with the normalization applied. Keys the agent
+// reported under their final name win over keys renamed onto them; beyond
+// that, collisions resolve in key order for determinism. The input map is
+// not modified.
+func normalizeLabels(n LabelNormalization, m map[string]string) map[string]string {
+	if len(n.Rename) == 0 && len(n.StripPrefixes) == 0 && !n.LowercaseValues {
+		return m
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make(map[string]string, len(m))
+	for _, k := range keys {
+		key := k
+		if renamed, ok := n.Rename[k]; ok {
+			if renamed == "" {
+				continue
+			}
+			key = renamed
+		} else {
+			for _, prefix := range n.StripPrefixes {
+				if stripped, ok := strings.CutPrefix(key, prefix); ok && stripped != "" {
+					key = stripped
+					break
+				}
+			}
+		}
+		v := m[k]
+		if n.LowercaseValues {
+			v = strings.ToLower(v)
+		}
+		if _, exists := out[key]; exists && key != k {
+			continue
+		}
+		out[key] = v
+	}
+	return out
+}
+
+// DefaultLabelMapping maps well-known OpenTelemetry resource attributes to
+// the canonical label keys used by selectors. The mapping is applied to both
+// identifying and non-identifying attributes of the agent description, so
+// fleets following the semantic conventions get usable selector labels
+// without any per-fleet extraction glue.
+func DefaultLabelMapping() map[string]string {
+	return map[string]string{
+		"service.name":       "service",
+		"k8s.namespace.name": "namespace",
+		"host.name":          "host",
+		"cloud.region":       "region",
+	}
+}
+
+// LabelRule derives one label from an agent description attribute: when the
+// attribute's value matches the pattern, the label is set. The value template
+// may reference capture groups as $1, $2, ...; empty means the whole
+// attribute value.
+type LabelRule struct {
+	Attr  string
+	Match *regexp.Regexp
+	Label string
+	Value string
+}
+
+// applyLabelRules evaluates the rules in order against the agent's labels
+// and attributes. As with the mapping, labels the agent reported directly
+// win over derived ones, which also makes an earlier rule win over a later
+// one targeting the same label.
+func applyLabelRules(rules []LabelRule, labels, attrs map[string]string) {
+	for _, rule := range rules {
+		if _, exists := labels[rule.Label]; exists {
+			continue
+		}
+		value, ok := labels[rule.Attr]
+		if !ok {
+			value, ok = attrs[rule.Attr]
+		}
+		if !ok {
+			continue
+		}
+		idx := rule.Match.FindStringSubmatchIndex(value)
+		if idx == nil {
+			continue
+		}
+		derived := value
+		if rule.Value != "" {
+			derived = string(rule.Match.ExpandString(nil, rule.Value, value, idx))
+		}
+		if derived != "" {
+			labels[rule.Label] = derived
+		}
+	}
+}
+
+// applyLabelMapping copies attribute values into labels under their canonical
+// keys per the mapping. Labels the agent reported directly win over derived
+// ones, and mapping entries with an empty target are disabled.
+func applyLabelMapping(mapping, labels, attrs map[string]string) {
+	for attrKey, labelKey := range mapping {
+		if labelKey == "" {
+			continue
+		}
+		if _, exists := labels[labelKey]; exists {
+			continue
+		}
+		if v, ok := labels[attrKey]; ok && attrKey != labelKey {
+			labels[labelKey] = v
+			continue
+		}
+		if v, ok := attrs[attrKey]; ok {
+			labels[labelKey] = v
+		}
+	}
+}
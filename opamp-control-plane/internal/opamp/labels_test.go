@@ -0,0 +1,109 @@
+package opamp
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestApplyLabelMapping(t *testing.T) {
+	labels := map[string]string{
+		"service.name": "checkout",
+		"region":       "explicit-region",
+	}
+	attrs := map[string]string{
+		"host.name":    "web-1",
+		"cloud.region": "eu-west-1",
+	}
+
+	applyLabelMapping(DefaultLabelMapping(), labels, attrs)
+
+	if labels["service"] != "checkout" {
+		t.Errorf("service = %q, want checkout (from identifying attribute)", labels["service"])
+	}
+	if labels["host"] != "web-1" {
+		t.Errorf("host = %q, want web-1 (from non-identifying attribute)", labels["host"])
+	}
+	if labels["region"] != "explicit-region" {
+		t.Errorf("region = %q, want explicit-region (reported label must win)", labels["region"])
+	}
+}
+
+func TestApplyLabelMappingDisabledEntry(t *testing.T) {
+	labels := map[string]string{}
+	attrs := map[string]string{"host.name": "web-1"}
+	applyLabelMapping(map[string]string{"host.name": ""}, labels, attrs)
+	if len(labels) != 0 {
+		t.Errorf("labels = %v, want empty for disabled mapping entry", labels)
+	}
+}
+
+func TestApplyLabelRules(t *testing.T) {
+	rules := []LabelRule{
+		{Attr: "host.name", Match: regexp.MustCompile(`^web-`), Label: "role", Value: "web"},
+		{Attr: "cloud.availability_zone", Match: regexp.MustCompile(`^([a-z]+-[a-z]+-\d+)`), Label: "region", Value: "$1"},
+		{Attr: "host.name", Match: regexp.MustCompile(`^db-`), Label: "role", Value: "db"},
+	}
+	labels := map[string]string{}
+	attrs := map[string]string{
+		"host.name":               "web-42",
+		"cloud.availability_zone": "eu-west-1a",
+	}
+
+	applyLabelRules(rules, labels, attrs)
+
+	if labels["role"] != "web" {
+		t.Errorf("role = %q, want web", labels["role"])
+	}
+	if labels["region"] != "eu-west-1" {
+		t.Errorf("region = %q, want eu-west-1 (capture group)", labels["region"])
+	}
+}
+
+func TestApplyLabelRulesReportedLabelWins(t *testing.T) {
+	rules := []LabelRule{
+		{Attr: "host.name", Match: regexp.MustCompile(`^web-`), Label: "role", Value: "web"},
+	}
+	labels := map[string]string{"role": "canary"}
+	applyLabelRules(rules, labels, map[string]string{"host.name": "web-1"})
+	if labels["role"] != "canary" {
+		t.Errorf("role = %q, want canary (reported label must win)", labels["role"])
+	}
+}
+
+func TestNormalizeLabels(t *testing.T) {
+	n := LabelNormalization{
+		Rename:          map[string]string{"svc": "service", "internal.debug": ""},
+		StripPrefixes:   []string{"service."},
+		LowercaseValues: true,
+	}
+	got := normalizeLabels(n, map[string]string{
+		"svc":             "Checkout",
+		"service.version": "0.98.0",
+		"internal.debug":  "true",
+		"env":             "PROD",
+	})
+	want := map[string]string{
+		"service": "checkout",
+		"version": "0.98.0",
+		"env":     "prod",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("normalized = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestNormalizeLabelsReportedKeyWins(t *testing.T) {
+	n := LabelNormalization{Rename: map[string]string{"svc": "service"}}
+	got := normalizeLabels(n, map[string]string{
+		"svc":     "renamed",
+		"service": "reported",
+	})
+	if got["service"] != "reported" {
+		t.Errorf("service = %q, want reported (key reported under its final name wins)", got["service"])
+	}
+}
@@ -0,0 +1,53 @@
+package opamp
+
+import "sync"
+
+// connLimiter enforces the global and per-IP connection caps. Counts are
+// taken when a connection is fully established and released when it closes;
+// admission checks happen earlier, at the HTTP request, so a burst of
+// connections mid-upgrade can briefly overshoot the caps by a small margin.
+type connLimiter struct {
+	max      int
+	maxPerIP int
+
+	mu    sync.Mutex
+	total int
+	perIP map[string]int
+}
+
+func newConnLimiter(max, maxPerIP int) *connLimiter {
+	return &connLimiter{max: max, maxPerIP: maxPerIP, perIP: map[string]int{}}
+}
+
+// admitted reports whether a new connection from ip is within the caps.
+func (l *connLimiter) admitted(ip string) (ok bool, reason string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.max > 0 && l.total >= l.max {
+		return false, "connection limit reached"
+	}
+	if l.maxPerIP > 0 && l.perIP[ip] >= l.maxPerIP {
+		return false, "per-ip connection limit reached"
+	}
+	return true, ""
+}
+
+// connected counts an established connection from ip.
+func (l *connLimiter) connected(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total++
+	l.perIP[ip]++
+}
+
+// closed releases the count taken by connected.
+func (l *connLimiter) closed(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total--
+	if l.perIP[ip] <= 1 {
+		delete(l.perIP, ip)
+	} else {
+		l.perIP[ip]--
+	}
+}
@@ -0,0 +1,41 @@
+package opamp
+
+import "testing"
+
+func TestConnLimiterGlobalCap(t *testing.T) {
+	l := newConnLimiter(2, 0)
+	l.connected("10.0.0.1")
+	l.connected("10.0.0.2")
+	if ok, _ := l.admitted("10.0.0.3"); ok {
+		t.Error("admitted connection over the global cap")
+	}
+	l.closed("10.0.0.1")
+	if ok, _ := l.admitted("10.0.0.3"); !ok {
+		t.Error("rejected connection after a slot freed up")
+	}
+}
+
+func TestConnLimiterPerIPCap(t *testing.T) {
+	l := newConnLimiter(0, 1)
+	l.connected("10.0.0.1")
+	if ok, reason := l.admitted("10.0.0.1"); ok || reason != "per-ip connection limit reached" {
+		t.Errorf("admitted = %v (%q), want per-ip rejection", ok, reason)
+	}
+	if ok, _ := l.admitted("10.0.0.2"); !ok {
+		t.Error("rejected a different IP under the per-ip cap")
+	}
+	l.closed("10.0.0.1")
+	if ok, _ := l.admitted("10.0.0.1"); !ok {
+		t.Error("rejected after the IP's connection closed")
+	}
+}
+
+func TestConnLimiterUnlimited(t *testing.T) {
+	l := newConnLimiter(0, 0)
+	for i := 0; i < 100; i++ {
+		l.connected("10.0.0.1")
+	}
+	if ok, _ := l.admitted("10.0.0.1"); !ok {
+		t.Error("unlimited limiter rejected a connection")
+	}
+}
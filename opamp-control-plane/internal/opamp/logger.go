@@ -0,0 +1,20 @@
+package opamp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// opampLogger adapts slog to the opamp-go Logger interface.
+type opampLogger struct {
+	logger *slog.Logger
+}
+
+func (l *opampLogger) Debugf(_ context.Context, format string, v ...interface{}) {
+	l.logger.Debug(fmt.Sprintf(format, v...))
+}
+
+func (l *opampLogger) Errorf(_ context.Context, format string, v ...interface{}) {
+	l.logger.Error(fmt.Sprintf(format, v...))
+}
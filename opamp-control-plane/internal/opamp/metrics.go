@@ -0,0 +1,81 @@
+package opamp
+
+import (
+	"sync/atomic"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+)
+
+// Metrics is a point-in-time snapshot of the OpAMP server's counters.
+type Metrics struct {
+	// ActiveConnections is the number of currently open agent connections.
+	ActiveConnections int64 `json:"active_connections"`
+	// MessagesReceived counts received AgentToServer messages, total and
+	// broken down by which parts of the message were populated.
+	MessagesReceived map[string]int64 `json:"messages_received"`
+	// ConfigPushesSent counts server-initiated config pushes that reached
+	// the agent.
+	ConfigPushesSent int64 `json:"config_pushes_sent"`
+	// SendFailures counts server-initiated sends that failed.
+	SendFailures int64 `json:"send_failures"`
+	// AuthRejections counts connections turned away by the authenticator.
+	AuthRejections int64 `json:"auth_rejections"`
+}
+
+// messageCounters counts received messages by populated part. A single
+// message increments every counter whose part it carries.
+type messageCounters struct {
+	total                     atomic.Int64
+	agentDescription          atomic.Int64
+	remoteConfigStatus        atomic.Int64
+	health                    atomic.Int64
+	packageStatuses           atomic.Int64
+	connectionSettingsRequest atomic.Int64
+	customMessage             atomic.Int64
+}
+
+func (c *messageCounters) count(msg *protobufs.AgentToServer) {
+	c.total.Add(1)
+	if msg.AgentDescription != nil {
+		c.agentDescription.Add(1)
+	}
+	if msg.RemoteConfigStatus != nil {
+		c.remoteConfigStatus.Add(1)
+	}
+	if msg.Health != nil {
+		c.health.Add(1)
+	}
+	if msg.PackageStatuses != nil {
+		c.packageStatuses.Add(1)
+	}
+	if msg.ConnectionSettingsRequest != nil {
+		c.connectionSettingsRequest.Add(1)
+	}
+	if msg.CustomMessage != nil {
+		c.customMessage.Add(1)
+	}
+}
+
+func (c *messageCounters) snapshot() map[string]int64 {
+	return map[string]int64{
+		"total":                       c.total.Load(),
+		"agent_description":           c.agentDescription.Load(),
+		"remote_config_status":        c.remoteConfigStatus.Load(),
+		"health":                      c.health.Load(),
+		"package_statuses":            c.packageStatuses.Load(),
+		"connection_settings_request": c.connectionSettingsRequest.Load(),
+		"custom_message":              c.customMessage.Load(),
+	}
+}
+
+// Metrics returns a snapshot of the server's connection and traffic
+// counters.
+func (s *Server) Metrics() Metrics {
+	return Metrics{
+		ActiveConnections: s.activeConns.Load(),
+		MessagesReceived:  s.msgCounters.snapshot(),
+		ConfigPushesSent:  s.pushesSent.Load(),
+		SendFailures:      s.sendFailures.Load(),
+		AuthRejections:    s.authRejects.Load(),
+	}
+}
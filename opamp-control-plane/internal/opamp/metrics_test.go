@@ -0,0 +1,45 @@
+package opamp
+
+import (
+	"testing"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+)
+
+func TestMessageCountersByPart(t *testing.T) {
+	s := &Server{}
+	s.msgCounters.count(&protobufs.AgentToServer{
+		AgentDescription: &protobufs.AgentDescription{},
+		Health:           &protobufs.ComponentHealth{},
+	})
+	s.msgCounters.count(&protobufs.AgentToServer{
+		RemoteConfigStatus: &protobufs.RemoteConfigStatus{},
+	})
+
+	m := s.Metrics()
+	want := map[string]int64{
+		"total":                2,
+		"agent_description":    1,
+		"health":               1,
+		"remote_config_status": 1,
+		"custom_message":       0,
+	}
+	for name, count := range want {
+		if m.MessagesReceived[name] != count {
+			t.Errorf("MessagesReceived[%s] = %d, want %d", name, m.MessagesReceived[name], count)
+		}
+	}
+}
+
+func TestMetricsSnapshot(t *testing.T) {
+	s := &Server{}
+	s.activeConns.Add(3)
+	s.pushesSent.Add(5)
+	s.sendFailures.Add(1)
+	s.authRejects.Add(2)
+
+	m := s.Metrics()
+	if m.ActiveConnections != 3 || m.ConfigPushesSent != 5 || m.SendFailures != 1 || m.AuthRejections != 2 {
+		t.Errorf("Metrics() = %+v, want counts 3/5/1/2", m)
+	}
+}
@@ -0,0 +1,63 @@
+package opamp
+
+import (
+	"sort"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/config"
+)
+
+// ownTelemetryOffers builds the connection settings offers that point an
+// agent's own telemetry at the destination configured on its selector. Each
+// signal is only offered when the agent reported the corresponding
+// ReportsOwn* capability, per the OpAMP spec.
+func ownTelemetryOffers(ot *config.OwnTelemetry, capabilities uint64) *protobufs.ConnectionSettingsOffers {
+	if ot == nil {
+		return nil
+	}
+	headers := telemetryHeaders(ot.Headers)
+	offers := &protobufs.ConnectionSettingsOffers{}
+	any := false
+	if ot.MetricsEndpoint != "" && capabilities&uint64(protobufs.AgentCapabilities_AgentCapabilities_ReportsOwnMetrics) != 0 {
+		offers.OwnMetrics = &protobufs.TelemetryConnectionSettings{
+			DestinationEndpoint: ot.MetricsEndpoint,
+			Headers:             headers,
+		}
+		any = true
+	}
+	if ot.TracesEndpoint != "" && capabilities&uint64(protobufs.AgentCapabilities_AgentCapabilities_ReportsOwnTraces) != 0 {
+		offers.OwnTraces = &protobufs.TelemetryConnectionSettings{
+			DestinationEndpoint: ot.TracesEndpoint,
+			Headers:             headers,
+		}
+		any = true
+	}
+	if ot.LogsEndpoint != "" && capabilities&uint64(protobufs.AgentCapabilities_AgentCapabilities_ReportsOwnLogs) != 0 {
+		offers.OwnLogs = &protobufs.TelemetryConnectionSettings{
+			DestinationEndpoint: ot.LogsEndpoint,
+			Headers:             headers,
+		}
+		any = true
+	}
+	if !any {
+		return nil
+	}
+	return offers
+}
+
+func telemetryHeaders(m map[string]string) *protobufs.Headers {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	headers := &protobufs.Headers{}
+	for _, k := range keys {
+		headers.Headers = append(headers.Headers, &protobufs.Header{Key: k, Value: m[k]})
+	}
+	return headers
+}
@@ -0,0 +1,38 @@
+package opamp
+
+import (
+	"testing"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/config"
+)
+
+func TestOwnTelemetryOffersCapabilityGating(t *testing.T) {
+	ot := &config.OwnTelemetry{
+		MetricsEndpoint: "https://otlp.example.com/v1/metrics",
+		TracesEndpoint:  "https://otlp.example.com/v1/traces",
+	}
+
+	caps := uint64(protobufs.AgentCapabilities_AgentCapabilities_ReportsOwnMetrics)
+	offers := ownTelemetryOffers(ot, caps)
+	if offers == nil {
+		t.Fatal("offers = nil, want own metrics offer")
+	}
+	if offers.OwnMetrics == nil || offers.OwnMetrics.DestinationEndpoint != ot.MetricsEndpoint {
+		t.Errorf("OwnMetrics = %v, want endpoint %s", offers.OwnMetrics, ot.MetricsEndpoint)
+	}
+	if offers.OwnTraces != nil {
+		t.Error("OwnTraces offered without ReportsOwnTraces capability")
+	}
+}
+
+func TestOwnTelemetryOffersNilWhenUnsupported(t *testing.T) {
+	ot := &config.OwnTelemetry{MetricsEndpoint: "https://otlp.example.com"}
+	if offers := ownTelemetryOffers(ot, 0); offers != nil {
+		t.Errorf("offers = %v, want nil for agent without ReportsOwn* capabilities", offers)
+	}
+	if offers := ownTelemetryOffers(nil, ^uint64(0)); offers != nil {
+		t.Errorf("offers = %v, want nil without own_telemetry config", offers)
+	}
+}
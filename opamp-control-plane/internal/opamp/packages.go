@@ -0,0 +1,83 @@
+package opamp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// packageStatusName maps the OpAMP status enum onto the registry's string
+// values.
+func packageStatusName(st protobufs.PackageStatusEnum) string {
+	switch st {
+	case protobufs.PackageStatusEnum_PackageStatusEnum_InstallPending:
+		return registry.PackageInstallPending
+	case protobufs.PackageStatusEnum_PackageStatusEnum_Installing:
+		return registry.PackageInstalling
+	case protobufs.PackageStatusEnum_PackageStatusEnum_InstallFailed:
+		return registry.PackageInstallFailed
+	default:
+		return registry.PackageInstalled
+	}
+}
+
+// recordPackageStatuses persists the agent-reported package state.
+func (s *Server) recordPackageStatuses(uid string, ps *protobufs.PackageStatuses, logger *slog.Logger) {
+	statuses := make([]registry.PackageStatus, 0, len(ps.Packages))
+	for name, p := range ps.Packages {
+		statuses = append(statuses, registry.PackageStatus{
+			Package: name,
+			Version: p.AgentHasVersion,
+			Status:  packageStatusName(p.Status),
+			Error:   p.ErrorMessage,
+		})
+	}
+	if err := s.registry.SetPackageStatuses(uid, statuses); err != nil {
+		logger.Error("failed to record package statuses", "error", err)
+	}
+}
+
+// packagesAvailable builds the PackagesAvailable offer for an agent from its
+// registry assignments, or nil when nothing is assigned.
+func (s *Server) packagesAvailable(uid string, logger *slog.Logger) *protobufs.PackagesAvailable {
+	assignments, err := s.registry.PackageAssignments(uid)
+	if err != nil {
+		logger.Error("failed to load package assignments", "error", err)
+		return nil
+	}
+	if len(assignments) == 0 {
+		return nil
+	}
+
+	offer := &protobufs.PackagesAvailable{Packages: map[string]*protobufs.PackageAvailable{}}
+	allHash := sha256.New()
+	for _, a := range assignments {
+		artifact, err := s.packages.Lookup(a.Package, a.Version)
+		if err != nil {
+			logger.Error("assigned package missing from artifact store",
+				"package", a.Package, "version", a.Version, "error", err)
+			continue
+		}
+		contentHash, _ := hex.DecodeString(artifact.Hash)
+		offer.Packages[a.Package] = &protobufs.PackageAvailable{
+			Type:    protobufs.PackageType_PackageType_TopLevel,
+			Version: a.Version,
+			File: &protobufs.DownloadableFile{
+				DownloadUrl: s.packages.DownloadURL(a.Package, a.Version),
+				ContentHash: contentHash,
+			},
+			Hash: contentHash,
+		}
+		fmt.Fprintf(allHash, "%s@%s:%s\n", a.Package, a.Version, artifact.Hash)
+	}
+	if len(offer.Packages) == 0 {
+		return nil
+	}
+	offer.AllPackagesHash = allHash.Sum(nil)
+	return offer
+}
@@ -0,0 +1,70 @@
+package opamp
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// PushScheduler sits between sync triggers and PushConfigToAll. Rapid git
+// syncs previously caused a full push storm per sync; the scheduler
+// coalesces triggers arriving within the debounce window into a single
+// fleet-wide push.
+type PushScheduler struct {
+	push     func(context.Context)
+	debounce time.Duration
+	trigger  chan struct{}
+	logger   *slog.Logger
+}
+
+// NewPushScheduler returns a scheduler pushing through server. A zero
+// debounce defaults to 2 seconds.
+func NewPushScheduler(server *Server, debounce time.Duration, logger *slog.Logger) *PushScheduler {
+	if debounce <= 0 {
+		debounce = 2 * time.Second
+	}
+	return &PushScheduler{
+		push:     func(ctx context.Context) { server.PushConfigToAll(ctx) },
+		debounce: debounce,
+		// Capacity 1: a trigger while one is pending coalesces into it.
+		trigger: make(chan struct{}, 1),
+		logger:  logger,
+	}
+}
+
+// Trigger requests a fleet-wide push. Triggers arriving while one is
+// pending or within the debounce window coalesce into a single push.
+func (p *PushScheduler) Trigger() {
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run services triggers until ctx is done.
+func (p *PushScheduler) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.trigger:
+		}
+		// Debounce: let a burst of triggers (e.g. several webhook
+		// deliveries for one push) settle before pushing once.
+		timer := time.NewTimer(p.debounce)
+	settle:
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-p.trigger:
+				// Absorbed into the pending push.
+			case <-timer.C:
+				break settle
+			}
+		}
+		p.logger.Debug("debounced push")
+		p.push(ctx)
+	}
+}
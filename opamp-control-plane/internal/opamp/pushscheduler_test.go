@@ -0,0 +1,60 @@
+package opamp
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func newTestScheduler(debounce time.Duration, push func(context.Context)) *PushScheduler {
+	return &PushScheduler{
+		push:     push,
+		debounce: debounce,
+		trigger:  make(chan struct{}, 1),
+		logger:   slog.Default(),
+	}
+}
+
+func TestPushSchedulerCoalescesTriggers(t *testing.T) {
+	pushes := make(chan struct{}, 10)
+	sched := newTestScheduler(20*time.Millisecond, func(context.Context) {
+		pushes <- struct{}{}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sched.Run(ctx)
+
+	for i := 0; i < 5; i++ {
+		sched.Trigger()
+	}
+	select {
+	case <-pushes:
+	case <-time.After(time.Second):
+		t.Fatal("no push after triggers")
+	}
+	select {
+	case <-pushes:
+		t.Fatal("burst of triggers caused more than one push")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPushSchedulerPushesAgainAfterDebounce(t *testing.T) {
+	pushes := make(chan struct{}, 10)
+	sched := newTestScheduler(10*time.Millisecond, func(context.Context) {
+		pushes <- struct{}{}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sched.Run(ctx)
+
+	for i := 0; i < 2; i++ {
+		sched.Trigger()
+		select {
+		case <-pushes:
+		case <-time.After(time.Second):
+			t.Fatalf("push %d did not happen", i+1)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package opamp
+
+import (
+	"context"
+	"time"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// RunReconciler periodically re-offers the current config to connected
+// agents whose recorded hash differs from the resolved one — for example
+// agents restored from an old image whose git-triggered push they missed.
+// At most batchSize agents are pushed per tick so a large drifted fleet is
+// converged gradually. Blocks until ctx is done.
+func (s *Server) RunReconciler(ctx context.Context, interval time.Duration, batchSize int) {
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reconcileOnce(ctx, batchSize)
+		}
+	}
+}
+
+func (s *Server) reconcileOnce(ctx context.Context, batchSize int) {
+	pushed := 0
+	s.conns.Range(func(key, _ any) bool {
+		uid := key.(string)
+		agent, err := s.registry.Get(uid)
+		if err != nil {
+			return true
+		}
+		if agent.Unmanaged {
+			return true
+		}
+		resolved, err := s.resolver.Resolve(agent)
+		if err != nil || resolved.Unmanaged {
+			return true
+		}
+		if resolved.Hash == agent.ConfigHash && agent.ConfigState != registry.ConfigStateFailed {
+			return true
+		}
+		if err := s.PushConfigToAgent(ctx, uid); err != nil {
+			s.logger.Warn("reconcile push failed", "agent", uid, "error", err)
+			return true
+		}
+		pushed++
+		return pushed < batchSize
+	})
+	if pushed > 0 {
+		s.logger.Info("reconcile pushed drifted agents", "count", pushed)
+	}
+}
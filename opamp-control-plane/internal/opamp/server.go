@@ -0,0 +1,753 @@
+// Package opamp implements the agent-facing side of the control plane: an
+// OpAMP server that registers connecting agents, keeps the registry current,
+// and offers each agent its resolved config.
+package opamp
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/open-telemetry/opamp-go/protobufs"
+	"github.com/open-telemetry/opamp-go/server"
+	"github.com/open-telemetry/opamp-go/server/types"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/certs"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/config"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/packages"
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// Server accepts OpAMP connections from agents and speaks the config
+// delivery half of the protocol.
+// Options tunes optional Server behavior.
+type Options struct {
+	// LabelMapping maps resource attribute keys to canonical label keys
+	// applied to every agent description. Nil means DefaultLabelMapping().
+	LabelMapping map[string]string
+	// LabelRules derive further labels from attribute values, applied
+	// after LabelMapping and before selector matching.
+	LabelRules []LabelRule
+	// LabelNormalization cleans up reported keys and values before the
+	// mapping and rules run.
+	LabelNormalization LabelNormalization
+	// Packages is the artifact store behind package offers. Nil disables
+	// the packages flow.
+	Packages *packages.Store
+	// CertSigner signs agent CSRs in the certificate provisioning flow.
+	// Nil disables the flow.
+	CertSigner *certs.CA
+	// Authenticator verifies connecting agents. Nil accepts every
+	// connection.
+	Authenticator Authenticator
+	// MinPushInterval is the minimum time between config pushes to one
+	// agent; pushes inside the window are dropped. Zero disables the
+	// limit.
+	MinPushInterval time.Duration
+	// HeartbeatInterval is the fleet-wide agent heartbeat interval offered
+	// to agents that report heartbeats. Zero leaves agent defaults alone;
+	// selectors can override per fleet segment.
+	HeartbeatInterval time.Duration
+	// DrainPeerURL, when set, is the OpAMP endpoint agents are redirected
+	// to during shutdown. Empty means agents are told to back off and
+	// retry instead.
+	DrainPeerURL string
+	// DrainRetryAfter is how long agents are told to back off during
+	// shutdown when no drain peer is configured. Zero means 30s.
+	DrainRetryAfter time.Duration
+	// MaxConnections caps concurrent agent connections; further connects
+	// are rejected with 503. Zero means unlimited.
+	MaxConnections int
+	// MaxConnectionsPerIP caps concurrent connections from one client IP;
+	// further connects are rejected with 429. Zero means unlimited.
+	MaxConnectionsPerIP int
+	// Allowlist, when true, only serves agents with a pre-registered,
+	// approved registry record; unknown agents are parked in a pending
+	// state instead of silently registered.
+	Allowlist bool
+}
+
+type Server struct {
+	registry          *registry.Registry
+	resolver          *config.Resolver
+	labelMapping      map[string]string
+	labelRules        []LabelRule
+	labelNormalize    LabelNormalization
+	packages          *packages.Store
+	certSigner        *certs.CA
+	authenticator     Authenticator
+	minPushInterval   time.Duration
+	heartbeatInterval time.Duration
+	drainPeerURL      string
+	drainRetryAfter   time.Duration
+	limiter           *connLimiter
+	allowlist         bool
+	logger            *slog.Logger
+
+	// authRejects counts connections turned away by the authenticator.
+	authRejects atomic.Int64
+	// Traffic counters surfaced by Metrics.
+	activeConns  atomic.Int64
+	pushesSent   atomic.Int64
+	sendFailures atomic.Int64
+	msgCounters  messageCounters
+
+	opamp server.OpAMPServer
+
+	// conns maps instance UID -> types.Connection for currently connected
+	// agents, and uids maps the reverse for cleanup on close.
+	conns sync.Map
+	uids  sync.Map
+	// rawUIDs maps normalized instance UID -> the UID bytes the agent sent
+	// on the wire, so server-initiated messages echo the agent's own form.
+	rawUIDs sync.Map
+	// caps maps instance UID -> the last reported capability bitmask, used
+	// to gate offers on what the agent actually accepts.
+	caps sync.Map
+	// lastPush maps instance UID -> time.Time of the last config push,
+	// enforcing minPushInterval.
+	lastPush sync.Map
+	// shutdowns remembers agents that announced a clean shutdown via
+	// AgentDisconnect, so the subsequent socket close is not recorded as an
+	// unexpected drop.
+	shutdowns sync.Map
+	// tenants maps types.Connection -> connInfo extracted at connect time,
+	// so agent records are scoped to the right tenant config tree and
+	// attributed to the authenticated identity.
+	tenants sync.Map
+
+	// customHandlers dispatches CustomMessage payloads by capability; see
+	// RegisterCustomMessageHandler.
+	customMu       sync.RWMutex
+	customHandlers map[string]CustomMessageHandler
+}
+
+// TenantHeader names the HTTP header agents use to identify their tenant on
+// connect. A tenant can also be carried as the "<tenant>:<secret>" prefix of
+// a bearer token.
+const TenantHeader = "X-Opamp-Tenant"
+
+// New returns a Server. Call Start to begin accepting connections.
+func New(reg *registry.Registry, resolver *config.Resolver, opts Options, logger *slog.Logger) *Server {
+	if opts.LabelMapping == nil {
+		opts.LabelMapping = DefaultLabelMapping()
+	}
+	if opts.DrainRetryAfter == 0 {
+		opts.DrainRetryAfter = drainRetryAfterDefault
+	}
+	return &Server{
+		registry:          reg,
+		resolver:          resolver,
+		labelMapping:      opts.LabelMapping,
+		labelRules:        opts.LabelRules,
+		labelNormalize:    opts.LabelNormalization,
+		packages:          opts.Packages,
+		certSigner:        opts.CertSigner,
+		authenticator:     opts.Authenticator,
+		minPushInterval:   opts.MinPushInterval,
+		heartbeatInterval: opts.HeartbeatInterval,
+		drainPeerURL:      opts.DrainPeerURL,
+		drainRetryAfter:   opts.DrainRetryAfter,
+		limiter:           newConnLimiter(opts.MaxConnections, opts.MaxConnectionsPerIP),
+		allowlist:         opts.Allowlist,
+		logger:            logger,
+	}
+}
+
+// Start begins listening for OpAMP connections on addr.
+func (s *Server) Start(addr string) error {
+	s.opamp = server.New(&opampLogger{logger: s.logger})
+	return s.opamp.Start(server.StartSettings{
+		ListenEndpoint: addr,
+		Settings: server.Settings{
+			Callbacks: server.CallbacksStruct{
+				OnConnectingFunc: s.onConnecting,
+			},
+		},
+	})
+}
+
+// AuthRejections returns how many connections the authenticator has turned
+// away since startup.
+func (s *Server) AuthRejections() int64 {
+	return s.authRejects.Load()
+}
+
+// Stop drains connected agents and shuts the OpAMP listener down. Agents
+// are notified before their sockets close — redirected to the configured
+// drain peer, or told to back off and retry — and marked disconnected in
+// the registry.
+func (s *Server) Stop(ctx context.Context) error {
+	if s.opamp == nil {
+		return nil
+	}
+	s.drainConnections(ctx)
+	return s.opamp.Stop(ctx)
+}
+
+// connInfo is the per-connection context captured at connect time.
+type connInfo struct {
+	tenant   string
+	identity string
+	ip       string
+}
+
+func (s *Server) onConnecting(r *http.Request) types.ConnectionResponse {
+	ip := remoteIP(r)
+	if ok, reason := s.limiter.admitted(ip); !ok {
+		s.logger.Warn("rejected connection", "remote", r.RemoteAddr, "error", reason)
+		status := http.StatusServiceUnavailable
+		if reason == "per-ip connection limit reached" {
+			status = http.StatusTooManyRequests
+		}
+		return types.ConnectionResponse{Accept: false, HTTPStatusCode: status}
+	}
+	var identity string
+	if s.authenticator != nil {
+		id, err := s.authenticator.Authenticate(r)
+		if err != nil {
+			s.authRejects.Add(1)
+			s.logger.Warn("rejected connection", "remote", r.RemoteAddr, "error", err)
+			return types.ConnectionResponse{Accept: false, HTTPStatusCode: http.StatusUnauthorized}
+		}
+		identity = id
+	}
+	info := connInfo{tenant: tenantFromRequest(r), identity: identity, ip: ip}
+	return types.ConnectionResponse{
+		Accept: true,
+		ConnectionCallbacks: server.ConnectionCallbacksStruct{
+			OnConnectedFunc: func(_ context.Context, conn types.Connection) {
+				s.tenants.Store(conn, info)
+				s.activeConns.Add(1)
+				s.limiter.connected(info.ip)
+			},
+			OnMessageFunc:         s.handleMessage,
+			OnConnectionCloseFunc: s.onConnectionClose,
+		},
+	}
+}
+
+// remoteIP extracts the client IP from the connect request's remote address.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// tenantFromRequest extracts the tenant from the connect request: the
+// dedicated header wins, otherwise a "<tenant>:<secret>" bearer token is
+// split. Agents carrying neither belong to the default tenant.
+func tenantFromRequest(r *http.Request) string {
+	if tenant := r.Header.Get(TenantHeader); tenant != "" {
+		return tenant
+	}
+	auth := r.Header.Get("Authorization")
+	if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+		if tenant, _, ok := strings.Cut(token, ":"); ok {
+			return tenant
+		}
+	}
+	return ""
+}
+
+// handleMessage processes one AgentToServer message and builds the reply.
+func (s *Server) handleMessage(ctx context.Context, conn types.Connection, msg *protobufs.AgentToServer) *protobufs.ServerToAgent {
+	// Agents commonly send 16-byte binary UUID/ULID instance UIDs; the
+	// registry and connection maps key on the normalized text form, while
+	// rawUIDs remembers the wire form for server-initiated sends.
+	uid := registry.NormalizeUID(string(msg.InstanceUid))
+	logger := s.logger.With("agent", uid)
+	s.msgCounters.count(msg)
+
+	// A different live connection already owning this UID means two agents
+	// share an identity, typically cloned VM images. Reassign the newcomer a
+	// fresh UID; the original keeps its mapping untouched.
+	var reassignedUID string
+	if v, ok := s.conns.Load(uid); ok && v.(types.Connection) != conn {
+		reassignedUID = newInstanceUID()
+		logger.Warn("instance uid collision, reassigning", "new_uid", reassignedUID)
+		if err := s.registry.RecordEvent(uid, registry.EventUIDReassigned, "collision: new connection reassigned to "+reassignedUID); err != nil {
+			logger.Error("failed to record uid reassignment", "error", err)
+		}
+		uid = reassignedUID
+		logger = s.logger.With("agent", uid)
+	}
+
+	s.conns.Store(uid, conn)
+	s.uids.Store(conn, uid)
+	if raw := string(msg.InstanceUid); raw != uid && reassignedUID == "" {
+		s.rawUIDs.Store(uid, raw)
+	}
+	if msg.Capabilities != 0 {
+		s.caps.Store(uid, msg.Capabilities)
+	}
+
+	if s.allowlist {
+		if rejection := s.gateUnknownAgent(uid, msg, logger); rejection != nil {
+			return rejection
+		}
+	}
+
+	if msg.AgentDescription != nil {
+		var info connInfo
+		if v, ok := s.tenants.Load(conn); ok {
+			info = v.(connInfo)
+		}
+		labels := normalizeLabels(s.labelNormalize, attrsToMap(msg.AgentDescription.IdentifyingAttributes))
+		description := normalizeLabels(s.labelNormalize, attrsToMap(msg.AgentDescription.NonIdentifyingAttributes))
+		applyLabelMapping(s.labelMapping, labels, description)
+		applyLabelRules(s.labelRules, labels, description)
+		agent := &registry.Agent{
+			InstanceUID: uid,
+			Tenant:      info.tenant,
+			Labels:      labels,
+			Description: description,
+		}
+		if err := s.registry.Upsert(agent); err != nil {
+			logger.Error("failed to upsert agent", "error", err)
+		}
+		if info.identity != "" {
+			if err := s.registry.RecordEvent(uid, registry.EventAuthenticated, info.identity); err != nil {
+				logger.Error("failed to record auth identity", "error", err)
+			}
+		}
+	} else if err := s.registry.Touch(uid); err != nil {
+		logger.Error("failed to touch agent", "error", err)
+	}
+
+	// Persist after the upsert so a brand-new agent's first message does not
+	// drop its capabilities on the floor.
+	if msg.Capabilities != 0 {
+		if err := s.registry.SetCapabilities(uid, decodeAgentCapabilities(msg.Capabilities)); err != nil {
+			logger.Error("failed to record capabilities", "error", err)
+		}
+	}
+
+	if rcs := msg.RemoteConfigStatus; rcs != nil {
+		s.recordConfigStatus(uid, rcs, logger)
+	}
+
+	if msg.PackageStatuses != nil {
+		s.recordPackageStatuses(uid, msg.PackageStatuses, logger)
+	}
+
+	if msg.Health != nil {
+		if err := s.registry.SetHealth(uid, healthFromProto(msg.Health)); err != nil && !errors.Is(err, registry.ErrNotFound) {
+			logger.Error("failed to record health", "error", err)
+		}
+	}
+
+	if msg.AgentDisconnect != nil {
+		// AgentDisconnect is the agent's last message before a deliberate
+		// shutdown; record it as such and skip all offers on the reply.
+		s.shutdowns.Store(uid, struct{}{})
+		if err := s.registry.SetShutdown(uid, "agent announced shutdown"); err != nil && !errors.Is(err, registry.ErrNotFound) {
+			logger.Error("failed to record agent shutdown", "error", err)
+		}
+		logger.Info("agent announced shutdown")
+		return &protobufs.ServerToAgent{InstanceUid: msg.InstanceUid}
+	}
+
+	response := &protobufs.ServerToAgent{
+		InstanceUid:  msg.InstanceUid,
+		Capabilities: uint64(protobufs.ServerCapabilities_ServerCapabilities_OffersRemoteConfig),
+	}
+	if s.certSigner != nil {
+		response.Capabilities |= uint64(protobufs.ServerCapabilities_ServerCapabilities_AcceptsConnectionSettingsRequest)
+	}
+	if reassignedUID != "" {
+		response.AgentIdentification = &protobufs.AgentIdentification{NewInstanceUid: []byte(reassignedUID)}
+	}
+
+	if msg.CustomMessage != nil {
+		response.CustomMessage = s.handleCustomMessage(ctx, uid, msg.CustomMessage)
+	}
+	if msg.CustomCapabilities != nil || msg.AgentDescription != nil {
+		response.CustomCapabilities = s.customCapabilities()
+	}
+
+	agent, err := s.registry.Get(uid)
+	if errors.Is(err, registry.ErrNotFound) {
+		// No record at all — typically the first message after a control
+		// plane restart with a fresh database. Ask for everything rather
+		// than waiting for the agent's next scheduled full report.
+		logger.Info("unknown agent, requesting full state report")
+		response.Flags |= uint64(protobufs.ServerToAgentFlags_ServerToAgentFlags_ReportFullState)
+		return response
+	}
+	if err != nil {
+		logger.Error("agent lookup failed", "error", err)
+		return response
+	}
+	if msg.AgentDescription == nil && len(agent.Labels) == 0 && len(agent.Description) == 0 {
+		// The record exists but we never saw a description; without labels
+		// the agent cannot be matched to a selector.
+		response.Flags |= uint64(protobufs.ServerToAgentFlags_ServerToAgentFlags_ReportFullState)
+	}
+
+	resolved, err := s.resolver.Resolve(agent)
+	if err != nil {
+		logger.Error("config resolution failed", "error", err)
+		return response
+	}
+
+	agentCaps := s.agentCaps(uid)
+
+	if offers := ownTelemetryOffers(resolved.OwnTelemetry, agentCaps); offers != nil {
+		response.ConnectionSettings = offers
+		response.Capabilities |= uint64(protobufs.ServerCapabilities_ServerCapabilities_OffersConnectionSettings)
+	}
+
+	s.offerHeartbeat(response, resolved, agentCaps)
+
+	if s.certSigner != nil && msg.ConnectionSettingsRequest != nil {
+		s.handleCertificateRequest(response, uid, msg.ConnectionSettingsRequest, logger)
+	}
+
+	if s.packages != nil && agentCaps&uint64(protobufs.AgentCapabilities_AgentCapabilities_AcceptsPackages) != 0 {
+		response.PackagesAvailable = s.packagesAvailable(uid, logger)
+		if response.PackagesAvailable != nil {
+			response.Capabilities |= uint64(protobufs.ServerCapabilities_ServerCapabilities_OffersPackages)
+		}
+	}
+
+	// A pending dead letter means a previous push to this agent failed; the
+	// agent talking to us again is the retry opportunity. Likewise a queued
+	// delivery from a config change while the agent was offline.
+	retryPending := false
+	if _, err := s.registry.DeadLetter(uid); err == nil {
+		retryPending = true
+	} else if !errors.Is(err, registry.ErrNotFound) {
+		logger.Error("dead letter lookup failed", "error", err)
+	}
+	if n, err := s.registry.PendingDeliveryCount(uid); err != nil {
+		logger.Error("pending delivery lookup failed", "error", err)
+	} else if n > 0 {
+		retryPending = true
+	}
+
+	if resolved.Unmanaged {
+		return response
+	}
+	// Offering RemoteConfig to an agent that did not report
+	// AcceptsRemoteConfig is a spec violation.
+	if agentCaps&uint64(protobufs.AgentCapabilities_AgentCapabilities_AcceptsRemoteConfig) == 0 {
+		return response
+	}
+
+	// Prefer the hash the agent reported in this very message over the DB
+	// copy: after a control plane restart the registry can lag behind, and
+	// trusting it would re-send a config the agent already runs.
+	agentHash := agent.ConfigHash
+	if msg.RemoteConfigStatus != nil && len(msg.RemoteConfigStatus.LastRemoteConfigHash) > 0 {
+		agentHash = hex.EncodeToString(msg.RemoteConfigStatus.LastRemoteConfigHash)
+	}
+
+	if resolved.Hash != agentHash || agent.ConfigState == registry.ConfigStateFailed || retryPending {
+		logger.Info("offering config", "selector", resolved.Selector, "hash", resolved.Hash)
+		if len(resolved.Warnings) > 0 {
+			logger.Warn("offered config has lint warnings", "selector", resolved.Selector, "warnings", resolved.Warnings)
+		}
+		response.RemoteConfig = makeRemoteConfig(resolved)
+		if err := s.registry.SetConfigState(uid, resolved.Hash, registry.ConfigStateOffered, ""); err != nil {
+			logger.Error("failed to record config offer", "error", err)
+		}
+		if err := s.registry.SetOfferedConfig(uid, resolved.Hash, resolved.Body); err != nil {
+			logger.Error("failed to record offered config body", "error", err)
+		}
+		if err := s.registry.RecordConfigVersion(resolved.Hash, resolved.Selector, resolved.Body, resolved.Commit); err != nil {
+			logger.Error("failed to record config version", "error", err)
+		}
+		if retryPending {
+			if err := s.registry.ClearDeadLetter(uid); err != nil {
+				logger.Error("failed to clear dead letter", "error", err)
+			}
+			if err := s.registry.ClearPendingDeliveries(uid); err != nil {
+				logger.Error("failed to clear pending deliveries", "error", err)
+			}
+		}
+	}
+	return response
+}
+
+func (s *Server) onConnectionClose(conn types.Connection) {
+	s.activeConns.Add(-1)
+	if v, ok := s.tenants.LoadAndDelete(conn); ok {
+		s.limiter.closed(v.(connInfo).ip)
+	}
+	v, ok := s.uids.LoadAndDelete(conn)
+	if !ok {
+		return
+	}
+	uid := v.(string)
+	s.conns.Delete(uid)
+	s.rawUIDs.Delete(uid)
+	s.caps.Delete(uid)
+	s.lastPush.Delete(uid)
+	if _, announced := s.shutdowns.LoadAndDelete(uid); announced {
+		// Already marked shutdown from the AgentDisconnect message; the
+		// socket closing is expected.
+		return
+	}
+	if err := s.registry.SetStatus(uid, registry.StatusDisconnected); err != nil && !errors.Is(err, registry.ErrNotFound) {
+		s.logger.Error("failed to mark agent disconnected", "agent", uid, "error", err)
+	}
+}
+
+// recordConfigStatus maps the agent-reported RemoteConfigStatus onto the
+// registry's config state.
+func (s *Server) recordConfigStatus(uid string, rcs *protobufs.RemoteConfigStatus, logger *slog.Logger) {
+	hash := hex.EncodeToString(rcs.LastRemoteConfigHash)
+	var state registry.ConfigState
+	switch rcs.Status {
+	case protobufs.RemoteConfigStatuses_RemoteConfigStatuses_APPLIED:
+		state = registry.ConfigStateApplied
+	case protobufs.RemoteConfigStatuses_RemoteConfigStatuses_APPLYING:
+		state = registry.ConfigStateApplying
+	case protobufs.RemoteConfigStatuses_RemoteConfigStatuses_FAILED:
+		state = registry.ConfigStateFailed
+	default:
+		return
+	}
+	if err := s.registry.SetConfigState(uid, hash, state, rcs.ErrorMessage); err != nil && !errors.Is(err, registry.ErrNotFound) {
+		logger.Error("failed to record config status", "error", err)
+	}
+}
+
+// Push skip reasons: the agent is fine, it just should not receive a config
+// right now. PushConfigToAll counts these as skips rather than failures.
+var (
+	errPushRateLimited = errors.New("push rate limit exceeded")
+	errAgentUnmanaged  = errors.New("agent is unmanaged")
+	errAgentPending    = errors.New("agent is pending allowlist approval")
+	errNoRemoteConfig  = errors.New("agent does not accept remote config")
+)
+
+// skippablePushError reports whether err means the push was deliberately not
+// made, as opposed to attempted and failed.
+func skippablePushError(err error) bool {
+	return errors.Is(err, errPushRateLimited) ||
+		errors.Is(err, errAgentUnmanaged) ||
+		errors.Is(err, errAgentPending) ||
+		errors.Is(err, errNoRemoteConfig)
+}
+
+// PushConfigToAgent resolves and sends the current config to one connected
+// agent, regardless of what it is currently running.
+func (s *Server) PushConfigToAgent(ctx context.Context, uid string) error {
+	v, ok := s.conns.Load(uid)
+	if !ok {
+		return errors.New("agent is not connected")
+	}
+	conn := v.(types.Connection)
+
+	if s.minPushInterval > 0 {
+		if v, ok := s.lastPush.Load(uid); ok && time.Since(v.(time.Time)) < s.minPushInterval {
+			return errPushRateLimited
+		}
+	}
+
+	agent, err := s.registry.Get(uid)
+	if err != nil {
+		return err
+	}
+	if agent.Pending {
+		return errAgentPending
+	}
+	resolved, err := s.resolver.Resolve(agent)
+	if err != nil {
+		return err
+	}
+	if resolved.Unmanaged {
+		return errAgentUnmanaged
+	}
+	if s.agentCaps(uid)&uint64(protobufs.AgentCapabilities_AgentCapabilities_AcceptsRemoteConfig) == 0 {
+		return errNoRemoteConfig
+	}
+	err = conn.Send(ctx, &protobufs.ServerToAgent{
+		InstanceUid:  []byte(s.wireUID(uid)),
+		RemoteConfig: makeRemoteConfig(resolved),
+	})
+	if err != nil {
+		s.sendFailures.Add(1)
+		// Capture the failed send so the config is re-offered when the
+		// agent next talks to us.
+		if dlErr := s.registry.RecordDeadLetter(uid, resolved.Hash, err.Error()); dlErr != nil {
+			s.logger.Error("failed to record dead letter", "agent", uid, "error", dlErr)
+		}
+		return err
+	}
+	s.pushesSent.Add(1)
+	if err := s.registry.SetOfferedConfig(uid, resolved.Hash, resolved.Body); err != nil {
+		s.logger.Error("failed to record offered config body", "agent", uid, "error", err)
+	}
+	if err := s.registry.RecordConfigVersion(resolved.Hash, resolved.Selector, resolved.Body, resolved.Commit); err != nil {
+		s.logger.Error("failed to record config version", "agent", uid, "error", err)
+	}
+	if err := s.registry.ClearDeadLetter(uid); err != nil {
+		s.logger.Error("failed to clear dead letter", "agent", uid, "error", err)
+	}
+	if err := s.registry.ClearPendingDeliveries(uid); err != nil {
+		s.logger.Error("failed to clear pending deliveries", "agent", uid, "error", err)
+	}
+	s.lastPush.Store(uid, time.Now())
+	return s.registry.SetConfigState(uid, resolved.Hash, registry.ConfigStateOffered, "")
+}
+
+// PushSummary reports the outcome of a fleet-wide config push.
+type PushSummary struct {
+	// Sent counts configs that reached their agent.
+	Sent int `json:"sent"`
+	// Skipped counts agents deliberately not pushed: unmanaged, not
+	// accepting remote config, or rate limited.
+	Skipped int `json:"skipped"`
+	// Failed counts pushes attempted but not delivered.
+	Failed int `json:"failed"`
+	// Queued counts disconnected agents whose new config was queued for
+	// delivery on their next connection.
+	Queued int `json:"queued"`
+}
+
+// Worker pool sizing for fleet-wide pushes: how many agents are pushed
+// concurrently and how long one push may take before its context is cut.
+const (
+	pushWorkers         = 8
+	pushPerAgentTimeout = 10 * time.Second
+)
+
+// PushConfigToAll re-resolves and sends configs to every connected agent
+// through a bounded worker pool. Called after a config repository sync.
+func (s *Server) PushConfigToAll(ctx context.Context) PushSummary {
+	var uids []string
+	s.conns.Range(func(key, _ any) bool {
+		uids = append(uids, key.(string))
+		return true
+	})
+	summary := s.PushConfigToAgents(ctx, uids)
+	summary.Queued = s.queueOfflineDeliveries()
+	if len(uids) > 0 || summary.Queued > 0 {
+		s.logger.Info("config push complete",
+			"sent", summary.Sent, "skipped", summary.Skipped, "failed", summary.Failed,
+			"queued", summary.Queued)
+	}
+	return summary
+}
+
+// PushConfigToAgents re-resolves and sends configs to the given agents
+// through a bounded worker pool. Unlike PushConfigToAll it does not queue
+// deliveries for disconnected agents, so a targeted push stays targeted.
+func (s *Server) PushConfigToAgents(ctx context.Context, uids []string) PushSummary {
+	var sent, skipped, failed atomic.Int64
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < pushWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for uid := range work {
+				pushCtx, cancel := context.WithTimeout(ctx, pushPerAgentTimeout)
+				err := s.PushConfigToAgent(pushCtx, uid)
+				cancel()
+				switch {
+				case err == nil:
+					sent.Add(1)
+				case skippablePushError(err):
+					s.logger.Debug("push skipped", "agent", uid, "reason", err)
+					skipped.Add(1)
+				default:
+					s.logger.Error("config push failed", "agent", uid, "error", err)
+					failed.Add(1)
+				}
+			}
+		}()
+	}
+	for i, uid := range uids {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- uid
+		if done := i + 1; done%100 == 0 {
+			s.logger.Info("config push progress", "queued", done, "total", len(uids))
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	return PushSummary{
+		Sent:    int(sent.Load()),
+		Skipped: int(skipped.Load()),
+		Failed:  int(failed.Load()),
+	}
+}
+
+// queueOfflineDeliveries records the current config hash as a pending
+// delivery for every disconnected managed agent that is not already running
+// it, so reconnecting agents are served immediately.
+func (s *Server) queueOfflineDeliveries() int {
+	agents, err := s.registry.List(registry.AgentFilter{})
+	if err != nil {
+		s.logger.Error("failed to list agents for delivery queue", "error", err)
+		return 0
+	}
+	queued := 0
+	for _, agent := range agents {
+		if _, connected := s.conns.Load(agent.InstanceUID); connected {
+			continue
+		}
+		if agent.Unmanaged || agent.Pending {
+			continue
+		}
+		resolved, err := s.resolver.Resolve(agent)
+		if err != nil || resolved.Unmanaged || resolved.Hash == agent.ConfigHash {
+			continue
+		}
+		if err := s.registry.QueueDelivery(agent.InstanceUID, resolved.Hash); err != nil {
+			s.logger.Error("failed to queue delivery", "agent", agent.InstanceUID, "error", err)
+			continue
+		}
+		queued++
+	}
+	return queued
+}
+
+// wireUID returns the instance UID in the form the agent sends on the wire,
+// which may be raw bytes rather than the normalized text the registry keys
+// on.
+func (s *Server) wireUID(uid string) string {
+	if v, ok := s.rawUIDs.Load(uid); ok {
+		return v.(string)
+	}
+	return uid
+}
+
+func makeRemoteConfig(resolved *config.ResolvedConfig) *protobufs.AgentRemoteConfig {
+	hash, _ := hex.DecodeString(resolved.Hash)
+	return &protobufs.AgentRemoteConfig{
+		Config: &protobufs.AgentConfigMap{
+			ConfigMap: map[string]*protobufs.AgentConfigFile{
+				"": {Body: resolved.Body, ContentType: resolved.ContentType},
+			},
+		},
+		ConfigHash: hash,
+	}
+}
+
+func attrsToMap(attrs []*protobufs.KeyValue) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		if kv == nil || kv.Value == nil {
+			continue
+		}
+		m[kv.Key] = kv.Value.GetStringValue()
+	}
+	return m
+}
@@ -0,0 +1,116 @@
+// Package packages implements the artifact store behind the OpAMP package
+// upgrade flow. Artifacts live in a local directory laid out as
+// <dir>/<package>/<version> (one file per version); the control plane serves
+// them over the API and offers them to agents via PackagesAvailable.
+package packages
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Artifact is one package version present in the store.
+type Artifact struct {
+	Package string `json:"package"`
+	Version string `json:"version"`
+	// Hash is the hex-encoded SHA-256 of the artifact contents.
+	Hash string `json:"hash"`
+	// Size is the artifact size in bytes.
+	Size int64 `json:"size"`
+}
+
+// Store is a directory-backed artifact store.
+type Store struct {
+	dir string
+	// baseURL is the externally reachable URL prefix agents download from,
+	// e.g. "https://controlplane.example.com/packages".
+	baseURL string
+}
+
+// NewStore returns a store over dir. baseURL is the URL prefix under which
+// the API serves artifact files.
+func NewStore(dir, baseURL string) *Store {
+	return &Store{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// List returns every artifact in the store, sorted by package then version.
+func (s *Store) List() ([]Artifact, error) {
+	pkgs, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list packages: %w", err)
+	}
+	var artifacts []Artifact
+	for _, pkg := range pkgs {
+		if !pkg.IsDir() {
+			continue
+		}
+		versions, err := os.ReadDir(filepath.Join(s.dir, pkg.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("list versions of %s: %w", pkg.Name(), err)
+		}
+		for _, version := range versions {
+			if version.IsDir() {
+				continue
+			}
+			a, err := s.Lookup(pkg.Name(), version.Name())
+			if err != nil {
+				return nil, err
+			}
+			artifacts = append(artifacts, *a)
+		}
+	}
+	sort.Slice(artifacts, func(i, j int) bool {
+		if artifacts[i].Package != artifacts[j].Package {
+			return artifacts[i].Package < artifacts[j].Package
+		}
+		return artifacts[i].Version < artifacts[j].Version
+	})
+	return artifacts, nil
+}
+
+// Lookup returns the artifact for a package version, hashing its contents.
+func (s *Store) Lookup(pkg, version string) (*Artifact, error) {
+	path, err := s.FilePath(pkg, version)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("package %s@%s: %w", pkg, version, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, fmt.Errorf("hash package %s@%s: %w", pkg, version, err)
+	}
+	return &Artifact{
+		Package: pkg,
+		Version: version,
+		Hash:    hex.EncodeToString(h.Sum(nil)),
+		Size:    size,
+	}, nil
+}
+
+// FilePath returns the on-disk path of an artifact, rejecting path escapes.
+func (s *Store) FilePath(pkg, version string) (string, error) {
+	if strings.ContainsAny(pkg, "/\\") || strings.ContainsAny(version, "/\\") ||
+		pkg == "" || version == "" || strings.HasPrefix(pkg, ".") || strings.HasPrefix(version, ".") {
+		return "", fmt.Errorf("invalid package reference %q@%q", pkg, version)
+	}
+	return filepath.Join(s.dir, pkg, version), nil
+}
+
+// DownloadURL returns the URL agents fetch the artifact from.
+func (s *Store) DownloadURL(pkg, version string) string {
+	return fmt.Sprintf("%s/%s/%s", s.baseURL, pkg, version)
+}
@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"fmt"
+	"time"
+)
+
+// apiSelectorsSchema stores selectors managed through the API rather than
+// the git repository, so simple routing changes do not need a git workflow.
+// Definitions are kept as the YAML the caller submitted so they round-trip
+// unchanged.
+const apiSelectorsSchema = `
+CREATE TABLE IF NOT EXISTS api_selectors (
+	name       TEXT PRIMARY KEY,
+	definition TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+`
+
+// PutAPISelector creates or replaces an API-managed selector definition.
+func (r *Registry) PutAPISelector(name, definition string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO api_selectors (name, definition, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET definition = excluded.definition, updated_at = excluded.updated_at`,
+		name, definition, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("put api selector %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteAPISelector removes an API-managed selector. ErrNotFound means no
+// selector with that name was stored.
+func (r *Registry) DeleteAPISelector(name string) error {
+	res, err := r.db.Exec(`DELETE FROM api_selectors WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("delete api selector %s: %w", name, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// APISelectors returns every API-managed selector definition keyed by name.
+func (r *Registry) APISelectors() (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT name, definition FROM api_selectors`)
+	if err != nil {
+		return nil, fmt.Errorf("list api selectors: %w", err)
+	}
+	defer rows.Close()
+	defs := map[string]string{}
+	for rows.Next() {
+		var name, definition string
+		if err := rows.Scan(&name, &definition); err != nil {
+			return nil, fmt.Errorf("scan api selector: %w", err)
+		}
+		defs[name] = definition
+	}
+	return defs, rows.Err()
+}
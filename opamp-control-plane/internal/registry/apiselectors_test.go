@@ -0,0 +1,41 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAPISelectors(t *testing.T) {
+	r := openTestRegistry(t)
+	if err := r.PutAPISelector("edge", "config: configs/edge.yaml\n"); err != nil {
+		t.Fatalf("PutAPISelector: %v", err)
+	}
+	if err := r.PutAPISelector("edge", "config: configs/edge-v2.yaml\n"); err != nil {
+		t.Fatalf("PutAPISelector update: %v", err)
+	}
+	if err := r.PutAPISelector("core", "config: configs/core.yaml\n"); err != nil {
+		t.Fatalf("PutAPISelector: %v", err)
+	}
+
+	defs, err := r.APISelectors()
+	if err != nil {
+		t.Fatalf("APISelectors: %v", err)
+	}
+	if len(defs) != 2 || defs["edge"] != "config: configs/edge-v2.yaml\n" {
+		t.Errorf("APISelectors = %v", defs)
+	}
+
+	if err := r.DeleteAPISelector("edge"); err != nil {
+		t.Fatalf("DeleteAPISelector: %v", err)
+	}
+	if err := r.DeleteAPISelector("edge"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting a missing selector returned %v, want ErrNotFound", err)
+	}
+	defs, err = r.APISelectors()
+	if err != nil {
+		t.Fatalf("APISelectors: %v", err)
+	}
+	if len(defs) != 1 {
+		t.Errorf("APISelectors after delete = %v", defs)
+	}
+}
@@ -0,0 +1,53 @@
+package registry
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// agentConfigsSchema stores the body of the last config delivered to each
+// agent, so operators can diff what an agent runs against what a push would
+// send.
+const agentConfigsSchema = `
+CREATE TABLE IF NOT EXISTS agent_configs (
+	instance_uid TEXT PRIMARY KEY,
+	config_hash  TEXT NOT NULL,
+	body         BLOB NOT NULL,
+	updated_at   TIMESTAMP NOT NULL
+);
+`
+
+// SetOfferedConfig records the config body last offered to an agent,
+// replacing any previous record.
+func (r *Registry) SetOfferedConfig(uid, hash string, body []byte) error {
+	_, err := r.db.Exec(`
+		INSERT INTO agent_configs (instance_uid, config_hash, body, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(instance_uid) DO UPDATE SET
+			config_hash = excluded.config_hash,
+			body = excluded.body,
+			updated_at = excluded.updated_at`,
+		uid, hash, body, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("set offered config for %s: %w", uid, err)
+	}
+	return nil
+}
+
+// OfferedConfig returns the hash and body of the config last offered to an
+// agent. ErrNotFound means no config was ever delivered.
+func (r *Registry) OfferedConfig(uid string) (string, []byte, error) {
+	var hash string
+	var body []byte
+	err := r.db.QueryRow(`SELECT config_hash, body FROM agent_configs WHERE instance_uid = ?`, uid).
+		Scan(&hash, &body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, ErrNotFound
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("get offered config for %s: %w", uid, err)
+	}
+	return hash, body, nil
+}
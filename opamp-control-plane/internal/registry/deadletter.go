@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DeadLetter records a config send that failed, so the config can be
+// re-offered when the agent next talks to us instead of being silently
+// dropped. At most one dead letter is kept per agent: a newer failure
+// replaces the older one but keeps the attempt count.
+type DeadLetter struct {
+	InstanceUID   string    `json:"instance_uid"`
+	ConfigHash    string    `json:"config_hash"`
+	Error         string    `json:"error"`
+	Attempts      int       `json:"attempts"`
+	FirstFailedAt time.Time `json:"first_failed_at"`
+	LastFailedAt  time.Time `json:"last_failed_at"`
+}
+
+const deadLetterSchema = `
+CREATE TABLE IF NOT EXISTS dead_letters (
+	instance_uid    TEXT PRIMARY KEY,
+	config_hash     TEXT NOT NULL,
+	error           TEXT NOT NULL,
+	attempts        INTEGER NOT NULL DEFAULT 1,
+	first_failed_at TIMESTAMP NOT NULL,
+	last_failed_at  TIMESTAMP NOT NULL
+);
+`
+
+// RecordDeadLetter records a failed config send for an agent.
+func (r *Registry) RecordDeadLetter(uid, hash, errMsg string) error {
+	now := time.Now().UTC()
+	_, err := r.db.Exec(`
+		INSERT INTO dead_letters (instance_uid, config_hash, error, attempts, first_failed_at, last_failed_at)
+		VALUES (?, ?, ?, 1, ?, ?)
+		ON CONFLICT(instance_uid) DO UPDATE SET
+			config_hash = excluded.config_hash,
+			error = excluded.error,
+			attempts = attempts + 1,
+			last_failed_at = excluded.last_failed_at`,
+		uid, hash, errMsg, now, now)
+	if err != nil {
+		return fmt.Errorf("record dead letter for %s: %w", uid, err)
+	}
+	return nil
+}
+
+// DeadLetter returns the pending dead letter for an agent, or ErrNotFound.
+func (r *Registry) DeadLetter(uid string) (*DeadLetter, error) {
+	row := r.db.QueryRow(`
+		SELECT instance_uid, config_hash, error, attempts, first_failed_at, last_failed_at
+		FROM dead_letters WHERE instance_uid = ?`, uid)
+	dl := &DeadLetter{}
+	err := row.Scan(&dl.InstanceUID, &dl.ConfigHash, &dl.Error, &dl.Attempts,
+		&dl.FirstFailedAt, &dl.LastFailedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load dead letter for %s: %w", uid, err)
+	}
+	return dl, nil
+}
+
+// DeadLetters returns all pending dead letters, oldest failure first.
+func (r *Registry) DeadLetters() ([]*DeadLetter, error) {
+	rows, err := r.db.Query(`
+		SELECT instance_uid, config_hash, error, attempts, first_failed_at, last_failed_at
+		FROM dead_letters ORDER BY first_failed_at`)
+	if err != nil {
+		return nil, fmt.Errorf("list dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var letters []*DeadLetter
+	for rows.Next() {
+		dl := &DeadLetter{}
+		if err := rows.Scan(&dl.InstanceUID, &dl.ConfigHash, &dl.Error, &dl.Attempts,
+			&dl.FirstFailedAt, &dl.LastFailedAt); err != nil {
+			return nil, err
+		}
+		letters = append(letters, dl)
+	}
+	return letters, rows.Err()
+}
+
+// ClearDeadLetter removes the pending dead letter for an agent.
+func (r *Registry) ClearDeadLetter(uid string) error {
+	_, err := r.db.Exec(`DELETE FROM dead_letters WHERE instance_uid = ?`, uid)
+	if err != nil {
+		return fmt.Errorf("clear dead letter for %s: %w", uid, err)
+	}
+	return nil
+}
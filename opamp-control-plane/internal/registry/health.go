@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// HealthState summarizes an agent's overall health for filtering.
+type HealthState string
+
+const (
+	HealthUnknown   HealthState = ""
+	HealthHealthy   HealthState = "healthy"
+	HealthUnhealthy HealthState = "unhealthy"
+)
+
+// AgentHealth mirrors the OpAMP ComponentHealth tree reported by an agent.
+type AgentHealth struct {
+	Healthy    bool                    `json:"healthy"`
+	StartTime  time.Time               `json:"start_time,omitempty"`
+	LastError  string                  `json:"last_error,omitempty"`
+	Status     string                  `json:"status,omitempty"`
+	StatusTime time.Time               `json:"status_time,omitempty"`
+	Components map[string]*AgentHealth `json:"components,omitempty"`
+}
+
+// Event type recorded when an agent's overall health flips.
+const EventHealthChanged = "health_changed"
+
+// SetHealth persists the health tree reported by an agent and records an
+// event when the overall healthy/unhealthy state transitions.
+func (r *Registry) SetHealth(uid string, h *AgentHealth) error {
+	state := HealthHealthy
+	if !h.Healthy {
+		state = HealthUnhealthy
+	}
+
+	var previous HealthState
+	err := r.db.QueryRow(`SELECT healthy FROM agents WHERE instance_uid = ?`, uid).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("load health for %s: %w", uid, err)
+	}
+
+	blob, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`UPDATE agents SET healthy = ?, health = ?, last_seen = ? WHERE instance_uid = ?`,
+		state, string(blob), time.Now().UTC(), uid)
+	if err != nil {
+		return fmt.Errorf("set health for %s: %w", uid, err)
+	}
+
+	if previous != state {
+		detail := string(previous) + " -> " + string(state)
+		if state == HealthUnhealthy && h.LastError != "" {
+			detail += ": " + h.LastError
+		}
+		return r.RecordEvent(uid, EventHealthChanged, detail)
+	}
+	return nil
+}
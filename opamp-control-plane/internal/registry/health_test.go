@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func openTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestSetHealthRecordsTransitions(t *testing.T) {
+	r := openTestRegistry(t)
+	uid := "01HX0000000000000000000000"
+	if err := r.Upsert(&Agent{InstanceUID: uid}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	if err := r.SetHealth(uid, &AgentHealth{Healthy: true}); err != nil {
+		t.Fatalf("SetHealth(healthy): %v", err)
+	}
+	if err := r.SetHealth(uid, &AgentHealth{Healthy: true}); err != nil {
+		t.Fatalf("SetHealth(healthy again): %v", err)
+	}
+	if err := r.SetHealth(uid, &AgentHealth{Healthy: false, LastError: "exporter down"}); err != nil {
+		t.Fatalf("SetHealth(unhealthy): %v", err)
+	}
+
+	agent, err := r.Get(uid)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if agent.Healthy != HealthUnhealthy {
+		t.Errorf("Healthy = %q, want %q", agent.Healthy, HealthUnhealthy)
+	}
+	if agent.Health == nil || agent.Health.LastError != "exporter down" {
+		t.Errorf("Health = %+v, want last_error preserved", agent.Health)
+	}
+
+	events, err := r.Events(uid, 10)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	var transitions int
+	for _, e := range events {
+		if e.Type == EventHealthChanged {
+			transitions++
+		}
+	}
+	if transitions != 2 {
+		t.Errorf("health_changed events = %d, want 2 (unknown->healthy, healthy->unhealthy)", transitions)
+	}
+
+	unhealthy, err := r.List(AgentFilter{Health: HealthUnhealthy})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(unhealthy) != 1 || unhealthy[0].InstanceUID != uid {
+		t.Errorf("List(unhealthy) = %v, want [%s]", unhealthy, uid)
+	}
+}
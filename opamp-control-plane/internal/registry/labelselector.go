@@ -0,0 +1,138 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LabelRequirement is one parsed term of a label selector expression.
+type LabelRequirement struct {
+	Key string
+	// Op is one of "=", "!=", "in", "notin", "exists", "!exists".
+	Op     string
+	Values []string
+}
+
+// matches reports whether the requirement holds for the given labels. As
+// with Kubernetes selectors, "!=" and "notin" also match agents missing the
+// key entirely.
+func (req LabelRequirement) matches(labels map[string]string) bool {
+	value, ok := labels[req.Key]
+	switch req.Op {
+	case "=":
+		return ok && value == req.Values[0]
+	case "!=":
+		return !ok || value != req.Values[0]
+	case "in":
+		if !ok {
+			return false
+		}
+		for _, v := range req.Values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	case "notin":
+		if !ok {
+			return true
+		}
+		for _, v := range req.Values {
+			if v == value {
+				return false
+			}
+		}
+		return true
+	case "exists":
+		return ok
+	case "!exists":
+		return !ok
+	}
+	return false
+}
+
+// ParseLabelSelector parses a Kubernetes-style label selector expression:
+// comma-separated requirements of the form "key=value", "key!=value",
+// "key in (v1,v2)", "key notin (v1,v2)", "key" (exists), or "!key" (does
+// not exist).
+func ParseLabelSelector(s string) ([]LabelRequirement, error) {
+	var reqs []LabelRequirement
+	for _, term := range splitSelectorTerms(s) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		req, err := parseRequirement(term)
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+// splitSelectorTerms splits on commas outside parentheses, so set values in
+// "key in (a,b)" stay together.
+func splitSelectorTerms(s string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, s[start:])
+}
+
+func parseRequirement(term string) (LabelRequirement, error) {
+	if key, ok := strings.CutPrefix(term, "!"); ok {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return LabelRequirement{}, fmt.Errorf("label selector %q: missing key", term)
+		}
+		return LabelRequirement{Key: key, Op: "!exists"}, nil
+	}
+	for _, set := range []string{" notin ", " in "} {
+		key, rest, found := strings.Cut(term, set)
+		if !found {
+			continue
+		}
+		rest = strings.TrimSpace(rest)
+		if !strings.HasPrefix(rest, "(") || !strings.HasSuffix(rest, ")") {
+			return LabelRequirement{}, fmt.Errorf("label selector %q: set values must be parenthesized", term)
+		}
+		var values []string
+		for _, v := range strings.Split(rest[1:len(rest)-1], ",") {
+			values = append(values, strings.TrimSpace(v))
+		}
+		key = strings.TrimSpace(key)
+		if key == "" || len(values) == 0 {
+			return LabelRequirement{}, fmt.Errorf("label selector %q: missing key or values", term)
+		}
+		return LabelRequirement{Key: key, Op: strings.TrimSpace(set), Values: values}, nil
+	}
+	if key, value, found := strings.Cut(term, "!="); found {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return LabelRequirement{}, fmt.Errorf("label selector %q: missing key", term)
+		}
+		return LabelRequirement{Key: key, Op: "!=", Values: []string{strings.TrimSpace(value)}}, nil
+	}
+	if key, value, found := strings.Cut(term, "="); found {
+		value = strings.TrimPrefix(value, "=") // tolerate "=="
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return LabelRequirement{}, fmt.Errorf("label selector %q: missing key", term)
+		}
+		return LabelRequirement{Key: key, Op: "=", Values: []string{strings.TrimSpace(value)}}, nil
+	}
+	return LabelRequirement{Key: term, Op: "exists"}, nil
+}
@@ -0,0 +1,63 @@
+package registry
+
+import "testing"
+
+func TestParseLabelSelector(t *testing.T) {
+	reqs, err := ParseLabelSelector("env=prod, region!=us-east, tier in (web, api), !legacy, canary")
+	if err != nil {
+		t.Fatalf("ParseLabelSelector: %v", err)
+	}
+	if len(reqs) != 5 {
+		t.Fatalf("parsed %d requirements, want 5: %+v", len(reqs), reqs)
+	}
+
+	cases := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{map[string]string{"env": "prod", "region": "eu", "tier": "web", "canary": "true"}, true},
+		{map[string]string{"env": "prod", "region": "us-east", "tier": "web", "canary": "true"}, false},
+		{map[string]string{"env": "prod", "tier": "db", "canary": "true"}, false},
+		{map[string]string{"env": "prod", "tier": "api", "canary": "true", "legacy": "1"}, false},
+		{map[string]string{"env": "prod", "tier": "api"}, false}, // canary missing
+	}
+	for i, tc := range cases {
+		if got := selectorMatches(reqs, tc.labels); got != tc.want {
+			t.Errorf("case %d: selectorMatches(%v) = %t, want %t", i, tc.labels, got, tc.want)
+		}
+	}
+}
+
+func TestParseLabelSelectorErrors(t *testing.T) {
+	for _, s := range []string{"=prod", "!", "tier in web"} {
+		if _, err := ParseLabelSelector(s); err == nil {
+			t.Errorf("ParseLabelSelector(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestListLabelSelector(t *testing.T) {
+	r := openTestRegistry(t)
+	agents := []*Agent{
+		{InstanceUID: "01HX0000000000000000000001", Labels: map[string]string{"env": "prod", "region": "eu"}},
+		{InstanceUID: "01HX0000000000000000000002", Labels: map[string]string{"env": "prod", "region": "us"}},
+		{InstanceUID: "01HX0000000000000000000003", Labels: map[string]string{"env": "dev"}},
+	}
+	for _, a := range agents {
+		if err := r.Upsert(a); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+
+	selector, err := ParseLabelSelector("env=prod,region!=us")
+	if err != nil {
+		t.Fatalf("ParseLabelSelector: %v", err)
+	}
+	got, err := r.List(AgentFilter{Selector: selector, Limit: 10})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != 1 || got[0].InstanceUID != agents[0].InstanceUID {
+		t.Errorf("List = %+v, want only the eu prod agent", got)
+	}
+}
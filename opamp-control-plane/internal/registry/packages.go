@@ -0,0 +1,193 @@
+package registry
+
+import (
+	"fmt"
+	"time"
+)
+
+// PackageAssignment targets a package version at one agent. Assignments are
+// the server-side intent; what the agent actually runs arrives later via
+// PackageStatuses.
+type PackageAssignment struct {
+	InstanceUID string    `json:"instance_uid"`
+	Package     string    `json:"package"`
+	Version     string    `json:"version"`
+	AssignedAt  time.Time `json:"assigned_at"`
+}
+
+// Event type recorded when a package version is targeted at an agent.
+const EventPackageAssigned = "package_assigned"
+
+// Event type recorded when an agent reports a failed package install.
+const EventPackageInstallFailed = "package_install_failed"
+
+// PackageStatus is the agent-reported state of one package: what version it
+// actually has and how the last install went.
+type PackageStatus struct {
+	InstanceUID string    `json:"instance_uid"`
+	Package     string    `json:"package"`
+	Version     string    `json:"version"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	ReportedAt  time.Time `json:"reported_at"`
+}
+
+// Package status values, mirroring the OpAMP PackageStatusEnum.
+const (
+	PackageInstalled      = "installed"
+	PackageInstallPending = "install_pending"
+	PackageInstalling     = "installing"
+	PackageInstallFailed  = "install_failed"
+)
+
+const packagesSchema = `
+CREATE TABLE IF NOT EXISTS package_assignments (
+	instance_uid TEXT NOT NULL,
+	package      TEXT NOT NULL,
+	version      TEXT NOT NULL,
+	assigned_at  TIMESTAMP NOT NULL,
+	PRIMARY KEY (instance_uid, package)
+);
+
+CREATE TABLE IF NOT EXISTS package_statuses (
+	instance_uid TEXT NOT NULL,
+	package      TEXT NOT NULL,
+	version      TEXT NOT NULL DEFAULT '',
+	status       TEXT NOT NULL,
+	error        TEXT NOT NULL DEFAULT '',
+	reported_at  TIMESTAMP NOT NULL,
+	PRIMARY KEY (instance_uid, package)
+);
+`
+
+// AssignPackage targets a package version at a set of agents, replacing any
+// previous assignment of the same package.
+func (r *Registry) AssignPackage(uids []string, pkg, version string) error {
+	now := time.Now().UTC()
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("assign package: %w", err)
+	}
+	defer tx.Rollback()
+	for _, uid := range uids {
+		_, err := tx.Exec(`
+			INSERT INTO package_assignments (instance_uid, package, version, assigned_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(instance_uid, package) DO UPDATE SET
+				version = excluded.version,
+				assigned_at = excluded.assigned_at`,
+			uid, pkg, version, now)
+		if err != nil {
+			return fmt.Errorf("assign package %s@%s to %s: %w", pkg, version, uid, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("assign package: %w", err)
+	}
+	for _, uid := range uids {
+		if err := r.RecordEvent(uid, EventPackageAssigned, pkg+"@"+version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PackageAssignments returns the package versions targeted at an agent.
+func (r *Registry) PackageAssignments(uid string) ([]PackageAssignment, error) {
+	rows, err := r.db.Query(`
+		SELECT instance_uid, package, version, assigned_at
+		FROM package_assignments WHERE instance_uid = ? ORDER BY package`, uid)
+	if err != nil {
+		return nil, fmt.Errorf("list package assignments for %s: %w", uid, err)
+	}
+	defer rows.Close()
+
+	var assignments []PackageAssignment
+	for rows.Next() {
+		var a PackageAssignment
+		if err := rows.Scan(&a.InstanceUID, &a.Package, &a.Version, &a.AssignedAt); err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, a)
+	}
+	return assignments, rows.Err()
+}
+
+// SetPackageStatuses replaces the agent's reported package statuses with the
+// given set (agents report the full set each time) and records an event for
+// each package newly entering the install-failed state.
+func (r *Registry) SetPackageStatuses(uid string, statuses []PackageStatus) error {
+	previous, err := r.PackageStatuses(uid)
+	if err != nil {
+		return err
+	}
+	prevStatus := make(map[string]string, len(previous))
+	for _, p := range previous {
+		prevStatus[p.Package] = p.Status
+	}
+
+	now := time.Now().UTC()
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("set package statuses: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM package_statuses WHERE instance_uid = ?`, uid); err != nil {
+		return fmt.Errorf("set package statuses for %s: %w", uid, err)
+	}
+	for _, st := range statuses {
+		_, err := tx.Exec(`
+			INSERT INTO package_statuses (instance_uid, package, version, status, error, reported_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			uid, st.Package, st.Version, st.Status, st.Error, now)
+		if err != nil {
+			return fmt.Errorf("set package status %s for %s: %w", st.Package, uid, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("set package statuses: %w", err)
+	}
+
+	for _, st := range statuses {
+		if st.Status == PackageInstallFailed && prevStatus[st.Package] != PackageInstallFailed {
+			detail := st.Package
+			if st.Error != "" {
+				detail += ": " + st.Error
+			}
+			if err := r.RecordEvent(uid, EventPackageInstallFailed, detail); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PackageStatuses returns the agent's reported package statuses.
+func (r *Registry) PackageStatuses(uid string) ([]PackageStatus, error) {
+	rows, err := r.db.Query(`
+		SELECT instance_uid, package, version, status, error, reported_at
+		FROM package_statuses WHERE instance_uid = ? ORDER BY package`, uid)
+	if err != nil {
+		return nil, fmt.Errorf("list package statuses for %s: %w", uid, err)
+	}
+	defer rows.Close()
+
+	var statuses []PackageStatus
+	for rows.Next() {
+		var st PackageStatus
+		if err := rows.Scan(&st.InstanceUID, &st.Package, &st.Version, &st.Status, &st.Error, &st.ReportedAt); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, rows.Err()
+}
+
+// UnassignPackage removes a package assignment from an agent.
+func (r *Registry) UnassignPackage(uid, pkg string) error {
+	_, err := r.db.Exec(`DELETE FROM package_assignments WHERE instance_uid = ? AND package = ?`, uid, pkg)
+	if err != nil {
+		return fmt.Errorf("unassign package %s from %s: %w", pkg, uid, err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package registry
+
+import "testing"
+
+func TestSetPackageStatusesReplacesAndRecordsFailures(t *testing.T) {
+	r := openTestRegistry(t)
+	uid := "01HX0000000000000000000000"
+	if err := r.Upsert(&Agent{InstanceUID: uid}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	first := []PackageStatus{
+		{Package: "collector", Version: "0.90.0", Status: PackageInstalled},
+		{Package: "plugin", Status: PackageInstallFailed, Error: "checksum mismatch"},
+	}
+	if err := r.SetPackageStatuses(uid, first); err != nil {
+		t.Fatalf("SetPackageStatuses: %v", err)
+	}
+	// Same failure reported again must not produce a second event.
+	if err := r.SetPackageStatuses(uid, first); err != nil {
+		t.Fatalf("SetPackageStatuses(repeat): %v", err)
+	}
+
+	statuses, err := r.PackageStatuses(uid)
+	if err != nil {
+		t.Fatalf("PackageStatuses: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("PackageStatuses returned %d entries, want 2", len(statuses))
+	}
+	if statuses[0].Package != "collector" || statuses[0].Version != "0.90.0" {
+		t.Errorf("statuses[0] = %+v, want collector 0.90.0", statuses[0])
+	}
+
+	events, err := r.Events(uid, 10)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	var failures int
+	for _, e := range events {
+		if e.Type == EventPackageInstallFailed {
+			failures++
+			if e.Detail != "plugin: checksum mismatch" {
+				t.Errorf("event detail = %q", e.Detail)
+			}
+		}
+	}
+	if failures != 1 {
+		t.Errorf("install failure events = %d, want 1", failures)
+	}
+
+	// A successful install replaces the failed entry.
+	if err := r.SetPackageStatuses(uid, []PackageStatus{
+		{Package: "plugin", Version: "1.2.0", Status: PackageInstalled},
+	}); err != nil {
+		t.Fatalf("SetPackageStatuses(recovered): %v", err)
+	}
+	statuses, err = r.PackageStatuses(uid)
+	if err != nil {
+		t.Fatalf("PackageStatuses: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Status != PackageInstalled {
+		t.Errorf("statuses after recovery = %+v, want single installed plugin", statuses)
+	}
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"fmt"
+	"time"
+)
+
+// PendingDelivery records a config change that happened while an agent was
+// disconnected, so the new config is offered on the agent's first message
+// after reconnecting rather than whenever the hash comparison next fires.
+// One row is kept per queued hash; several changes while offline leave
+// several rows, all cleared by the one delivery of the current config.
+type PendingDelivery struct {
+	InstanceUID string    `json:"instance_uid"`
+	ConfigHash  string    `json:"config_hash"`
+	QueuedAt    time.Time `json:"queued_at"`
+}
+
+const pendingSchema = `
+CREATE TABLE IF NOT EXISTS pending_deliveries (
+	instance_uid TEXT NOT NULL,
+	config_hash  TEXT NOT NULL,
+	queued_at    TIMESTAMP NOT NULL,
+	PRIMARY KEY (instance_uid, config_hash)
+);
+`
+
+// QueueDelivery queues a config hash for delivery to a disconnected agent.
+// Queuing the same hash again is a no-op.
+func (r *Registry) QueueDelivery(uid, hash string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO pending_deliveries (instance_uid, config_hash, queued_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(instance_uid, config_hash) DO NOTHING`,
+		uid, hash, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("queue delivery for %s: %w", uid, err)
+	}
+	return nil
+}
+
+// PendingDeliveryCount returns how many config changes are queued for an
+// agent.
+func (r *Registry) PendingDeliveryCount(uid string) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM pending_deliveries WHERE instance_uid = ?`, uid).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count pending deliveries for %s: %w", uid, err)
+	}
+	return n, nil
+}
+
+// PendingDeliveryCounts returns the queue depth for every agent with at
+// least one pending delivery.
+func (r *Registry) PendingDeliveryCounts() (map[string]int, error) {
+	rows, err := r.db.Query(`
+		SELECT instance_uid, COUNT(*) FROM pending_deliveries GROUP BY instance_uid`)
+	if err != nil {
+		return nil, fmt.Errorf("count pending deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var uid string
+		var n int
+		if err := rows.Scan(&uid, &n); err != nil {
+			return nil, err
+		}
+		counts[uid] = n
+	}
+	return counts, rows.Err()
+}
+
+// ClearPendingDeliveries removes all queued deliveries for an agent.
+func (r *Registry) ClearPendingDeliveries(uid string) error {
+	_, err := r.db.Exec(`DELETE FROM pending_deliveries WHERE instance_uid = ?`, uid)
+	if err != nil {
+		return fmt.Errorf("clear pending deliveries for %s: %w", uid, err)
+	}
+	return nil
+}
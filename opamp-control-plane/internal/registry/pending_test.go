@@ -0,0 +1,48 @@
+package registry
+
+import "testing"
+
+func TestPendingDeliveries(t *testing.T) {
+	r := openTestRegistry(t)
+
+	if err := r.Upsert(&Agent{InstanceUID: "agent-1"}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	if err := r.QueueDelivery("agent-1", "hash-a"); err != nil {
+		t.Fatalf("QueueDelivery: %v", err)
+	}
+	if err := r.QueueDelivery("agent-1", "hash-a"); err != nil {
+		t.Fatalf("QueueDelivery duplicate: %v", err)
+	}
+	if err := r.QueueDelivery("agent-1", "hash-b"); err != nil {
+		t.Fatalf("QueueDelivery: %v", err)
+	}
+
+	n, err := r.PendingDeliveryCount("agent-1")
+	if err != nil {
+		t.Fatalf("PendingDeliveryCount: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 pending deliveries, got %d", n)
+	}
+
+	counts, err := r.PendingDeliveryCounts()
+	if err != nil {
+		t.Fatalf("PendingDeliveryCounts: %v", err)
+	}
+	if counts["agent-1"] != 2 {
+		t.Fatalf("expected 2 pending deliveries in counts, got %d", counts["agent-1"])
+	}
+
+	if err := r.ClearPendingDeliveries("agent-1"); err != nil {
+		t.Fatalf("ClearPendingDeliveries: %v", err)
+	}
+	n, err = r.PendingDeliveryCount("agent-1")
+	if err != nil {
+		t.Fatalf("PendingDeliveryCount: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected empty queue after clear, got %d", n)
+	}
+}
@@ -0,0 +1,665 @@
+// Package registry persists the fleet state: every agent the control plane
+// has ever seen, its reported description and labels, its connection state,
+// and the status of the config we last offered it.
+//
+// The registry is backed by an embedded SQLite database so a control plane
+// restart does not lose fleet history. All methods are safe for concurrent
+// use.
+package registry
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// ErrNotFound is returned when an agent is not present in the registry.
+var ErrNotFound = errors.New("agent not found")
+
+// AgentStatus is the connection state of an agent.
+type AgentStatus string
+
+const (
+	StatusConnected    AgentStatus = "connected"
+	StatusDisconnected AgentStatus = "disconnected"
+	// StatusShutdown marks an agent that announced a clean shutdown via
+	// AgentDisconnect, as opposed to a dropped socket. Staleness checks
+	// should leave these agents alone: they said goodbye.
+	StatusShutdown AgentStatus = "shutdown"
+)
+
+// ConfigState is the last known state of the remote config on an agent.
+type ConfigState string
+
+const (
+	ConfigStateUnset    ConfigState = ""
+	ConfigStateOffered  ConfigState = "offered"
+	ConfigStateApplied  ConfigState = "applied"
+	ConfigStateFailed   ConfigState = "failed"
+	ConfigStateApplying ConfigState = "applying"
+)
+
+// Agent is one collector instance known to the control plane.
+type Agent struct {
+	InstanceUID string            `json:"instance_uid"`
+	Tenant      string            `json:"tenant,omitempty"`
+	Status      AgentStatus       `json:"status"`
+	Labels      map[string]string `json:"labels"`
+	Description map[string]string `json:"description"`
+	// Capabilities are the decoded OpAMP capability names the agent last
+	// reported.
+	Capabilities []string     `json:"capabilities,omitempty"`
+	ConfigHash   string       `json:"config_hash"`
+	ConfigState  ConfigState  `json:"config_state"`
+	ConfigError  string       `json:"config_error,omitempty"`
+	Healthy      HealthState  `json:"healthy,omitempty"`
+	Health       *AgentHealth `json:"health,omitempty"`
+	// Unmanaged agents are observed (heartbeats, health, events) but never
+	// offered configs.
+	Unmanaged bool `json:"unmanaged,omitempty"`
+	// Pending agents connected while the allowlist was enforced without
+	// being pre-registered; they are parked until an operator approves them.
+	Pending bool `json:"pending,omitempty"`
+	// CertNotAfter is the expiry of the client certificate last issued to
+	// this agent via the CSR flow. Nil when none was ever issued.
+	CertNotAfter *time.Time `json:"cert_not_after,omitempty"`
+	FirstSeen    time.Time  `json:"first_seen"`
+	LastSeen     time.Time  `json:"last_seen"`
+}
+
+// AgentFilter narrows List results. Zero values mean "no constraint".
+type AgentFilter struct {
+	Tenant      string
+	Status      AgentStatus
+	ConfigState ConfigState
+	Health      HealthState
+	Labels      map[string]string
+	// Selector holds parsed label selector requirements (see
+	// ParseLabelSelector), evaluated in addition to the Labels equalities.
+	Selector []LabelRequirement
+	// LastSeenBefore / LastSeenAfter bound the agents' last_seen timestamps,
+	// e.g. to find agents that went quiet in a specific window.
+	LastSeenBefore time.Time
+	LastSeenAfter  time.Time
+	Limit          int
+	Offset         int
+	// After, when set, returns only agents with an instance UID strictly
+	// greater than it. Agents are always ordered by UID, so it is a stable
+	// pagination cursor even while the fleet churns.
+	After string
+}
+
+// Event is one entry in the event log. Agent-scoped events carry the
+// instance UID; fleet-level change events (git syncs, operator actions)
+// carry an actor instead.
+type Event struct {
+	ID          int64     `json:"id"`
+	Time        time.Time `json:"time"`
+	InstanceUID string    `json:"instance_uid,omitempty"`
+	Type        string    `json:"type"`
+	Detail      string    `json:"detail,omitempty"`
+	Actor       string    `json:"actor,omitempty"`
+}
+
+// Event types recorded by the control plane.
+const (
+	EventConnected         = "connected"
+	EventDisconnected      = "disconnected"
+	EventConfigOffered     = "config_offered"
+	EventConfigApplied     = "config_applied"
+	EventConfigFailed      = "config_failed"
+	EventCertificateIssued = "certificate_issued"
+	EventUIDReassigned     = "uid_reassigned"
+	EventManagedChanged    = "managed_changed"
+	EventAuthenticated     = "authenticated"
+	EventGitSync           = "git_sync"
+	EventSyncTriggered     = "sync_triggered"
+	EventConfigPushed      = "config_pushed"
+	EventApprovalChanged   = "approval_changed"
+	EventRollback          = "rollback"
+	EventRolloutStarted    = "rollout_started"
+	EventChangeProposed    = "change_proposed"
+	EventChangeApproved    = "change_approved"
+	EventChangeRejected    = "change_rejected"
+	EventSelectorUpdated   = "selector_updated"
+	EventSelectorDeleted   = "selector_deleted"
+)
+
+// changeEventTypes are the event types surfaced in the fleet change feed:
+// things an operator or the config repo did, as opposed to agent telemetry.
+// Extend this list as new change-producing operations are added.
+var changeEventTypes = []string{
+	EventGitSync,
+	EventSyncTriggered,
+	EventConfigPushed,
+	EventManagedChanged,
+	EventApprovalChanged,
+	EventRollback,
+	EventRolloutStarted,
+	EventChangeProposed,
+	EventChangeApproved,
+	EventChangeRejected,
+	EventSelectorUpdated,
+	EventSelectorDeleted,
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS agents (
+	instance_uid TEXT PRIMARY KEY,
+	tenant       TEXT NOT NULL DEFAULT '',
+	status       TEXT NOT NULL DEFAULT 'disconnected',
+	labels       TEXT NOT NULL DEFAULT '{}',
+	description  TEXT NOT NULL DEFAULT '{}',
+	capabilities TEXT NOT NULL DEFAULT '[]',
+	config_hash  TEXT NOT NULL DEFAULT '',
+	config_state TEXT NOT NULL DEFAULT '',
+	config_error TEXT NOT NULL DEFAULT '',
+	healthy      TEXT NOT NULL DEFAULT '',
+	health       TEXT NOT NULL DEFAULT 'null',
+	unmanaged    INTEGER NOT NULL DEFAULT 0,
+	pending      INTEGER NOT NULL DEFAULT 0,
+	cert_not_after TIMESTAMP,
+	first_seen   TIMESTAMP NOT NULL,
+	last_seen    TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS events (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	time         TIMESTAMP NOT NULL,
+	instance_uid TEXT NOT NULL,
+	type         TEXT NOT NULL,
+	detail       TEXT NOT NULL DEFAULT '',
+	actor        TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_agent ON events(instance_uid, time);
+`
+
+// Registry is the persistent fleet database.
+type Registry struct {
+	db *sql.DB
+
+	// eventHook, when set, observes every recorded event. Set once at
+	// startup via SetEventHook, before the registry sees traffic.
+	eventHook func(*Event)
+}
+
+// migrations are additive schema changes applied to databases created by
+// older versions. "duplicate column" errors are expected on fresh databases
+// and ignored.
+var migrations = []string{
+	`ALTER TABLE agents ADD COLUMN tenant TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE agents ADD COLUMN healthy TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE agents ADD COLUMN health TEXT NOT NULL DEFAULT 'null'`,
+	`ALTER TABLE agents ADD COLUMN capabilities TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE agents ADD COLUMN unmanaged INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE agents ADD COLUMN pending INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE agents ADD COLUMN cert_not_after TIMESTAMP`,
+	`ALTER TABLE events ADD COLUMN actor TEXT NOT NULL DEFAULT ''`,
+}
+
+// Open opens (creating if necessary) the registry database at path.
+func Open(path string) (*Registry, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("open registry db: %w", err)
+	}
+	for _, s := range []string{schema, packagesSchema, deadLetterSchema, pendingSchema, agentConfigsSchema, configVersionsSchema, apiSelectorsSchema} {
+		if _, err := db.Exec(s); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("apply registry schema: %w", err)
+		}
+	}
+	for _, m := range migrations {
+		if _, err := db.Exec(m); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			db.Close()
+			return nil, fmt.Errorf("apply registry migration: %w", err)
+		}
+	}
+	r := &Registry{db: db}
+	if err := r.normalizeStoredUIDs(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("normalize stored uids: %w", err)
+	}
+	return r, nil
+}
+
+// Close closes the underlying database.
+func (r *Registry) Close() error {
+	return r.db.Close()
+}
+
+// Upsert inserts or refreshes an agent record. Labels and description are
+// replaced wholesale when non-nil; connection status and config fields are
+// left untouched so callers use the dedicated setters for those.
+func (r *Registry) Upsert(a *Agent) error {
+	now := time.Now().UTC()
+	labels, err := json.Marshal(orEmpty(a.Labels))
+	if err != nil {
+		return err
+	}
+	desc, err := json.Marshal(orEmpty(a.Description))
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO agents (instance_uid, tenant, status, labels, description, pending, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(instance_uid) DO UPDATE SET
+			tenant = excluded.tenant,
+			labels = excluded.labels,
+			description = excluded.description,
+			last_seen = excluded.last_seen`,
+		a.InstanceUID, a.Tenant, StatusConnected, string(labels), string(desc), a.Pending, now, now)
+	if err != nil {
+		return fmt.Errorf("upsert agent %s: %w", a.InstanceUID, err)
+	}
+	return nil
+}
+
+// SetStatus updates the connection status of an agent and records an event.
+func (r *Registry) SetStatus(uid string, status AgentStatus) error {
+	now := time.Now().UTC()
+	res, err := r.db.Exec(`UPDATE agents SET status = ?, last_seen = ? WHERE instance_uid = ?`,
+		status, now, uid)
+	if err != nil {
+		return fmt.Errorf("set status for %s: %w", uid, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	evType := EventConnected
+	if status == StatusDisconnected {
+		evType = EventDisconnected
+	}
+	return r.RecordEvent(uid, evType, "")
+}
+
+// SetShutdown marks an agent as cleanly shut down and records the reason.
+func (r *Registry) SetShutdown(uid, reason string) error {
+	now := time.Now().UTC()
+	res, err := r.db.Exec(`UPDATE agents SET status = ?, last_seen = ? WHERE instance_uid = ?`,
+		StatusShutdown, now, uid)
+	if err != nil {
+		return fmt.Errorf("set shutdown for %s: %w", uid, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return r.RecordEvent(uid, EventDisconnected, reason)
+}
+
+// Touch bumps last_seen for an agent without changing anything else.
+func (r *Registry) Touch(uid string) error {
+	_, err := r.db.Exec(`UPDATE agents SET last_seen = ? WHERE instance_uid = ?`,
+		time.Now().UTC(), uid)
+	return err
+}
+
+// SetConfigState records the state of the config identified by hash on the
+// agent, along with an optional error message, and logs the transition.
+func (r *Registry) SetConfigState(uid, hash string, state ConfigState, errMsg string) error {
+	res, err := r.db.Exec(`
+		UPDATE agents SET config_hash = ?, config_state = ?, config_error = ?, last_seen = ?
+		WHERE instance_uid = ?`,
+		hash, state, errMsg, time.Now().UTC(), uid)
+	if err != nil {
+		return fmt.Errorf("set config state for %s: %w", uid, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	var evType string
+	switch state {
+	case ConfigStateOffered:
+		evType = EventConfigOffered
+	case ConfigStateApplied:
+		evType = EventConfigApplied
+	case ConfigStateFailed:
+		evType = EventConfigFailed
+	default:
+		return nil
+	}
+	detail := hash
+	if errMsg != "" {
+		detail = hash + ": " + errMsg
+	}
+	return r.RecordEvent(uid, evType, detail)
+}
+
+// SetCapabilities stores the decoded capability names the agent reported.
+func (r *Registry) SetCapabilities(uid string, capabilities []string) error {
+	if capabilities == nil {
+		capabilities = []string{}
+	}
+	data, err := json.Marshal(capabilities)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`UPDATE agents SET capabilities = ? WHERE instance_uid = ?`, string(data), uid)
+	if err != nil {
+		return fmt.Errorf("set capabilities for %s: %w", uid, err)
+	}
+	return nil
+}
+
+// SetUnmanaged marks an agent as unmanaged (never offered configs) or back
+// as managed, and logs the transition.
+func (r *Registry) SetUnmanaged(uid string, unmanaged bool) error {
+	res, err := r.db.Exec(`UPDATE agents SET unmanaged = ? WHERE instance_uid = ?`, unmanaged, uid)
+	if err != nil {
+		return fmt.Errorf("set unmanaged for %s: %w", uid, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	detail := "managed"
+	if unmanaged {
+		detail = "unmanaged"
+	}
+	return r.RecordEvent(uid, EventManagedChanged, detail)
+}
+
+// SetPending parks an agent awaiting allowlist approval or releases it, and
+// logs the transition. Releasing a pending agent is the approval step: its
+// next message is served normally.
+func (r *Registry) SetPending(uid string, pending bool) error {
+	res, err := r.db.Exec(`UPDATE agents SET pending = ? WHERE instance_uid = ?`, pending, uid)
+	if err != nil {
+		return fmt.Errorf("set pending for %s: %w", uid, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	detail := "approved"
+	if pending {
+		detail = "pending approval"
+	}
+	return r.RecordEvent(uid, EventApprovalChanged, detail)
+}
+
+// SetCertificate records the expiry of a client certificate issued to the
+// agent via the CSR flow, and logs the issuance.
+func (r *Registry) SetCertificate(uid string, notAfter time.Time) error {
+	res, err := r.db.Exec(`UPDATE agents SET cert_not_after = ? WHERE instance_uid = ?`,
+		notAfter.UTC(), uid)
+	if err != nil {
+		return fmt.Errorf("set certificate for %s: %w", uid, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return r.RecordEvent(uid, EventCertificateIssued, "expires "+notAfter.UTC().Format(time.RFC3339))
+}
+
+// Get returns a single agent by instance UID.
+func (r *Registry) Get(uid string) (*Agent, error) {
+	row := r.db.QueryRow(`
+		SELECT instance_uid, tenant, status, labels, description, capabilities, config_hash,
+		       config_state, config_error, healthy, health, unmanaged, pending, cert_not_after,
+		       first_seen, last_seen
+		FROM agents WHERE instance_uid = ?`, uid)
+	a, err := scanAgent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return a, err
+}
+
+// List returns agents matching the filter, ordered by instance UID.
+func (r *Registry) List(f AgentFilter) ([]*Agent, error) {
+	query := `
+		SELECT instance_uid, tenant, status, labels, description, capabilities, config_hash,
+		       config_state, config_error, healthy, health, unmanaged, pending, cert_not_after,
+		       first_seen, last_seen
+		FROM agents WHERE 1=1`
+	var args []any
+	if f.Tenant != "" {
+		query += ` AND tenant = ?`
+		args = append(args, f.Tenant)
+	}
+	if f.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, f.Status)
+	}
+	if f.ConfigState != "" {
+		query += ` AND config_state = ?`
+		args = append(args, f.ConfigState)
+	}
+	if f.Health != "" {
+		query += ` AND healthy = ?`
+		args = append(args, f.Health)
+	}
+	if !f.LastSeenBefore.IsZero() {
+		query += ` AND last_seen < ?`
+		args = append(args, f.LastSeenBefore.UTC())
+	}
+	if !f.LastSeenAfter.IsZero() {
+		query += ` AND last_seen > ?`
+		args = append(args, f.LastSeenAfter.UTC())
+	}
+	if f.After != "" {
+		query += ` AND instance_uid > ?`
+		args = append(args, f.After)
+	}
+	query += ` ORDER BY instance_uid`
+	// Label filters are evaluated in Go (labels are stored as JSON), so the
+	// limit and offset can only be pushed into SQL when none are set;
+	// otherwise they apply to the filtered results below.
+	filtered := len(f.Labels) > 0 || len(f.Selector) > 0
+	if f.Limit > 0 && !filtered {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list agents: %w", err)
+	}
+	defer rows.Close()
+
+	var agents []*Agent
+	skip := 0
+	if filtered {
+		skip = f.Offset
+	}
+	for rows.Next() {
+		a, err := scanAgent(rows)
+		if err != nil {
+			return nil, err
+		}
+		if !labelsMatch(f.Labels, a.Labels) || !selectorMatches(f.Selector, a.Labels) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		agents = append(agents, a)
+		if filtered && f.Limit > 0 && len(agents) == f.Limit {
+			break
+		}
+	}
+	return agents, rows.Err()
+}
+
+func selectorMatches(reqs []LabelRequirement, labels map[string]string) bool {
+	for _, req := range reqs {
+		if !req.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetEventHook registers a function observing every event as it is
+// recorded, e.g. for outbound notifications. Must be called before the
+// registry sees traffic; the hook runs synchronously and should not block.
+func (r *Registry) SetEventHook(fn func(*Event)) {
+	r.eventHook = fn
+}
+
+// RecordEvent appends an agent-scoped entry to the event log.
+func (r *Registry) RecordEvent(uid, evType, detail string) error {
+	return r.record(uid, "", evType, detail)
+}
+
+// RecordChange appends a fleet-level change entry attributed to an actor,
+// e.g. a git sync or an operator action via the API.
+func (r *Registry) RecordChange(actor, evType, detail string) error {
+	return r.record("", actor, evType, detail)
+}
+
+func (r *Registry) record(uid, actor, evType, detail string) error {
+	now := time.Now().UTC()
+	res, err := r.db.Exec(`INSERT INTO events (time, instance_uid, type, detail, actor) VALUES (?, ?, ?, ?, ?)`,
+		now, uid, evType, detail, actor)
+	if err != nil {
+		return fmt.Errorf("record event %s: %w", evType, err)
+	}
+	if r.eventHook != nil {
+		id, _ := res.LastInsertId()
+		r.eventHook(&Event{ID: id, Time: now, InstanceUID: uid, Type: evType, Detail: detail, Actor: actor})
+	}
+	return nil
+}
+
+// Events returns the most recent events for an agent, newest first.
+func (r *Registry) Events(uid string, limit int) ([]*Event, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	return r.queryEvents(`
+		SELECT id, time, instance_uid, type, detail, actor FROM events
+		WHERE instance_uid = ? ORDER BY time DESC, id DESC LIMIT ?`, uid, limit)
+}
+
+// statusEventTypes are the event types that mark a connection or config
+// status transition, surfaced in the per-agent status timeline.
+var statusEventTypes = []string{
+	EventConnected,
+	EventDisconnected,
+	EventConfigOffered,
+	EventConfigApplied,
+	EventConfigFailed,
+	EventHealthChanged,
+}
+
+// StatusHistory returns the agent's connection and config status
+// transitions, newest first. Details carry the config hash or the agent's
+// error message for failed applies.
+func (r *Registry) StatusHistory(uid string, limit int) ([]*Event, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	args := make([]any, 0, len(statusEventTypes)+2)
+	placeholders := ""
+	for i, t := range statusEventTypes {
+		if i > 0 {
+			placeholders += ", "
+		}
+		placeholders += "?"
+		args = append(args, t)
+	}
+	args = append(args, uid, limit)
+	return r.queryEvents(`SELECT id, time, instance_uid, type, detail, actor FROM events
+		WHERE type IN (`+placeholders+`) AND instance_uid = ?
+		ORDER BY time DESC, id DESC LIMIT ?`, args...)
+}
+
+// Changes returns the fleet change feed, newest first: git syncs, manual
+// pushes, and other operator actions across all agents. since, when
+// non-zero, excludes entries at or before that time.
+func (r *Registry) Changes(since time.Time, limit int) ([]*Event, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	args := make([]any, 0, len(changeEventTypes)+2)
+	placeholders := ""
+	for i, t := range changeEventTypes {
+		if i > 0 {
+			placeholders += ", "
+		}
+		placeholders += "?"
+		args = append(args, t)
+	}
+	query := `SELECT id, time, instance_uid, type, detail, actor FROM events
+		WHERE type IN (` + placeholders + `)`
+	if !since.IsZero() {
+		query += ` AND time > ?`
+		args = append(args, since.UTC())
+	}
+	query += ` ORDER BY time DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+	return r.queryEvents(query, args...)
+}
+
+func (r *Registry) queryEvents(query string, args ...any) ([]*Event, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		e := &Event{}
+		if err := rows.Scan(&e.ID, &e.Time, &e.InstanceUID, &e.Type, &e.Detail, &e.Actor); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAgent(row rowScanner) (*Agent, error) {
+	a := &Agent{}
+	var labels, desc, capabilities, health string
+	var certNotAfter sql.NullTime
+	err := row.Scan(&a.InstanceUID, &a.Tenant, &a.Status, &labels, &desc, &capabilities,
+		&a.ConfigHash, &a.ConfigState, &a.ConfigError, &a.Healthy, &health, &a.Unmanaged,
+		&a.Pending, &certNotAfter, &a.FirstSeen, &a.LastSeen)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(capabilities), &a.Capabilities); err != nil {
+		return nil, fmt.Errorf("decode capabilities for %s: %w", a.InstanceUID, err)
+	}
+	if certNotAfter.Valid {
+		a.CertNotAfter = &certNotAfter.Time
+	}
+	if err := json.Unmarshal([]byte(labels), &a.Labels); err != nil {
+		return nil, fmt.Errorf("decode labels for %s: %w", a.InstanceUID, err)
+	}
+	if err := json.Unmarshal([]byte(desc), &a.Description); err != nil {
+		return nil, fmt.Errorf("decode description for %s: %w", a.InstanceUID, err)
+	}
+	if err := json.Unmarshal([]byte(health), &a.Health); err != nil {
+		return nil, fmt.Errorf("decode health for %s: %w", a.InstanceUID, err)
+	}
+	return a, nil
+}
+
+func labelsMatch(want, have map[string]string) bool {
+	for k, v := range want {
+		if have[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func orEmpty(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
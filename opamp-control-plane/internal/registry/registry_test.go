@@ -0,0 +1,106 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetShutdown(t *testing.T) {
+	r := openTestRegistry(t)
+	uid := "01HX0000000000000000000000"
+	if err := r.Upsert(&Agent{InstanceUID: uid}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if err := r.SetShutdown(uid, "agent announced shutdown"); err != nil {
+		t.Fatalf("SetShutdown: %v", err)
+	}
+	agent, err := r.Get(uid)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if agent.Status != StatusShutdown {
+		t.Errorf("Status = %q, want %q", agent.Status, StatusShutdown)
+	}
+	events, err := r.Events(uid, 10)
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	if len(events) == 0 || events[0].Type != EventDisconnected || events[0].Detail != "agent announced shutdown" {
+		t.Errorf("events[0] = %+v, want disconnected with shutdown reason", events[0])
+	}
+
+	if err := r.SetShutdown("unknown-agent-uid", "x"); err != ErrNotFound {
+		t.Errorf("SetShutdown(unknown) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestListAfterCursor(t *testing.T) {
+	r := openTestRegistry(t)
+	uids := []string{
+		"01HX0000000000000000000001",
+		"01HX0000000000000000000002",
+		"01HX0000000000000000000003",
+	}
+	for _, uid := range uids {
+		if err := r.Upsert(&Agent{InstanceUID: uid}); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+
+	page, err := r.List(AgentFilter{Limit: 2})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(page) != 2 || page[0].InstanceUID != uids[0] || page[1].InstanceUID != uids[1] {
+		t.Fatalf("first page = %+v, want first two agents", page)
+	}
+
+	page, err = r.List(AgentFilter{Limit: 2, After: uids[1]})
+	if err != nil {
+		t.Fatalf("List(after): %v", err)
+	}
+	if len(page) != 1 || page[0].InstanceUID != uids[2] {
+		t.Errorf("second page = %+v, want only the last agent", page)
+	}
+}
+
+func TestChangesFeed(t *testing.T) {
+	r := openTestRegistry(t)
+	uid := "01HX0000000000000000000000"
+	if err := r.Upsert(&Agent{InstanceUID: uid}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	if err := r.RecordChange("gitsync", EventGitSync, "commit abc123"); err != nil {
+		t.Fatalf("RecordChange: %v", err)
+	}
+	if err := r.RecordChange("ops@example.com", EventSyncTriggered, ""); err != nil {
+		t.Fatalf("RecordChange: %v", err)
+	}
+	// Agent telemetry must not appear in the change feed.
+	if err := r.RecordEvent(uid, EventConfigApplied, ""); err != nil {
+		t.Fatalf("RecordEvent: %v", err)
+	}
+
+	changes, err := r.Changes(time.Time{}, 10)
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Changes returned %d entries, want 2: %+v", len(changes), changes)
+	}
+	if changes[0].Type != EventSyncTriggered || changes[0].Actor != "ops@example.com" {
+		t.Errorf("changes[0] = %+v, want sync_triggered by ops@example.com", changes[0])
+	}
+	if changes[1].Type != EventGitSync || changes[1].Detail != "commit abc123" {
+		t.Errorf("changes[1] = %+v, want git_sync with commit detail", changes[1])
+	}
+
+	none, err := r.Changes(time.Now().Add(time.Hour), 10)
+	if err != nil {
+		t.Fatalf("Changes(since): %v", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("Changes(since future) = %+v, want empty", none)
+	}
+}
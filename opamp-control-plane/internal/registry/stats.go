@@ -0,0 +1,71 @@
+package registry
+
+import "fmt"
+
+// Stats are fleet-wide aggregate counts for dashboards, computed in SQL so
+// large fleets are not paged through the API to draw a pie chart.
+type Stats struct {
+	Agents        int            `json:"agents"`
+	ByStatus      map[string]int `json:"by_status"`
+	ByConfigState map[string]int `json:"by_config_state"`
+	// BySelector counts agents per the selector that produced their current
+	// config, via the recorded config versions. Agents whose hash predates
+	// version recording (or who have no config) count under "".
+	BySelector map[string]int `json:"by_selector"`
+	// ByVersion and ByOS group on the agent-reported "service.version" and
+	// "os.type" description attributes; agents not reporting them count
+	// under "".
+	ByVersion map[string]int `json:"by_version"`
+	ByOS      map[string]int `json:"by_os"`
+}
+
+// Stats returns aggregate fleet counts.
+func (r *Registry) Stats() (*Stats, error) {
+	s := &Stats{
+		ByStatus:      map[string]int{},
+		ByConfigState: map[string]int{},
+		BySelector:    map[string]int{},
+		ByVersion:     map[string]int{},
+		ByOS:          map[string]int{},
+	}
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM agents`).Scan(&s.Agents); err != nil {
+		return nil, fmt.Errorf("count agents: %w", err)
+	}
+	counts := []struct {
+		dest  map[string]int
+		query string
+	}{
+		{s.ByStatus, `SELECT status, COUNT(*) FROM agents GROUP BY status`},
+		{s.ByConfigState, `SELECT config_state, COUNT(*) FROM agents GROUP BY config_state`},
+		{s.BySelector, `SELECT COALESCE(v.selector, ''), COUNT(*)
+			FROM agents a LEFT JOIN config_versions v ON a.config_hash = v.config_hash
+			GROUP BY 1`},
+		{s.ByVersion, `SELECT COALESCE(json_extract(description, '$."service.version"'), ''), COUNT(*)
+			FROM agents GROUP BY 1`},
+		{s.ByOS, `SELECT COALESCE(json_extract(description, '$."os.type"'), ''), COUNT(*)
+			FROM agents GROUP BY 1`},
+	}
+	for _, c := range counts {
+		if err := r.countInto(c.dest, c.query); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (r *Registry) countInto(dest map[string]int, query string) error {
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return fmt.Errorf("fleet stats: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var n int
+		if err := rows.Scan(&key, &n); err != nil {
+			return fmt.Errorf("fleet stats: %w", err)
+		}
+		dest[key] = n
+	}
+	return rows.Err()
+}
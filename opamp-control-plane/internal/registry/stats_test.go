@@ -0,0 +1,60 @@
+package registry
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	r := openTestRegistry(t)
+	agents := []*Agent{
+		{
+			InstanceUID: "01HX0000000000000000000001",
+			Description: map[string]string{"service.version": "0.98.0", "os.type": "linux"},
+		},
+		{
+			InstanceUID: "01HX0000000000000000000002",
+			Description: map[string]string{"service.version": "0.98.0", "os.type": "linux"},
+		},
+		{
+			InstanceUID: "01HX0000000000000000000003",
+			Description: map[string]string{"os.type": "windows"},
+		},
+	}
+	for _, a := range agents {
+		if err := r.Upsert(a); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+	}
+	for _, uid := range []string{agents[0].InstanceUID, agents[1].InstanceUID} {
+		if err := r.SetConfigState(uid, "hash-a", ConfigStateApplied, ""); err != nil {
+			t.Fatalf("SetConfigState: %v", err)
+		}
+	}
+	if err := r.SetStatus(agents[2].InstanceUID, StatusDisconnected); err != nil {
+		t.Fatalf("SetStatus: %v", err)
+	}
+	if err := r.RecordConfigVersion("hash-a", "edge", []byte("receivers: {}"), "abc123"); err != nil {
+		t.Fatalf("RecordConfigVersion: %v", err)
+	}
+
+	stats, err := r.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Agents != 3 {
+		t.Errorf("Agents = %d, want 3", stats.Agents)
+	}
+	if stats.ByStatus[string(StatusConnected)] != 2 || stats.ByStatus[string(StatusDisconnected)] != 1 {
+		t.Errorf("ByStatus = %v", stats.ByStatus)
+	}
+	if stats.ByConfigState[string(ConfigStateApplied)] != 2 {
+		t.Errorf("ByConfigState = %v", stats.ByConfigState)
+	}
+	if stats.BySelector["edge"] != 2 || stats.BySelector[""] != 1 {
+		t.Errorf("BySelector = %v", stats.BySelector)
+	}
+	if stats.ByVersion["0.98.0"] != 2 || stats.ByVersion[""] != 1 {
+		t.Errorf("ByVersion = %v", stats.ByVersion)
+	}
+	if stats.ByOS["linux"] != 2 || stats.ByOS["windows"] != 1 {
+		t.Errorf("ByOS = %v", stats.ByOS)
+	}
+}
@@ -0,0 +1,93 @@
+package registry
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// uidTextPattern matches instance UIDs already in a printable canonical form
+// (ULID, UUID, or similar). Mirrors the pattern the API accepts in URLs.
+var uidTextPattern = regexp.MustCompile(`^[0-9a-zA-Z-]{16,64}$`)
+
+// uidHexPattern matches the two textual UUID spellings that normalize to the
+// canonical lowercase dashed form: 32 bare hex digits or the dashed layout in
+// either case.
+var uidHexPattern = regexp.MustCompile(`^(?:[0-9a-fA-F]{32}|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+
+// NormalizeUID maps an instance UID as received on the wire to its stored
+// text form. Agents commonly send 16-byte binary UUIDs/ULIDs; storing the
+// raw bytes produces registry keys that fail the API's UID validation and
+// render as garbage. Printable UIDs pass through unchanged; 16-byte binary
+// values become canonical UUID text; anything else is hex-encoded. The
+// mapping is deterministic so the same agent always normalizes to the same
+// key.
+func NormalizeUID(uid string) string {
+	if uidHexPattern.MatchString(uid) {
+		// UUID text in some spelling; canonicalize so the dashed, undashed,
+		// and uppercase forms all land on the same registry key.
+		b, err := hex.DecodeString(strings.ToLower(strings.ReplaceAll(uid, "-", "")))
+		if err == nil && len(b) == 16 {
+			return formatUUID(b)
+		}
+	}
+	if uidTextPattern.MatchString(uid) {
+		return uid
+	}
+	if len(uid) == 16 {
+		return formatUUID([]byte(uid))
+	}
+	return hex.EncodeToString([]byte(uid))
+}
+
+// formatUUID renders 16 bytes in canonical lowercase dashed UUID form.
+func formatUUID(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// normalizeStoredUIDs rewrites rows persisted by older versions that stored
+// raw binary UIDs. When the normalized key already exists the stale binary
+// row is dropped in its favor.
+func (r *Registry) normalizeStoredUIDs() error {
+	rows, err := r.db.Query(`SELECT instance_uid FROM agents`)
+	if err != nil {
+		return fmt.Errorf("list agent uids: %w", err)
+	}
+	var renames [][2]string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			rows.Close()
+			return err
+		}
+		if normalized := NormalizeUID(uid); normalized != uid {
+			renames = append(renames, [2]string{uid, normalized})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, rename := range renames {
+		old, normalized := rename[0], rename[1]
+		var exists int
+		if err := r.db.QueryRow(`SELECT COUNT(*) FROM agents WHERE instance_uid = ?`, normalized).Scan(&exists); err != nil {
+			return err
+		}
+		if exists > 0 {
+			if _, err := r.db.Exec(`DELETE FROM agents WHERE instance_uid = ?`, old); err != nil {
+				return fmt.Errorf("drop stale agent row: %w", err)
+			}
+		} else if _, err := r.db.Exec(`UPDATE agents SET instance_uid = ? WHERE instance_uid = ?`, normalized, old); err != nil {
+			return fmt.Errorf("normalize agent uid: %w", err)
+		}
+		for _, table := range []string{"events", "dead_letters", "package_assignments", "package_statuses", "pending_deliveries", "agent_configs"} {
+			if _, err := r.db.Exec(`UPDATE OR REPLACE `+table+` SET instance_uid = ? WHERE instance_uid = ?`, normalized, old); err != nil {
+				return fmt.Errorf("normalize uids in %s: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
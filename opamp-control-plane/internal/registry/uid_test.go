@@ -0,0 +1,69 @@
+package registry
+
+import "testing"
+
+func TestNormalizeUID(t *testing.T) {
+	binary := string([]byte{
+		0x01, 0x8e, 0x3a, 0x52, 0x7b, 0x1c, 0x4d, 0x9e,
+		0xae, 0x02, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66,
+	})
+	tests := []struct {
+		name, in, want string
+	}{
+		{"ulid text", "01HV3ZX2J8K9M2N3P4Q5R6S7T8", "01HV3ZX2J8K9M2N3P4Q5R6S7T8"},
+		{"uuid text", "9a1f4c2e-7b3d-4e5f-8a9b-0c1d2e3f4a5b", "9a1f4c2e-7b3d-4e5f-8a9b-0c1d2e3f4a5b"},
+		{"binary uuid", binary, "018e3a52-7b1c-4d9e-ae02-112233445566"},
+		{"undashed hex uuid", "018E3A527B1C4D9EAE02112233445566", "018e3a52-7b1c-4d9e-ae02-112233445566"},
+		{"uppercase dashed uuid", "018E3A52-7B1C-4D9E-AE02-112233445566", "018e3a52-7b1c-4d9e-ae02-112233445566"},
+		{"short binary", "\x01\x02\x03\x04", "01020304"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeUID(tt.in); got != tt.want {
+				t.Errorf("NormalizeUID(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeUIDDeterministic(t *testing.T) {
+	in := string(make([]byte, 16))
+	if NormalizeUID(in) != NormalizeUID(in) {
+		t.Error("normalization is not deterministic")
+	}
+}
+
+func TestOpenNormalizesStoredUIDs(t *testing.T) {
+	path := t.TempDir() + "/reg.db"
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary := string([]byte{
+		0x01, 0x8e, 0x3a, 0x52, 0x7b, 0x1c, 0x4d, 0x9e,
+		0xae, 0x02, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66,
+	})
+	// Simulate an older version that stored the raw wire bytes.
+	if _, err := r.db.Exec(`
+		INSERT INTO agents (instance_uid, first_seen, last_seen)
+		VALUES (?, datetime('now'), datetime('now'))`, binary); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if _, err := r.Get("018e3a52-7b1c-4d9e-ae02-112233445566"); err != nil {
+		t.Errorf("normalized agent not found after reopen: %v", err)
+	}
+	if _, err := r.Get(binary); err == nil {
+		t.Error("raw binary uid still present after reopen")
+	}
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// configVersionsSchema is an append-only record of every distinct resolved
+// config the server has offered, keyed by hash. Agents report hashes in
+// their RemoteConfigStatus long after the git repository has moved on; this
+// table lets the API show the exact bytes behind any historical hash.
+const configVersionsSchema = `
+CREATE TABLE IF NOT EXISTS config_versions (
+	config_hash TEXT PRIMARY KEY,
+	selector    TEXT NOT NULL,
+	body        BLOB NOT NULL,
+	commit_sha  TEXT NOT NULL,
+	created_at  TIMESTAMP NOT NULL
+);
+`
+
+// ConfigVersion is one distinct resolved config the server has offered.
+type ConfigVersion struct {
+	Hash      string    `json:"hash"`
+	Selector  string    `json:"selector"`
+	Body      []byte    `json:"body,omitempty"`
+	Commit    string    `json:"commit,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecordConfigVersion stores a resolved config under its hash. Recording the
+// same hash again is a no-op, so the first-seen selector and commit are kept.
+func (r *Registry) RecordConfigVersion(hash, selector string, body []byte, commit string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO config_versions (config_hash, selector, body, commit_sha, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(config_hash) DO NOTHING`,
+		hash, selector, body, commit, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("record config version %s: %w", hash, err)
+	}
+	return nil
+}
+
+// ConfigVersion returns the stored config for a hash. ErrNotFound means the
+// server never offered a config with that hash.
+func (r *Registry) ConfigVersion(hash string) (*ConfigVersion, error) {
+	v := ConfigVersion{Hash: hash}
+	err := r.db.QueryRow(`
+		SELECT selector, body, commit_sha, created_at
+		FROM config_versions WHERE config_hash = ?`, hash).
+		Scan(&v.Selector, &v.Body, &v.Commit, &v.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get config version %s: %w", hash, err)
+	}
+	return &v, nil
+}
+
+// ConfigVersions returns all stored versions, newest first, without bodies.
+func (r *Registry) ConfigVersions() ([]ConfigVersion, error) {
+	rows, err := r.db.Query(`
+		SELECT config_hash, selector, commit_sha, created_at
+		FROM config_versions ORDER BY created_at DESC, config_hash`)
+	if err != nil {
+		return nil, fmt.Errorf("list config versions: %w", err)
+	}
+	defer rows.Close()
+	var versions []ConfigVersion
+	for rows.Next() {
+		var v ConfigVersion
+		if err := rows.Scan(&v.Hash, &v.Selector, &v.Commit, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan config version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
@@ -0,0 +1,46 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConfigVersions(t *testing.T) {
+	r := openTestRegistry(t)
+
+	if err := r.RecordConfigVersion("hash-a", "prod", []byte("receivers: {}"), "commit-1"); err != nil {
+		t.Fatalf("RecordConfigVersion: %v", err)
+	}
+	// Re-recording the same hash keeps the original row.
+	if err := r.RecordConfigVersion("hash-a", "other", []byte("changed"), "commit-2"); err != nil {
+		t.Fatalf("RecordConfigVersion duplicate: %v", err)
+	}
+	if err := r.RecordConfigVersion("hash-b", "staging", []byte("exporters: {}"), "commit-2"); err != nil {
+		t.Fatalf("RecordConfigVersion: %v", err)
+	}
+
+	v, err := r.ConfigVersion("hash-a")
+	if err != nil {
+		t.Fatalf("ConfigVersion: %v", err)
+	}
+	if v.Selector != "prod" || string(v.Body) != "receivers: {}" || v.Commit != "commit-1" {
+		t.Fatalf("unexpected version: %+v", v)
+	}
+
+	if _, err := r.ConfigVersion("hash-missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	versions, err := r.ConfigVersions()
+	if err != nil {
+		t.Fatalf("ConfigVersions: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(versions))
+	}
+	for _, v := range versions {
+		if len(v.Body) != 0 {
+			t.Fatalf("list included body for %s", v.Hash)
+		}
+	}
+}
@@ -0,0 +1,379 @@
+// Package rollout pushes config changes to the fleet in stages. Agents are
+// split into batches; each batch is pushed and the controller waits for the
+// agents to report their config status before starting the next, pausing
+// the rollout automatically when too many agents in a batch fail.
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// Pusher pushes the current resolved config to a connected agent.
+// Implemented by the opamp server.
+type Pusher interface {
+	PushConfigToAgent(ctx context.Context, uid string) error
+}
+
+// pollInterval is how often a batch's agents are re-checked while waiting
+// for them to apply.
+const pollInterval = 2 * time.Second
+
+// Options configure one rollout. Zero values take the defaults.
+type Options struct {
+	// Tenant and Labels narrow the target agents; empty means the whole
+	// fleet.
+	Tenant string            `json:"tenant,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// BatchSize is the number of agents pushed per stage. Default 20.
+	BatchSize int `json:"batch_size,omitempty"`
+	// BatchTimeout is how long to wait for a batch to apply before counting
+	// stragglers as failed. Default 2m.
+	BatchTimeout time.Duration `json:"batch_timeout,omitempty"`
+	// FailureThreshold is the fraction of a batch that may fail before the
+	// rollout pauses itself. Default 0.25.
+	FailureThreshold float64 `json:"failure_threshold,omitempty"`
+	// RequireHealthy additionally requires agents to report healthy, not
+	// just ConfigStateApplied, before a batch counts as done.
+	RequireHealthy bool `json:"require_healthy,omitempty"`
+}
+
+// State is a rollout's lifecycle phase.
+type State string
+
+const (
+	StateRunning   State = "running"
+	StatePaused    State = "paused"
+	StateCompleted State = "completed"
+	StateCancelled State = "cancelled"
+)
+
+// Rollout is one staged push across the fleet.
+type Rollout struct {
+	ID      string  `json:"id"`
+	State   State   `json:"state"`
+	Options Options `json:"options"`
+	// Total is the number of target agents; Applied and Failed count agents
+	// from finished batches plus the batch in flight.
+	Total   int `json:"total"`
+	Applied int `json:"applied"`
+	Failed  int `json:"failed"`
+	// Batch is the index of the batch in flight (or next to run), Batches
+	// the overall count.
+	Batch   int `json:"batch"`
+	Batches int `json:"batches"`
+	// PauseReason explains a paused state: set by the failure gate or the
+	// pause endpoint.
+	PauseReason string    `json:"pause_reason,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	batches [][]string
+	resume  chan struct{}
+}
+
+// Controller runs rollouts. One rollout is in flight at a time; starting a
+// second while one is running is an error.
+type Controller struct {
+	registry *registry.Registry
+	pusher   Pusher
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	seq      int
+	rollouts map[string]*Rollout
+	active   string
+}
+
+// New returns a Controller pushing through pusher.
+func New(reg *registry.Registry, pusher Pusher, logger *slog.Logger) *Controller {
+	return &Controller{
+		registry: reg,
+		pusher:   pusher,
+		logger:   logger,
+		rollouts: map[string]*Rollout{},
+	}
+}
+
+// Start begins a rollout over the agents matching opts and returns it
+// immediately; batches proceed in the background until the rollout
+// completes, pauses past its last batch, or is cancelled.
+func (c *Controller) Start(opts Options) (*Rollout, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 20
+	}
+	if opts.BatchTimeout <= 0 {
+		opts.BatchTimeout = 2 * time.Minute
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 0.25
+	}
+
+	agents, err := c.registry.List(registry.AgentFilter{
+		Tenant: opts.Tenant,
+		Status: registry.StatusConnected,
+		Labels: opts.Labels,
+		Limit:  10000,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list agents: %w", err)
+	}
+	var uids []string
+	for _, agent := range agents {
+		if agent.Unmanaged || agent.Pending {
+			continue
+		}
+		uids = append(uids, agent.InstanceUID)
+	}
+	if len(uids) == 0 {
+		return nil, fmt.Errorf("no connected managed agents match")
+	}
+
+	var batches [][]string
+	for len(uids) > 0 {
+		n := min(opts.BatchSize, len(uids))
+		batches = append(batches, uids[:n])
+		uids = uids[n:]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active != "" {
+		return nil, fmt.Errorf("rollout %s is already running", c.active)
+	}
+	c.seq++
+	now := time.Now().UTC()
+	r := &Rollout{
+		ID:        fmt.Sprintf("rollout-%d", c.seq),
+		State:     StateRunning,
+		Options:   opts,
+		Batches:   len(batches),
+		CreatedAt: now,
+		UpdatedAt: now,
+		batches:   batches,
+		resume:    make(chan struct{}, 1),
+	}
+	for _, b := range batches {
+		r.Total += len(b)
+	}
+	c.rollouts[r.ID] = r
+	c.active = r.ID
+	go c.run(r)
+	return c.snapshot(r), nil
+}
+
+// Get returns a rollout by ID.
+func (c *Controller) Get(id string) (*Rollout, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.rollouts[id]
+	if !ok {
+		return nil, false
+	}
+	return c.snapshot(r), true
+}
+
+// List returns all rollouts, newest first.
+func (c *Controller) List() []*Rollout {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]*Rollout, 0, len(c.rollouts))
+	for i := c.seq; i > 0; i-- {
+		if r, ok := c.rollouts[fmt.Sprintf("rollout-%d", i)]; ok {
+			out = append(out, c.snapshot(r))
+		}
+	}
+	return out
+}
+
+// Pause stops a running rollout before its next batch.
+func (c *Controller) Pause(id, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.rollouts[id]
+	if !ok {
+		return registry.ErrNotFound
+	}
+	if r.State != StateRunning {
+		return fmt.Errorf("rollout is %s", r.State)
+	}
+	r.State = StatePaused
+	r.PauseReason = reason
+	r.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// Resume continues a paused rollout with its next batch.
+func (c *Controller) Resume(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.rollouts[id]
+	if !ok {
+		return registry.ErrNotFound
+	}
+	if r.State != StatePaused {
+		return fmt.Errorf("rollout is %s", r.State)
+	}
+	r.State = StateRunning
+	r.PauseReason = ""
+	r.UpdatedAt = time.Now().UTC()
+	select {
+	case r.resume <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Cancel abandons a rollout; agents already pushed keep the new config.
+func (c *Controller) Cancel(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.rollouts[id]
+	if !ok {
+		return registry.ErrNotFound
+	}
+	if r.State == StateCompleted || r.State == StateCancelled {
+		return fmt.Errorf("rollout is %s", r.State)
+	}
+	r.State = StateCancelled
+	r.UpdatedAt = time.Now().UTC()
+	if c.active == id {
+		c.active = ""
+	}
+	select {
+	case r.resume <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// snapshot copies the exported fields for API responses; callers hold c.mu.
+func (c *Controller) snapshot(r *Rollout) *Rollout {
+	out := *r
+	out.batches = nil
+	out.resume = nil
+	return &out
+}
+
+// run drives one rollout batch by batch. It deliberately does not inherit
+// the API request context: a rollout outlives the request that started it
+// and ends only through its own state machine.
+func (c *Controller) run(r *Rollout) {
+	ctx := context.Background()
+	defer func() {
+		c.mu.Lock()
+		if c.active == r.ID {
+			c.active = ""
+		}
+		c.mu.Unlock()
+	}()
+
+	for i := 0; i < len(r.batches); i++ {
+		if !c.awaitRunnable(ctx, r, i) {
+			return
+		}
+		batch := r.batches[i]
+		c.logger.Info("rollout batch starting", "rollout", r.ID, "batch", i+1, "of", r.Batches, "agents", len(batch))
+
+		failed := map[string]bool{}
+		for _, uid := range batch {
+			if err := c.pusher.PushConfigToAgent(ctx, uid); err != nil {
+				c.logger.Warn("rollout push failed", "rollout", r.ID, "agent", uid, "error", err)
+				failed[uid] = true
+			}
+		}
+		applied := c.waitForBatch(ctx, r, batch, failed)
+
+		c.mu.Lock()
+		r.Applied += applied
+		r.Failed += len(batch) - applied
+		r.UpdatedAt = time.Now().UTC()
+		rate := float64(len(batch)-applied) / float64(len(batch))
+		if rate > r.Options.FailureThreshold && r.State == StateRunning && i+1 < len(r.batches) {
+			r.State = StatePaused
+			r.PauseReason = fmt.Sprintf("batch %d failure rate %.0f%% exceeds threshold", i+1, rate*100)
+			c.logger.Warn("rollout paused", "rollout", r.ID, "reason", r.PauseReason)
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	if r.State == StateRunning || r.State == StatePaused {
+		r.State = StateCompleted
+		r.UpdatedAt = time.Now().UTC()
+	}
+	c.mu.Unlock()
+	c.logger.Info("rollout finished", "rollout", r.ID, "applied", r.Applied, "failed", r.Failed)
+}
+
+// awaitRunnable blocks while the rollout is paused and reports whether the
+// rollout should proceed with batch i.
+func (c *Controller) awaitRunnable(ctx context.Context, r *Rollout, i int) bool {
+	for {
+		c.mu.Lock()
+		state := r.State
+		if state == StateRunning {
+			r.Batch = i + 1
+		}
+		c.mu.Unlock()
+		switch state {
+		case StateRunning:
+			return true
+		case StatePaused:
+			select {
+			case <-ctx.Done():
+				return false
+			case <-r.resume:
+			}
+		default:
+			return false
+		}
+	}
+}
+
+// waitForBatch polls the registry until every agent in the batch has either
+// applied the config (and is healthy, if required) or failed, or the batch
+// timeout passes. It returns the number of agents that applied.
+func (c *Controller) waitForBatch(ctx context.Context, r *Rollout, batch []string, failed map[string]bool) int {
+	deadline := time.Now().Add(r.Options.BatchTimeout)
+	applied := map[string]bool{}
+	for {
+		c.mu.Lock()
+		state := r.State
+		c.mu.Unlock()
+		if state == StateCancelled {
+			return len(applied)
+		}
+		for _, uid := range batch {
+			if applied[uid] || failed[uid] {
+				continue
+			}
+			agent, err := c.registry.Get(uid)
+			if err != nil {
+				continue
+			}
+			switch {
+			case agent.ConfigState == registry.ConfigStateFailed:
+				failed[uid] = true
+			case agent.ConfigState == registry.ConfigStateApplied:
+				if r.Options.RequireHealthy && agent.Healthy != registry.HealthHealthy {
+					continue
+				}
+				applied[uid] = true
+			}
+		}
+		if len(applied)+len(failed) == len(batch) || time.Now().After(deadline) {
+			return len(applied)
+		}
+		select {
+		case <-ctx.Done():
+			return len(applied)
+		case <-time.After(pollInterval):
+		}
+	}
+}
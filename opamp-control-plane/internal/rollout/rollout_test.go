@@ -0,0 +1,129 @@
+package rollout
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcrisp4/ai-coding-experiments/opamp-control-plane/internal/registry"
+)
+
+// fakePusher records pushes and marks each pushed agent applied or failed
+// in the registry, standing in for the agent's status report.
+type fakePusher struct {
+	reg  *registry.Registry
+	fail map[string]bool
+
+	mu     sync.Mutex
+	pushed []string
+}
+
+func (p *fakePusher) PushConfigToAgent(_ context.Context, uid string) error {
+	p.mu.Lock()
+	p.pushed = append(p.pushed, uid)
+	p.mu.Unlock()
+	state := registry.ConfigStateApplied
+	if p.fail[uid] {
+		state = registry.ConfigStateFailed
+	}
+	return p.reg.SetConfigState(uid, "hash", state, "")
+}
+
+func newTestController(t *testing.T, uids []string, fail map[string]bool) (*Controller, *fakePusher) {
+	t.Helper()
+	reg, err := registry.Open(t.TempDir() + "/reg.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reg.Close() })
+	for _, uid := range uids {
+		if err := reg.Upsert(&registry.Agent{InstanceUID: uid}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pusher := &fakePusher{reg: reg, fail: fail}
+	return New(reg, pusher, slog.New(slog.NewTextHandler(io.Discard, nil))), pusher
+}
+
+func waitForState(t *testing.T, c *Controller, id string, want State) *Rollout {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		r, ok := c.Get(id)
+		if !ok {
+			t.Fatalf("rollout %s disappeared", id)
+		}
+		if r.State == want {
+			return r
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	r, _ := c.Get(id)
+	t.Fatalf("rollout never reached %s, stuck at %s", want, r.State)
+	return nil
+}
+
+func TestRolloutCompletesInBatches(t *testing.T) {
+	uids := []string{"agent-1", "agent-2", "agent-3"}
+	c, pusher := newTestController(t, uids, nil)
+
+	r, err := c.Start(Options{BatchSize: 2, BatchTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if r.Batches != 2 || r.Total != 3 {
+		t.Fatalf("expected 2 batches over 3 agents, got %d over %d", r.Batches, r.Total)
+	}
+
+	done := waitForState(t, c, r.ID, StateCompleted)
+	if done.Applied != 3 || done.Failed != 0 {
+		t.Fatalf("expected 3 applied, got applied=%d failed=%d", done.Applied, done.Failed)
+	}
+	pusher.mu.Lock()
+	defer pusher.mu.Unlock()
+	if len(pusher.pushed) != 3 {
+		t.Fatalf("expected 3 pushes, got %v", pusher.pushed)
+	}
+}
+
+func TestRolloutPausesOnFailures(t *testing.T) {
+	uids := []string{"agent-1", "agent-2", "agent-3", "agent-4"}
+	c, _ := newTestController(t, uids, map[string]bool{"agent-1": true, "agent-2": true})
+
+	r, err := c.Start(Options{BatchSize: 2, BatchTimeout: 5 * time.Second, FailureThreshold: 0.5})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	paused := waitForState(t, c, r.ID, StatePaused)
+	if paused.Failed != 2 {
+		t.Fatalf("expected 2 failed in first batch, got %d", paused.Failed)
+	}
+	if paused.PauseReason == "" {
+		t.Fatal("expected a pause reason")
+	}
+
+	if err := c.Resume(r.ID); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	done := waitForState(t, c, r.ID, StateCompleted)
+	if done.Applied != 2 || done.Failed != 2 {
+		t.Fatalf("expected applied=2 failed=2, got applied=%d failed=%d", done.Applied, done.Failed)
+	}
+}
+
+func TestRolloutSingleFlight(t *testing.T) {
+	c, _ := newTestController(t, []string{"agent-1"}, map[string]bool{"agent-1": true})
+
+	r, err := c.Start(Options{BatchTimeout: 5 * time.Second, FailureThreshold: 0.99})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := c.Start(Options{}); err == nil {
+		t.Fatal("expected second Start to fail while one is running")
+	}
+	waitForState(t, c, r.ID, StateCompleted)
+}
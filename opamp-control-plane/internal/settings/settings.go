@@ -0,0 +1,598 @@
+// Package settings loads the control plane's own configuration file.
+//
+// The file is YAML and is deliberately small: everything that describes the
+// fleet lives in the git-backed config repository, not here. This file only
+// tells the server where to listen, where the config repo is, and where to
+// keep its state.
+package settings
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings is the top-level server configuration.
+type Settings struct {
+	Server     Server     `yaml:"server"`
+	Database   Database   `yaml:"database"`
+	Git        Git        `yaml:"git"`
+	Bundle     Bundle     `yaml:"bundle"`
+	Auth       Auth       `yaml:"auth"`
+	API        API        `yaml:"api"`
+	Push       Push       `yaml:"push"`
+	Reconcile  Reconcile  `yaml:"reconcile"`
+	Labels     Labels     `yaml:"labels"`
+	Packages   Packages   `yaml:"packages"`
+	Certs      Certs      `yaml:"certs"`
+	Vault      Vault      `yaml:"vault"`
+	Sops       Sops       `yaml:"sops"`
+	Validation Validation `yaml:"validation"`
+	Policy     Policy     `yaml:"policy"`
+	Notify     Notify     `yaml:"notify"`
+	Federation Federation `yaml:"federation"`
+	Log        Log        `yaml:"log"`
+}
+
+// Server holds the listen addresses for the two HTTP surfaces: the OpAMP
+// endpoint agents connect to and the REST API operators use.
+type Server struct {
+	OpAMPListenAddr string `yaml:"opamp_listen_addr"`
+	APIListenAddr   string `yaml:"api_listen_addr"`
+	// HeartbeatInterval is offered fleet-wide to agents that report
+	// heartbeats; selectors can override it per fleet segment. Zero leaves
+	// agent defaults alone.
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+	// DrainPeerURL, when set, redirects agents to a peer control plane
+	// during shutdown instead of telling them to back off and retry.
+	DrainPeerURL string `yaml:"drain_peer_url"`
+	// DrainRetryAfter is how long agents are told to back off during
+	// shutdown. Defaults to 30s.
+	DrainRetryAfter time.Duration `yaml:"drain_retry_after"`
+	// MaxConnections caps concurrent OpAMP connections; zero means
+	// unlimited.
+	MaxConnections int `yaml:"max_connections"`
+	// MaxConnectionsPerIP caps concurrent OpAMP connections per client IP;
+	// zero means unlimited.
+	MaxConnectionsPerIP int `yaml:"max_connections_per_ip"`
+	// Allowlist, when true, only serves pre-registered approved agents;
+	// unknown agents are parked pending operator approval.
+	Allowlist bool `yaml:"allowlist"`
+	// ConfigHashAlgorithm picks the hash identifying resolved configs:
+	// "sha256" (default) or "sha512". Changing it re-offers every config.
+	ConfigHashAlgorithm string `yaml:"config_hash_algorithm"`
+}
+
+// Database configures the embedded registry database.
+type Database struct {
+	Path string `yaml:"path"`
+}
+
+// Git configures the config repository source. If URL is empty the server
+// runs in local mode and serves configs straight out of LocalPath.
+type Git struct {
+	URL    string `yaml:"url"`
+	Branch string `yaml:"branch"`
+	// Ref pins the checkout to a tag or exact commit SHA instead of
+	// tracking Branch, so production only moves when the pin is updated.
+	Ref       string `yaml:"ref"`
+	LocalPath string `yaml:"local_path"`
+	// Path is a subdirectory of the checkout treated as the config root,
+	// for monorepos that keep collector configs next to other code. Empty
+	// means the repository root.
+	Path string `yaml:"path"`
+	// Sparse materializes only Path in the checkout instead of the whole
+	// repository, so control plane instances don't carry the rest of a
+	// large monorepo on disk. Requires Path.
+	Sparse       bool          `yaml:"sparse"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	Token        string        `yaml:"token"`
+	// Proxy routes git HTTPS traffic through the given proxy URL, for
+	// deployments behind corporate egress proxies.
+	Proxy string `yaml:"proxy"`
+	// CABundle is a path to a PEM bundle of additional CAs trusted for the
+	// git remote, for internally-signed GitLab or Gitea instances.
+	CABundle      string `yaml:"ca_bundle"`
+	WebhookSecret string `yaml:"webhook_secret"`
+	// GitHubApp authenticates to GitHub as a GitHub App installation with
+	// automatic installation-token refresh, instead of a long-lived token.
+	GitHubApp GitHubApp `yaml:"github_app"`
+	// SigningKeys is a path to an armored PGP public keyring. When set,
+	// every synced commit must carry a valid signature from one of the
+	// keys; unverified commits are rejected and the last verified commit
+	// keeps being served.
+	SigningKeys string `yaml:"signing_keys"`
+	// ForceReset recovers from force-pushed history by hard-resetting the
+	// checkout to the remote branch head instead of erroring on every poll.
+	ForceReset bool `yaml:"force_reset"`
+	// RequireApproval holds every synced change as a pending change set
+	// that must be approved through the API before it is loaded or pushed.
+	RequireApproval bool `yaml:"require_approval"`
+	// Environments map promotion stages to config tree roots: a
+	// subdirectory of the checkout (path) or a separate branch synced into
+	// its own checkout under local_path (branch). Branch and path
+	// environments cannot be mixed.
+	Environments []GitEnvironment `yaml:"environments"`
+	// EnvironmentLabel is the agent label whose value picks an environment.
+	// Defaults to "deployment.environment".
+	EnvironmentLabel string `yaml:"environment_label"`
+	// Export periodically commits rendered per-selector configs and a
+	// fleet summary to a branch, giving auditors a git-native view of what
+	// was deployed.
+	Export GitExport `yaml:"export"`
+}
+
+// Bundle configures an HTTP(S) config bundle source as an alternative to
+// git: a .tar.gz or .zip published by CI is periodically downloaded and
+// unpacked into git.local_path, with ETag-based change detection. Requires
+// local mode (git.url empty).
+type Bundle struct {
+	URL string `yaml:"url"`
+	// Interval between bundle polls. Defaults to 1m.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// GitHubApp identifies a GitHub App installation. Enabled when AppID is set.
+type GitHubApp struct {
+	AppID          int64 `yaml:"app_id"`
+	InstallationID int64 `yaml:"installation_id"`
+	// PrivateKey is a path to the app's PEM private key.
+	PrivateKey string `yaml:"private_key"`
+}
+
+// GitExport configures the optional write-back of deployed state. Disabled
+// unless Branch is set; the branch must already exist on the remote.
+type GitExport struct {
+	// URL of the repository to push exports to; defaults to git.url.
+	URL    string `yaml:"url"`
+	Branch string `yaml:"branch"`
+	// LocalPath is the export checkout directory. Defaults to "exportrepo".
+	LocalPath string `yaml:"local_path"`
+	// Interval between exports. Defaults to 10m.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// GitEnvironment is one promotion stage: agents labelled with Name resolve
+// against the config tree it points at.
+type GitEnvironment struct {
+	Name string `yaml:"name"`
+	// Branch is synced into its own checkout at <local_path>/<name>.
+	Branch string `yaml:"branch"`
+	// Path is a subdirectory of the regular checkout.
+	Path string `yaml:"path"`
+	// Match routes agents to this environment by a full label match instead
+	// of the environment label's value equalling Name: every listed label
+	// must match, with the same value syntax as selector matches.
+	// Environments with matches are evaluated in declaration order; the
+	// first match wins.
+	Match map[string]string `yaml:"match"`
+}
+
+// Auth configures agent authentication on the OpAMP endpoint. Connections
+// are accepted unconditionally unless tokens are configured.
+type Auth struct {
+	// Tokens are static bearer tokens, each attributed to an identity name.
+	Tokens []AuthToken `yaml:"tokens"`
+	// TokenFile points at a file with one "<token> <identity>" pair per
+	// line; entries add to Tokens.
+	TokenFile string `yaml:"token_file"`
+}
+
+// AuthToken is one static bearer token.
+type AuthToken struct {
+	Token string `yaml:"token"`
+	// Name identifies who holds the token, e.g. "edge-fleet"; recorded on
+	// authenticated events.
+	Name string `yaml:"name"`
+}
+
+// API configures operator authentication on the REST API. Without keys the
+// API is open, as before.
+type API struct {
+	// Keys are static API keys sent in the X-API-Key header. With at least
+	// one key configured, mutating endpoints require a valid key; reads
+	// and the health check stay open.
+	Keys []APIKey `yaml:"keys"`
+}
+
+// APIKey is one static API key.
+type APIKey struct {
+	Key string `yaml:"key"`
+	// Name identifies who holds the key, e.g. "ci"; recorded as the actor
+	// on audited changes.
+	Name string `yaml:"name"`
+}
+
+// Push configures how config pushes are scheduled after a repository sync.
+type Push struct {
+	// Debounce is how long to wait after a sync trigger for further
+	// triggers before pushing; triggers inside the window coalesce into
+	// one push. Defaults to 2s.
+	Debounce time.Duration `yaml:"debounce"`
+	// MinAgentInterval is the minimum time between two pushes to the same
+	// agent. Zero disables the limit.
+	MinAgentInterval time.Duration `yaml:"min_agent_interval"`
+}
+
+// Reconcile configures the periodic push that re-offers the current config
+// to drifted agents, independent of git changes. Disabled when Interval is
+// zero.
+type Reconcile struct {
+	Interval time.Duration `yaml:"interval"`
+	// BatchSize caps how many agents are pushed per tick. Defaults to 50.
+	BatchSize int `yaml:"batch_size"`
+}
+
+// Labels configures how agent description attributes become selector labels.
+type Labels struct {
+	// Semconv maps resource attribute keys to canonical label keys. Entries
+	// merge into the built-in semantic-convention mapping; set a key's value
+	// to "" to disable a built-in entry.
+	Semconv map[string]string `yaml:"semconv"`
+	// Rules derive further labels from attribute values, applied in order
+	// after the semconv mapping and before selector matching.
+	Rules []LabelRule `yaml:"rules"`
+	// Normalize cleans up reported keys and values before the mapping and
+	// rules run.
+	Normalize LabelNormalize `yaml:"normalize"`
+}
+
+// LabelNormalize rewrites reported attribute and label keys so selectors see
+// consistent names across collector versions.
+type LabelNormalize struct {
+	// Rename maps reported keys to replacements; an empty replacement
+	// drops the key.
+	Rename map[string]string `yaml:"rename"`
+	// StripPrefixes are key prefixes removed from keys not covered by
+	// rename, e.g. "service." turns "service.version" into "version".
+	StripPrefixes []string `yaml:"strip_prefixes"`
+	// LowercaseValues lowercases every reported value.
+	LowercaseValues bool `yaml:"lowercase_values"`
+}
+
+// LabelRule derives one label from an agent attribute. The attribute value
+// must match the regular expression; the label value template may reference
+// capture groups as $1, $2, ... and defaults to the whole attribute value.
+// E.g. attr "host.name", match "^web-", label "role", value "web".
+type LabelRule struct {
+	Attr  string `yaml:"attr"`
+	Match string `yaml:"match"`
+	Label string `yaml:"label"`
+	Value string `yaml:"value"`
+}
+
+// Packages configures the artifact store behind the OpAMP package upgrade
+// flow. The flow is disabled unless Dir is set.
+type Packages struct {
+	// Dir is the artifact directory, laid out as <dir>/<package>/<version>.
+	Dir string `yaml:"dir"`
+	// BaseURL is the externally reachable prefix of the package download
+	// endpoint, e.g. "https://controlplane.example.com/packages".
+	BaseURL string `yaml:"base_url"`
+}
+
+// Certs configures the CA behind the OpAMP client certificate provisioning
+// flow. The flow is disabled unless CACert is set.
+type Certs struct {
+	// CACert and CAKey are paths to the PEM-encoded CA certificate and
+	// private key used to sign agent CSRs.
+	CACert string `yaml:"ca_cert"`
+	CAKey  string `yaml:"ca_key"`
+	// Validity is how long issued certificates are valid for.
+	Validity time.Duration `yaml:"validity"`
+}
+
+// Vault configures secret injection from HashiCorp Vault. An empty addr
+// disables the flow; configs referencing secrets then fail to resolve.
+type Vault struct {
+	Addr string `yaml:"addr"`
+	// Token authenticates to Vault; TokenFile reads it from a file instead,
+	// taking precedence when both are set.
+	Token     string `yaml:"token"`
+	TokenFile string `yaml:"token_file"`
+}
+
+// Sops configures decryption of SOPS-encrypted files in the config repo.
+// Disabled, an encrypted file fails the snapshot load.
+type Sops struct {
+	Enabled bool `yaml:"enabled"`
+	// Binary is the sops executable to invoke; defaults to "sops" on PATH.
+	Binary string `yaml:"binary"`
+	// AgeKeyFile points at an age identity file for decryption. Cloud KMS
+	// keys use the ambient credentials sops picks up itself.
+	AgeKeyFile string `yaml:"age_key_file"`
+}
+
+// Validation configures config validation beyond the built-in structural
+// checks.
+type Validation struct {
+	// OtelcolBinary, when set, is a collector binary whose validate command
+	// checks component settings in every resolved config. Point it at the
+	// same build the fleet runs.
+	OtelcolBinary string `yaml:"otelcol_binary"`
+	// RejectPlaintextSecrets blocks configs containing likely plaintext
+	// credentials instead of only warning about them.
+	RejectPlaintextSecrets bool `yaml:"reject_plaintext_secrets"`
+	// MaxFileSize and MaxConfigSize cap, in bytes, how large an individual
+	// config file and the merged result may be. Zero means unlimited.
+	MaxFileSize   int `yaml:"max_file_size"`
+	MaxConfigSize int `yaml:"max_config_size"`
+}
+
+// Policy configures the optional OPA policy stage run on every resolved
+// config. Disabled unless Dir is set.
+type Policy struct {
+	// Dir holds the Rego policies evaluated against resolved configs.
+	Dir string `yaml:"dir"`
+	// Binary is the opa executable to invoke; defaults to "opa" on PATH.
+	Binary string `yaml:"binary"`
+	// Query selects the denial rule set; defaults to "data.opamp.deny".
+	Query string `yaml:"query"`
+}
+
+// Notify configures outbound webhooks for fleet events.
+type Notify struct {
+	Webhooks []NotifyWebhook `yaml:"webhooks"`
+}
+
+// NotifyWebhook is one outbound webhook receiver. Deliveries are signed
+// with HMAC-SHA256 using Secret; see the notify package for the scheme.
+type NotifyWebhook struct {
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret"`
+	// Events limits delivery to the listed event types. Empty means all.
+	Events []string `yaml:"events"`
+}
+
+// Federation configures the aggregated read-only view over downstream
+// control planes. The federated endpoints are disabled unless peers are
+// listed.
+type Federation struct {
+	Peers []FederationPeer `yaml:"peers"`
+}
+
+// FederationPeer is one downstream control plane queried via its REST API.
+type FederationPeer struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Token, when set, is sent as a bearer token on peer requests.
+	Token string `yaml:"token"`
+}
+
+// Log configures logging.
+type Log struct {
+	Level string `yaml:"level"`
+}
+
+// Load reads and validates the settings file at path.
+func Load(path string) (*Settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read settings: %w", err)
+	}
+	s := Default()
+	if err := yaml.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parse settings %s: %w", path, err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid settings %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Default returns settings with all defaults applied.
+func Default() *Settings {
+	return &Settings{
+		Server: Server{
+			OpAMPListenAddr: ":4320",
+			APIListenAddr:   ":8420",
+		},
+		Database: Database{
+			Path: "controlplane.db",
+		},
+		Git: Git{
+			Branch:       "main",
+			LocalPath:    "configrepo",
+			PollInterval: time.Minute,
+			Export: GitExport{
+				LocalPath: "exportrepo",
+				Interval:  10 * time.Minute,
+			},
+		},
+		Bundle: Bundle{
+			Interval: time.Minute,
+		},
+		Push: Push{
+			Debounce: 2 * time.Second,
+		},
+		Certs: Certs{
+			Validity: 30 * 24 * time.Hour,
+		},
+		Log: Log{
+			Level: "info",
+		},
+	}
+}
+
+// Validate checks settings for obvious misconfiguration.
+func (s *Settings) Validate() error {
+	if s.Server.OpAMPListenAddr == "" {
+		return fmt.Errorf("server.opamp_listen_addr must be set")
+	}
+	if s.Server.APIListenAddr == "" {
+		return fmt.Errorf("server.api_listen_addr must be set")
+	}
+	if s.Git.LocalPath == "" {
+		return fmt.Errorf("git.local_path must be set")
+	}
+	if s.Git.PollInterval < time.Second {
+		return fmt.Errorf("git.poll_interval must be at least 1s")
+	}
+	branchEnvs, pathEnvs := 0, 0
+	envNames := map[string]bool{}
+	for _, env := range s.Git.Environments {
+		if env.Name == "" {
+			return fmt.Errorf("git.environments entries must set name")
+		}
+		if envNames[env.Name] {
+			return fmt.Errorf("git.environments has duplicate name %s", env.Name)
+		}
+		envNames[env.Name] = true
+		if env.Branch != "" && env.Path != "" {
+			return fmt.Errorf("git environment %s sets both branch and path", env.Name)
+		}
+		for k := range env.Match {
+			if k == "" {
+				return fmt.Errorf("git environment %s has a match entry with an empty label key", env.Name)
+			}
+		}
+		if env.Branch != "" {
+			branchEnvs++
+		} else {
+			pathEnvs++
+		}
+	}
+	if branchEnvs > 0 && pathEnvs > 0 {
+		return fmt.Errorf("git.environments cannot mix branch and path environments")
+	}
+	if branchEnvs > 0 && s.Git.URL == "" {
+		return fmt.Errorf("git.environments with branches require git.url")
+	}
+	if s.Git.Path != "" && !filepath.IsLocal(s.Git.Path) {
+		return fmt.Errorf("git.path must be a relative path inside the repository")
+	}
+	if s.Git.Path != "" && branchEnvs > 0 {
+		return fmt.Errorf("git.path cannot be combined with branch environments")
+	}
+	if s.Git.Sparse && s.Git.Path == "" {
+		return fmt.Errorf("git.sparse requires git.path")
+	}
+	if s.Git.Ref != "" && s.Git.URL == "" {
+		return fmt.Errorf("git.ref requires git.url")
+	}
+	if s.Git.Ref != "" && branchEnvs > 0 {
+		return fmt.Errorf("git.ref cannot be combined with branch environments")
+	}
+	if s.Git.Proxy != "" {
+		u, err := url.Parse(s.Git.Proxy)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("git.proxy must be an absolute http(s) url")
+		}
+	}
+	if s.Git.GitHubApp.AppID != 0 || s.Git.GitHubApp.InstallationID != 0 || s.Git.GitHubApp.PrivateKey != "" {
+		if s.Git.GitHubApp.AppID == 0 || s.Git.GitHubApp.InstallationID == 0 || s.Git.GitHubApp.PrivateKey == "" {
+			return fmt.Errorf("git.github_app requires app_id, installation_id, and private_key")
+		}
+	}
+	if s.Git.Export.Branch != "" {
+		if s.Git.Export.URL == "" && s.Git.URL == "" {
+			return fmt.Errorf("git.export requires a repository url")
+		}
+		if s.Git.Export.LocalPath == "" {
+			return fmt.Errorf("git.export.local_path must be set")
+		}
+		if s.Git.Export.Interval < time.Second {
+			return fmt.Errorf("git.export.interval must be at least 1s")
+		}
+	}
+	if s.Bundle.URL != "" {
+		if s.Git.URL != "" {
+			return fmt.Errorf("bundle.url cannot be combined with git.url")
+		}
+		if s.Bundle.Interval < time.Second {
+			return fmt.Errorf("bundle.interval must be at least 1s")
+		}
+	}
+	for i, prefix := range s.Labels.Normalize.StripPrefixes {
+		if prefix == "" {
+			return fmt.Errorf("labels.normalize.strip_prefixes[%d] must not be empty", i)
+		}
+	}
+	for i, rule := range s.Labels.Rules {
+		if rule.Attr == "" || rule.Label == "" {
+			return fmt.Errorf("labels.rules[%d] must set attr and label", i)
+		}
+		if rule.Match == "" {
+			return fmt.Errorf("labels.rules[%d].match must be set", i)
+		}
+		if _, err := regexp.Compile(rule.Match); err != nil {
+			return fmt.Errorf("labels.rules[%d].match: %w", i, err)
+		}
+	}
+	if s.Packages.Dir != "" && s.Packages.BaseURL == "" {
+		return fmt.Errorf("packages.base_url must be set when packages.dir is set")
+	}
+	if s.Server.HeartbeatInterval != 0 && s.Server.HeartbeatInterval < time.Second {
+		return fmt.Errorf("server.heartbeat_interval must be at least 1s")
+	}
+	if s.Server.DrainRetryAfter < 0 {
+		return fmt.Errorf("server.drain_retry_after must not be negative")
+	}
+	if s.Server.MaxConnections < 0 {
+		return fmt.Errorf("server.max_connections must not be negative")
+	}
+	if s.Server.MaxConnectionsPerIP < 0 {
+		return fmt.Errorf("server.max_connections_per_ip must not be negative")
+	}
+	if s.Validation.MaxFileSize < 0 || s.Validation.MaxConfigSize < 0 {
+		return fmt.Errorf("validation size limits must not be negative")
+	}
+	switch s.Server.ConfigHashAlgorithm {
+	case "", "sha256", "sha512":
+	default:
+		return fmt.Errorf("server.config_hash_algorithm must be sha256 or sha512")
+	}
+	if s.Push.Debounce < 0 {
+		return fmt.Errorf("push.debounce must not be negative")
+	}
+	if s.Push.MinAgentInterval < 0 {
+		return fmt.Errorf("push.min_agent_interval must not be negative")
+	}
+	if s.Reconcile.Interval != 0 && s.Reconcile.Interval < time.Second {
+		return fmt.Errorf("reconcile.interval must be at least 1s")
+	}
+	for i, t := range s.Auth.Tokens {
+		if t.Token == "" {
+			return fmt.Errorf("auth.tokens[%d].token must be set", i)
+		}
+	}
+	for i, k := range s.API.Keys {
+		if k.Key == "" {
+			return fmt.Errorf("api.keys[%d].key must be set", i)
+		}
+		if k.Name == "" {
+			return fmt.Errorf("api.keys[%d].name must be set", i)
+		}
+	}
+	if (s.Certs.CACert == "") != (s.Certs.CAKey == "") {
+		return fmt.Errorf("certs.ca_cert and certs.ca_key must be set together")
+	}
+	if s.Certs.CACert != "" && s.Certs.Validity < time.Minute {
+		return fmt.Errorf("certs.validity must be at least 1m")
+	}
+	if s.Vault.Addr != "" && s.Vault.Token == "" && s.Vault.TokenFile == "" {
+		return fmt.Errorf("vault.token or vault.token_file must be set when vault.addr is")
+	}
+	for i, p := range s.Federation.Peers {
+		if p.Name == "" {
+			return fmt.Errorf("federation.peers[%d].name must be set", i)
+		}
+		if p.URL == "" {
+			return fmt.Errorf("federation.peers[%d].url must be set", i)
+		}
+	}
+	for i, wh := range s.Notify.Webhooks {
+		if wh.URL == "" {
+			return fmt.Errorf("notify.webhooks[%d].url must be set", i)
+		}
+		if wh.Secret == "" {
+			return fmt.Errorf("notify.webhooks[%d].secret must be set", i)
+		}
+	}
+	return nil
+}